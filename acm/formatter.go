@@ -6,14 +6,15 @@ import (
 
 	"github.com/jschaf/bibtex"
 	bibtexAst "github.com/jschaf/bibtex/ast"
+	"github.com/lmondada/goldmark-bibtex/cite"
 )
 
 func FormatAuthor(author *bibtexAst.Author) (authorFmt string) {
-	authorFmt += author.First.(*bibtexAst.Text).Value
+	authorFmt += cite.Text(author.First)
 	authorFmt += " "
-	authorFmt += author.Prefix.(*bibtexAst.Text).Value
+	authorFmt += cite.Text(author.Prefix)
 	authorFmt += " <span class=\"last-name\">"
-	authorFmt += author.Last.(*bibtexAst.Text).Value
+	authorFmt += cite.Text(author.Last)
 	authorFmt += "</span>"
 	return
 }
@@ -33,27 +34,172 @@ func join(list []string) (s string) {
 	return
 }
 
+// defaultEtAl is the ACM truncation: "Smith et al." for three or more authors
+// in the text, and all authors in the reference list.
+var defaultEtAl = cite.EtAl{
+	Inline:    cite.Truncation{Min: 3, UseFirst: 1},
+	Reference: cite.NoTruncation,
+}
+
 // FormatAuthors formats a list of authors according to ACM style
 // ACM style uses full names and separates authors with commas, using "and" for the last author
 func FormatAuthors(authors bibtexAst.Authors) string {
-	authorList := make([]string, len(authors))
-	for i, author := range authors {
+	return formatAuthors(authors, defaultEtAl.Reference)
+}
+
+func formatAuthors(authors bibtexAst.Authors, etAl cite.Truncation) string {
+	shown, truncated := etAl.Shown(len(authors))
+	authorList := make([]string, shown)
+	for i, author := range authors[:shown] {
 		authorList[i] = FormatAuthor(author)
 	}
 
+	if truncated {
+		return fmt.Sprintf(`<span class="authors">%s et al.</span>`, strings.Join(authorList, ", "))
+	}
 	return fmt.Sprintf(`<span class="authors">%s</span>`, join(authorList))
 }
 
+// FormatInlineCitation formats the inline citation of a group of cited items
+// in the ACM author-year format, like "(Smith and Jones 2023, p. 4)". A single
+// item cited in the ModeAuthorInText mode is formatted as a narrative citation,
+// like "Smith and Jones (2023)".
+func FormatInlineCitation(items []cite.Item) string {
+	return formatInlineCitation(items, defaultEtAl.Inline)
+}
+
+func formatInlineCitation(items []cite.Item, etAl cite.Truncation) string {
+	if len(items) == 1 && items[0].Mode == cite.ModeAuthorInText {
+		item := items[0]
+		if item.Entry == nil {
+			return "?"
+		}
+		label := fmt.Sprintf("%s (%s)", formatAuthorLabel(item.Entry, etAl), item.Decorate(cite.Year(item.Entry)+item.YearSuffix))
+		return formatKeySpan(item.Entry, label)
+	}
+
+	parts := make([]string, len(items))
+	for i, item := range items {
+		switch {
+		case item.Entry == nil:
+			parts[i] = item.Decorate("?")
+		case item.Mode == cite.ModeSuppressAuthor:
+			parts[i] = item.Decorate(formatKeySpan(item.Entry, cite.Year(item.Entry)+item.YearSuffix))
+		default:
+			label := formatAuthorLabel(item.Entry, etAl) + " " + cite.Year(item.Entry) + item.YearSuffix
+			parts[i] = item.Decorate(formatKeySpan(item.Entry, label))
+		}
+	}
+	return "(" + strings.Join(parts, "; ") + ")"
+}
+
+func formatKeySpan(entry *bibtex.Entry, label string) string {
+	return fmt.Sprintf(`<span data-bibtex-key="%s" class="citation-key">%s</span>`, entry.Key, label)
+}
+
+// formatAuthorLabel formats the author last names of a short citation key,
+// like "Smith", "Smith and Jones" or "Smith et al.", or the editors,
+// organization or title of works without authors.
+func formatAuthorLabel(entry *bibtex.Entry, etAl cite.Truncation) string {
+	lead := cite.LeadOf(entry)
+	if len(lead.Names) == 0 {
+		return lead.Label()
+	}
+	shown, truncated := etAl.Shown(len(lead.Names))
+	names := make([]string, shown)
+	for i, author := range lead.Names[:shown] {
+		names[i] = cite.Text(author.Last)
+	}
+	if truncated {
+		return strings.Join(names, ", ") + " et al."
+	}
+	return join(names)
+}
+
+// formatLead formats the names that lead a reference: the authors, else the
+// editors with "(Ed.)", else the organization, else the title.
+func formatLead(entry *bibtex.Entry, etAl cite.Truncation) string {
+	lead := cite.LeadOf(entry)
+	switch {
+	case len(lead.Names) == 1 && lead.Editors:
+		return formatAuthors(lead.Names, etAl) + " (Ed.)"
+	case lead.Editors:
+		return formatAuthors(lead.Names, etAl) + " (Eds.)"
+	case len(lead.Names) > 0:
+		return formatAuthors(lead.Names, etAl)
+	case lead.Title != "":
+		return getTitle(entry, "title")
+	default:
+		// Works without names or title start with their year
+		return lead.Organization
+	}
+}
+
+// formatTitle returns the title of a reference, or "" if the title already
+// leads the reference, see formatLead.
+func formatTitle(entry *bibtex.Entry) string {
+	if lead := cite.LeadOf(entry); lead.Title != "" {
+		return ""
+	}
+	return getTitle(entry, "title")
+}
+
+// formatHead joins the beginning of a reference, the lead, year and title,
+// into sentences, skipping the title if it leads the reference.
+func formatHead(parts ...string) (head string) {
+	for _, part := range parts {
+		head = joinSentence(head, part)
+	}
+	return head
+}
+
+// joinSentence joins two parts of a reference with a period, unless the
+// first already ends with one, like "n.d.", or with "?" or "!". Empty parts
+// are skipped.
+func joinSentence(s, next string) string {
+	switch {
+	case next == "":
+		return s
+	case s == "":
+		return next
+	case endsSentence(s):
+		return s + " " + next
+	default:
+		return s + ". " + next
+	}
+}
+
+// endsSentence reports whether the text of s, without its closing tags,
+// ends with a period, "?" or "!".
+func endsSentence(s string) bool {
+	for strings.HasSuffix(s, ">") {
+		i := strings.LastIndex(s, "</")
+		if i < 0 || strings.Contains(s[i:], " ") {
+			break
+		}
+		s = s[:i]
+	}
+	return strings.HasSuffix(s, ".") || strings.HasSuffix(s, "?") || strings.HasSuffix(s, "!")
+}
+
+// formatEnd ends a reference with a period, and closes its span.
+func formatEnd(citation string) string {
+	if !endsSentence(citation) {
+		citation += "."
+	}
+	return citation + "</span>"
+}
+
 func formatDoi(doi string) string {
 	return fmt.Sprintf(`<span class="doi">doi: <a href="https://doi.org/%s">%s</a></span>`, doi, doi)
 }
 
-func getArticleRef(entry *bibtex.Entry) articleRef {
+func getArticleRef(entry *bibtex.Entry, etAl cite.Truncation) articleRef {
 	return articleRef{
-		authors: FormatAuthors(entry.Tags["author"].(bibtexAst.Authors)),
-		year:    getFieldText(entry, "year"),
-		title:   getFieldText(entry, "title"),
-		journal: getFieldText(entry, "journal"),
+		authors: formatLead(entry, etAl),
+		year:    cite.Year(entry),
+		title:   formatTitle(entry),
+		journal: getTitle(entry, "journal"),
 		number:  getFieldText(entry, "number"),
 		volume:  getFieldText(entry, "volume"),
 		pages:   getFieldText(entry, "pages"),
@@ -62,12 +208,12 @@ func getArticleRef(entry *bibtex.Entry) articleRef {
 	}
 }
 
-func getProceedingsRef(entry *bibtex.Entry) proceedingsRef {
+func getProceedingsRef(entry *bibtex.Entry, etAl cite.Truncation) proceedingsRef {
 	return proceedingsRef{
-		authors:   FormatAuthors(entry.Tags["author"].(bibtexAst.Authors)),
-		year:      getFieldText(entry, "year"),
-		title:     getFieldText(entry, "title"),
-		booktitle: getFieldText(entry, "booktitle"),
+		authors:   formatLead(entry, etAl),
+		year:      cite.Year(entry),
+		title:     formatTitle(entry),
+		booktitle: getTitle(entry, "booktitle"),
 		month:     getMonth(entry),
 		address:   getFieldText(entry, "address"),
 		pages:     getFieldText(entry, "pages"),
@@ -76,11 +222,11 @@ func getProceedingsRef(entry *bibtex.Entry) proceedingsRef {
 	}
 }
 
-func getBookRef(entry *bibtex.Entry) bookRef {
+func getBookRef(entry *bibtex.Entry, etAl cite.Truncation) bookRef {
 	return bookRef{
-		authors:   FormatAuthors(entry.Tags["author"].(bibtexAst.Authors)),
-		year:      getFieldText(entry, "year"),
-		title:     getFieldText(entry, "title"),
+		authors:   formatLead(entry, etAl),
+		year:      cite.Year(entry),
+		title:     formatTitle(entry),
 		publisher: getFieldText(entry, "publisher"),
 		address:   getFieldText(entry, "address"),
 		edition:   getFieldText(entry, "edition"),
@@ -88,92 +234,119 @@ func getBookRef(entry *bibtex.Entry) bookRef {
 	}
 }
 
-func getArxivRef(entry *bibtex.Entry) arxivRef {
+func getArxivRef(entry *bibtex.Entry, etAl cite.Truncation) arxivRef {
 	return arxivRef{
-		authors:      FormatAuthors(entry.Tags["author"].(bibtexAst.Authors)),
-		year:         getFieldText(entry, "year"),
-		title:        getFieldText(entry, "title"),
+		authors:      formatLead(entry, etAl),
+		year:         cite.Year(entry),
+		title:        formatTitle(entry),
 		eprint:       getFieldText(entry, "eprint"),
 		primaryClass: getFieldText(entry, "primaryclass"),
 	}
 }
 
-func getDefaultRef(entry *bibtex.Entry) defaultRef {
+func getDefaultRef(entry *bibtex.Entry, etAl cite.Truncation) defaultRef {
 	return defaultRef{
-		authors:      FormatAuthors(entry.Tags["author"].(bibtexAst.Authors)),
-		year:         getFieldText(entry, "year"),
+		authors:      formatLead(entry, etAl),
+		year:         cite.Year(entry),
 		month:        getMonth(entry),
-		title:        getFieldText(entry, "title"),
+		title:        formatTitle(entry),
 		howpublished: getFieldText(entry, "howpublished"),
 		url:          getFieldText(entry, "url"),
+		accessed:     getAccessed(entry),
 	}
 }
 
-type phdthesisRef struct {
+type thesisRef struct {
 	authors string
 	year    string
 	title   string
+	kind    string
 	school  string
 	address string
 	doi     string
 }
 
-// formatPhdthesis formats a PhD thesis citation in ACM style
+// formatThesis formats a thesis or a technical report citation in ACM style,
+// with its kind and the school or institution
 // Example: John Doe. 2023. Quantum Computing with Superconducting Qubits. PhD Thesis. Stanford University, Stanford, CA.
-func formatPhdthesis(ref phdthesisRef) string {
-	citation := fmt.Sprintf(`<span class="citation-full">%s. %s. %s. PhD Thesis`,
-		ref.authors, ref.year, ref.title)
+// Example: Jane Roe. 2021. Sparse Graph Matching. Technical Report TR-21-3. MIT, Cambridge, MA.
+func formatThesis(ref thesisRef) string {
+	citation := `<span class="citation-full">` + joinSentence(formatHead(ref.authors, ref.year, ref.title), ref.kind)
 
 	if ref.school != "" {
-		citation += ". " + ref.school
+		citation = joinSentence(citation, ref.school)
 		if ref.address != "" {
 			citation += ", " + ref.address
 		}
 	}
 
 	if ref.doi != "" {
-		citation += fmt.Sprintf(". %s", formatDoi(ref.doi))
+		citation = joinSentence(citation, formatDoi(ref.doi))
 	}
 
-	citation += "</span>"
-	return citation
+	return formatEnd(citation)
 }
 
-func getPhdthesisRef(entry *bibtex.Entry) phdthesisRef {
-	return phdthesisRef{
-		authors: FormatAuthors(entry.Tags["author"].(bibtexAst.Authors)),
-		year:    getFieldText(entry, "year"),
-		title:   getFieldText(entry, "title"),
-		school:  getFieldText(entry, "school"),
+// getThesisRef returns the reference of a thesis or a technical report of a
+// kind, published by the school or institution in field.
+func getThesisRef(entry *bibtex.Entry, etAl cite.Truncation, kind, field string) thesisRef {
+	return thesisRef{
+		authors: formatLead(entry, etAl),
+		year:    cite.Year(entry),
+		title:   formatTitle(entry),
+		kind:    kind,
+		school:  getFieldText(entry, field),
 		address: getFieldText(entry, "address"),
 		doi:     getFieldText(entry, "doi"),
 	}
 }
 
+// getReportKind returns the kind of a technical report, like "Technical
+// Report 42", from its type and number.
+func getReportKind(entry *bibtex.Entry) string {
+	kind := getFieldText(entry, "type")
+	if kind == "" {
+		kind = "Technical Report"
+	}
+	if number := getFieldText(entry, "number"); number != "" {
+		kind += " " + number
+	}
+	return kind
+}
+
 // FormatCitation formats a full citation in ACM style
 func FormatCitation(entry *bibtex.Entry) string {
+	return formatCitation(entry, defaultEtAl.Reference)
+}
+
+func formatCitation(entry *bibtex.Entry, etAl cite.Truncation) string {
 	archivePrefix := getFieldText(entry, "archiveprefix")
 
 	switch strings.ToLower(entry.Type) {
 	case "article":
-		articleRef := getArticleRef(entry)
+		articleRef := getArticleRef(entry, etAl)
 		return formatArticle(articleRef)
 	case "inproceedings", "conference":
-		proceedingsRef := getProceedingsRef(entry)
+		proceedingsRef := getProceedingsRef(entry, etAl)
 		return formatProceedings(proceedingsRef)
 	case "book":
-		bookRef := getBookRef(entry)
+		bookRef := getBookRef(entry, etAl)
 		return formatBook(bookRef)
 	case "phdthesis":
-		phdthesisRef := getPhdthesisRef(entry)
-		return formatPhdthesis(phdthesisRef)
+		return formatThesis(getThesisRef(entry, etAl, "PhD Thesis", "school"))
+	case "mastersthesis":
+		return formatThesis(getThesisRef(entry, etAl, "Master's Thesis", "school"))
+	case "thesis":
+		return formatThesis(getThesisRef(entry, etAl, getFieldText(entry, "type"), "school"))
+	case "techreport":
+		return formatThesis(getThesisRef(entry, etAl, getReportKind(entry), "institution"))
 	default:
 		if !strings.EqualFold(archivePrefix, "arXiv") {
-			defaultRef := getDefaultRef(entry)
+			defaultRef := getDefaultRef(entry, etAl)
 			return formatDefault(defaultRef)
 		}
 		// Handle arXiv papers specially
-		arxivRef := getArxivRef(entry)
+		arxivRef := getArxivRef(entry, etAl)
 		return formatArxiv(arxivRef)
 	}
 }
@@ -193,10 +366,10 @@ type articleRef struct {
 // formatArticle formats an article citation in ACM style
 // Example: Patricia S. Abril and Robert Plant. 2007. The patent holder's dilemma: Buy, sell, or troll? Commun. ACM 50, 1 (Jan. 2007), 36-44. https://doi.org/10.1145/1188913.1188915
 func formatArticle(article articleRef) string {
-	citation := fmt.Sprintf(
-		`<span class="citation-full">%s. %s. %s. <em>%s</em>`,
-		article.authors, article.year, article.title, article.journal,
-	)
+	citation := `<span class="citation-full">` + formatHead(article.authors, article.year, article.title)
+	if article.journal != "" {
+		citation = joinSentence(citation, "<em>"+article.journal+"</em>")
+	}
 
 	if article.volume != "" {
 		citation += " " + article.volume
@@ -220,11 +393,10 @@ func formatArticle(article articleRef) string {
 	}
 
 	if article.doi != "" {
-		citation += fmt.Sprintf(". %s", formatDoi(article.doi))
+		citation = joinSentence(citation, formatDoi(article.doi))
 	}
 
-	citation += "</span>"
-	return citation
+	return formatEnd(citation)
 }
 
 type proceedingsRef struct {
@@ -242,8 +414,10 @@ type proceedingsRef struct {
 // formatProceedings formats a conference proceedings citation in ACM style
 // Example: Sten Andler. 1979. Predicate path expressions. In Proceedings of the 6th. ACM SIGACT-SIGPLAN Symposium on Principles of Programming Languages (POPL '79), January 29 - 31, 1979, San Antonio, Texas. ACM Inc., New York, NY, 226-236. https://doi.org/10.1145/567752.567774
 func formatProceedings(ref proceedingsRef) string {
-	citation := fmt.Sprintf(`<span class="citation-full">%s. %s. %s. In <em>%s</em>`,
-		ref.authors, ref.year, ref.title, ref.booktitle)
+	citation := `<span class="citation-full">` + formatHead(ref.authors, ref.year, ref.title)
+	if ref.booktitle != "" {
+		citation = joinSentence(citation, "In <em>"+ref.booktitle+"</em>")
+	}
 
 	if ref.month != "" || ref.address != "" {
 		citation += ", "
@@ -259,7 +433,7 @@ func formatProceedings(ref proceedingsRef) string {
 	}
 
 	if ref.publisher != "" {
-		citation += ". " + ref.publisher
+		citation = joinSentence(citation, ref.publisher)
 	}
 
 	if ref.pages != "" {
@@ -267,11 +441,10 @@ func formatProceedings(ref proceedingsRef) string {
 	}
 
 	if ref.doi != "" {
-		citation += fmt.Sprintf(". %s", formatDoi(ref.doi))
+		citation = joinSentence(citation, formatDoi(ref.doi))
 	}
 
-	citation += "</span>"
-	return citation
+	return formatEnd(citation)
 }
 
 type bookRef struct {
@@ -287,26 +460,29 @@ type bookRef struct {
 // formatBook formats a book citation in ACM style
 // Example: David Kosiur. 2001. Understanding Policy-Based Networking (2nd. ed.). Wiley, New York, NY.
 func formatBook(ref bookRef) string {
-	citation := fmt.Sprintf(`<span class="citation-full">%s. %s. <em>%s</em>`,
-		ref.authors, ref.year, ref.title)
+	title := ref.title
+	if title != "" {
+		title = "<em>" + title + "</em>"
+	}
+	citation := fmt.Sprintf(`<span class="citation-full">%s`,
+		formatHead(ref.authors, ref.year, title))
 
 	if ref.edition != "" {
 		citation += fmt.Sprintf(" (%s ed.)", ref.edition)
 	}
 
 	if ref.publisher != "" {
-		citation += ". " + ref.publisher
+		citation = joinSentence(citation, ref.publisher)
 		if ref.address != "" {
 			citation += ", " + ref.address
 		}
 	}
 
 	if ref.doi != "" {
-		citation += fmt.Sprintf(". %s", formatDoi(ref.doi))
+		citation = joinSentence(citation, formatDoi(ref.doi))
 	}
 
-	citation += "</span>"
-	return citation
+	return formatEnd(citation)
 }
 
 type arxivRef struct {
@@ -320,19 +496,18 @@ type arxivRef struct {
 // formatArxiv formats an arXiv paper citation in ACM style
 // Example: "Ali Javadi-Abhari et al. 2024. Quantum computing with Qiskit. arXiv: 2405.08810 [quant-ph]"
 func formatArxiv(ref arxivRef) string {
-	citation := fmt.Sprintf(`<span class="citation-full">%s. %s. %s`,
-		ref.authors, ref.year, ref.title)
+	citation := fmt.Sprintf(`<span class="citation-full">%s`,
+		formatHead(ref.authors, ref.year, ref.title))
 
 	if ref.eprint != "" {
-		citation += fmt.Sprintf(". arXiv: <a href=\"https://arxiv.org/abs/%s\">%s", ref.eprint, ref.eprint)
+		eprint := fmt.Sprintf("arXiv: <a href=\"https://arxiv.org/abs/%s\">%s", ref.eprint, ref.eprint)
 		if ref.primaryClass != "" {
-			citation += fmt.Sprintf(" [%s]", ref.primaryClass)
+			eprint += fmt.Sprintf(" [%s]", ref.primaryClass)
 		}
-		citation += "</a>"
+		citation = joinSentence(citation, eprint+"</a>")
 	}
 
-	citation += "</span>"
-	return citation
+	return formatEnd(citation)
 }
 
 type defaultRef struct {
@@ -342,23 +517,31 @@ type defaultRef struct {
 	title        string
 	howpublished string
 	url          string
+	accessed     string
 }
 
 // formatDefault formats other types of citations in ACM style
 // This is a basic formatter that includes the essential elements of a citation:
 // authors, year, title, and URL/DOI if available
 func formatDefault(ref defaultRef) string {
-	citation := fmt.Sprintf(`<span class="citation-full">%s. %s. %s`,
-		ref.authors, ref.year, ref.title)
+	citation := fmt.Sprintf(`<span class="citation-full">%s`,
+		formatHead(ref.authors, ref.year, ref.title))
 
 	if ref.month != "" {
-		citation += fmt.Sprintf(". (%s %s)", ref.month, ref.year)
+		citation = joinSentence(citation, fmt.Sprintf("(%s %s)", ref.month, ref.year))
 	}
 
 	if ref.url != "" || ref.howpublished != "" {
-		citation += ". "
+		if endsSentence(citation) {
+			citation += " "
+		} else {
+			citation += ". "
+		}
 		if ref.url != "" {
 			citation += "Retrieved "
+			if ref.accessed != "" {
+				citation += ref.accessed + " "
+			}
 		}
 		if ref.howpublished != "" {
 			citation += ref.howpublished
@@ -369,46 +552,101 @@ func formatDefault(ref defaultRef) string {
 		}
 	}
 
-	citation += "</span>"
-	return citation
+	return formatEnd(citation)
 }
 
-func getMonth(entry *bibtex.Entry) string {
-	month := entry.Tags["month"]
-
-	if month == nil {
-		return ""
-	}
-
-	identToMonth := map[interface{}]string{
-		"jan": "January",
-		"feb": "February",
-		"mar": "March",
-		"apr": "April",
-		"may": "May",
-		"jun": "June",
-		"jul": "July",
-		"aug": "August",
-		"sep": "Septempter",
-		"oct": "October",
-		"nov": "November",
-		"dec": "December",
+// getAccessed returns the date the URL of an entry was accessed, like
+// "March 15, 2021", or "" if it is not set.
+func getAccessed(entry *bibtex.Entry) string {
+	if date, ok := cite.URLDate(entry); ok {
+		return date.String()
 	}
+	return ""
+}
 
-	switch ident := month.(type) {
+// getMonth returns the name of the month of an entry, like "March", or ""
+// if it is not set.
+func getMonth(entry *bibtex.Entry) string {
+	switch month := entry.Tags["month"].(type) {
 	case *bibtexAst.Ident:
-		return identToMonth[ident.Name]
+		for i, key := range cite.MonthKeys {
+			if month.Name == key {
+				return cite.MonthNames[i]
+			}
+		}
+		return ""
 	case *bibtexAst.Text:
-		return ident.Value
+		return month.Value
 	default:
-		panic("cannot render month type")
+		return ""
 	}
 }
 
 // getFieldText safely gets the text of a BibTeX field
 func getFieldText(entry *bibtex.Entry, field string) string {
-	if f := entry.Tags[field]; f != nil {
-		return f.(*bibtexAst.Text).Value
+	return cite.Field(entry, field)
+}
+
+// getTitle returns a title field in title case.
+func getTitle(entry *bibtex.Entry, field string) string {
+	return cite.Title(entry, field, cite.TitleCase)
+}
+
+// Style is the ACM reference format. It implements the Style interface of the
+// goldmark-bibtex extension.
+type Style struct {
+	// Numeric selects numeric citations, like "[1]", numbered in the order
+	// the works are first cited. Otherwise citations use the author-year
+	// format, like "(Smith 2023)".
+	Numeric bool
+	// EtAl overrides the truncation of author lists, see defaultEtAl.
+	EtAl cite.EtAl
+}
+
+// FormatCitation formats an inline citation, see FormatInlineCitation and
+// cite.FormatNumeric.
+func (s Style) FormatCitation(items []cite.Item) string {
+	etAl := s.EtAl.Or(defaultEtAl).Inline
+	if s.Numeric {
+		return cite.FormatNumeric(items, func(entry *bibtex.Entry) string {
+			return formatAuthorLabel(entry, etAl)
+		})
 	}
-	return ""
+	return formatInlineCitation(items, etAl)
+}
+
+// FormatReference formats a full reference, see FormatCitation.
+func (s Style) FormatReference(ref cite.Reference) string {
+	return formatCitation(cite.WithYearSuffix(ref.Entry, ref.YearSuffix), s.EtAl.Or(defaultEtAl).Reference)
+}
+
+// Less orders the reference list alphabetically by author, then by year. In
+// the numeric format, references keep the order in which they are first
+// cited.
+func (s Style) Less(a, b *bibtex.Entry) bool {
+	if s.Numeric {
+		return false
+	}
+	return cite.LessAuthorYear(a, b)
+}
+
+// Disambiguate adds year suffixes, like "Smith et al. 2020a", to works with
+// the same author-year citation. Numeric citations need no disambiguation.
+func (s Style) Disambiguate(refs []cite.Reference) {
+	if s.Numeric {
+		return
+	}
+	cite.Disambiguate(refs, cite.DisambiguationRules{
+		Truncation:    s.EtAl.Or(defaultEtAl).Inline,
+		AddYearSuffix: true,
+	})
+}
+
+// FormatBibliography formats the reference list as an unordered list, or as an
+// ordered list in the numeric format.
+func (s Style) FormatBibliography(refs []cite.Reference) string {
+	if s.Numeric {
+		return cite.FormatList("ol", refs, s.FormatReference)
+	}
+	return cite.FormatList("ul", refs, s.FormatReference)
 }
@@ -0,0 +1,186 @@
+package acm
+
+import (
+	"testing"
+
+	"github.com/jschaf/bibtex"
+	bibtexAst "github.com/jschaf/bibtex/ast"
+	"github.com/lmondada/goldmark-bibtex/cite"
+)
+
+// newEntry returns an entry with text fields, and authors written
+// "Last, First".
+func newEntry(typ, key string, fields map[string]string, authors ...string) *bibtex.Entry {
+	tags := make(map[string]bibtexAst.Expr)
+	for field, value := range fields {
+		tags[field] = &bibtexAst.Text{Value: value}
+	}
+	if len(authors) > 0 {
+		names := make(bibtexAst.Authors, len(authors))
+		for i, author := range authors {
+			names[i] = cite.ParseName(author)
+		}
+		tags["author"] = names
+	}
+	return &bibtex.Entry{Type: typ, Key: key, Tags: tags}
+}
+
+// keySpan returns the span of the key of a citation.
+func keySpan(key, label string) string {
+	return `<span data-bibtex-key="` + key + `" class="citation-key">` + label + `</span>`
+}
+
+func TestFormatCitation(t *testing.T) {
+	smith := newEntry("article", "smith", map[string]string{"title": "Graphs", "year": "2020"}, "Smith, John")
+	pair := newEntry("article", "pair", map[string]string{"title": "Trees", "year": "2021"}, "Doe, Jane", "Roe, Richard")
+	group := newEntry("article", "group", map[string]string{"title": "Forests", "year": "2022"},
+		"Doe, Jane", "Roe, Richard", "Poe, Edgar")
+
+	tests := []struct {
+		name  string
+		style Style
+		items []cite.Item
+		want  string
+	}{
+		{
+			"author-year",
+			Style{},
+			[]cite.Item{
+				{Entry: smith, Key: "smith", Locator: "p. 4"},
+				{Entry: pair, Key: "pair", Disambiguation: cite.Disambiguation{YearSuffix: "a"}},
+			},
+			"(" + keySpan("smith", "Smith 2020") + ", p. 4; " + keySpan("pair", "Doe and Roe 2021a") + ")",
+		},
+		{
+			"et al.",
+			Style{},
+			[]cite.Item{{Entry: group, Key: "group"}},
+			"(" + keySpan("group", "Doe et al. 2022") + ")",
+		},
+		{
+			"narrative",
+			Style{},
+			[]cite.Item{{Entry: pair, Key: "pair", Mode: cite.ModeAuthorInText}},
+			keySpan("pair", "Doe and Roe (2021)"),
+		},
+		{
+			"suppressed author",
+			Style{},
+			[]cite.Item{{Entry: smith, Key: "smith", Mode: cite.ModeSuppressAuthor}},
+			"(" + keySpan("smith", "2020") + ")",
+		},
+		{
+			"missing",
+			Style{},
+			[]cite.Item{{Key: "missing"}},
+			"(?)",
+		},
+		{
+			"truncation",
+			Style{EtAl: cite.EtAl{Inline: cite.Truncation{Min: 2, UseFirst: 1}}},
+			[]cite.Item{{Entry: pair, Key: "pair"}},
+			"(" + keySpan("pair", "Doe et al. 2021") + ")",
+		},
+		{
+			"numeric",
+			Style{Numeric: true},
+			[]cite.Item{{Entry: smith, Key: "smith", Number: 2}, {Entry: pair, Key: "pair", Number: 1, Locator: "p. 4"}},
+			"[" + keySpan("smith", "2") + ", " + keySpan("pair", "1") + ", p. 4]",
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.style.FormatCitation(tt.items); got != tt.want {
+			t.Errorf("%s: FormatCitation = %q; want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLess(t *testing.T) {
+	doe := newEntry("book", "doe", map[string]string{"year": "2021"}, "Doe, Jane")
+	smith2019 := newEntry("book", "smith2019", map[string]string{"year": "2019"}, "Smith, John")
+	smith2020 := newEntry("book", "smith2020", map[string]string{"year": "2020"}, "Smith, John")
+
+	tests := []struct {
+		style Style
+		a, b  *bibtex.Entry
+		want  bool
+	}{
+		{Style{}, doe, smith2019, true},
+		{Style{}, smith2019, doe, false},
+		{Style{}, smith2019, smith2020, true},
+		{Style{}, smith2020, smith2019, false},
+		// Numeric references keep the order of their first citation
+		{Style{Numeric: true}, doe, smith2019, false},
+		{Style{Numeric: true}, smith2019, doe, false},
+	}
+	for _, tt := range tests {
+		if got := tt.style.Less(tt.a, tt.b); got != tt.want {
+			t.Errorf("Numeric %v: Less(%s, %s) = %v; want %v", tt.style.Numeric, tt.a.Key, tt.b.Key, got, tt.want)
+		}
+	}
+}
+
+func TestFormatReference(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry *bibtex.Entry
+		want  string
+	}{
+		{
+			"article",
+			newEntry("article", "a", map[string]string{"title": "graph pattern matching", "journal": "Cluster Computing",
+				"year": "2020", "volume": "23", "number": "3", "pages": "1529–1553"}, "Mahfoud, Houari"),
+			`<span class="citation-full"><span class="authors">Houari  <span class="last-name">Mahfoud</span></span>. 2020. ` +
+				`Graph Pattern Matching. <em>Cluster Computing</em> 23, 3 (1529–1553).</span>`,
+		},
+		{
+			"book",
+			newEntry("book", "b", map[string]string{"title": "Graphs", "year": "2019", "edition": "2nd",
+				"publisher": "Springer", "address": "Berlin"}, "Doe, Jane"),
+			`<span class="citation-full"><span class="authors">Jane  <span class="last-name">Doe</span></span>. 2019. ` +
+				`<em>Graphs</em> (2nd ed.). Springer, Berlin.</span>`,
+		},
+		{
+			"technical report",
+			newEntry("techreport", "r", map[string]string{"title": "Sparse Graph Matching", "year": "2021", "number": "TR-21-3",
+				"institution": "MIT", "address": "Cambridge, MA"}, "Roe, Jane"),
+			`<span class="citation-full"><span class="authors">Jane  <span class="last-name">Roe</span></span>. 2021. ` +
+				`Sparse Graph Matching. Technical Report TR-21-3. MIT, Cambridge, MA.</span>`,
+		},
+		{
+			"master's thesis",
+			newEntry("mastersthesis", "m", map[string]string{"title": "Graph Search", "year": "2019",
+				"school": "University of Oxford"}, "Smith, John"),
+			`<span class="citation-full"><span class="authors">John  <span class="last-name">Smith</span></span>. 2019. ` +
+				`Graph Search. Master's Thesis. University of Oxford.</span>`,
+		},
+		{
+			"thesis",
+			newEntry("thesis", "t", map[string]string{"title": "Graph Search", "year": "2019", "type": "Habilitation",
+				"school": "ETH Zurich"}, "Smith, John"),
+			`<span class="citation-full"><span class="authors">John  <span class="last-name">Smith</span></span>. 2019. ` +
+				`Graph Search. Habilitation. ETH Zurich.</span>`,
+		},
+	}
+	for _, tt := range tests {
+		if got := (Style{}).FormatReference(cite.Reference{Entry: tt.entry}); got != tt.want {
+			t.Errorf("%s: FormatReference = %q; want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// Months are written out
+	entry := newEntry("article", "a", map[string]string{"title": "Graphs", "year": "2020", "pages": "1–9"}, "Smith, John")
+	entry.Tags["month"] = &bibtexAst.Ident{Name: "sep"}
+	want := `<span class="citation-full"><span class="authors">John  <span class="last-name">Smith</span></span>. 2020. Graphs (September 2020, 1–9).</span>`
+	if got := (Style{}).FormatReference(cite.Reference{Entry: entry}); got != want {
+		t.Errorf("month: FormatReference = %q; want %q", got, want)
+	}
+
+	// Year suffixes disambiguate the references of the author-year format
+	entry = newEntry("misc", "m", map[string]string{"title": "Notes", "year": "2020"}, "Smith, John")
+	want = `<span class="citation-full"><span class="authors">John  <span class="last-name">Smith</span></span>. 2020b. Notes.</span>`
+	ref := cite.Reference{Entry: entry, Disambiguation: cite.Disambiguation{YearSuffix: "b"}}
+	if got := (Style{}).FormatReference(ref); got != want {
+		t.Errorf("year suffix: FormatReference = %q; want %q", got, want)
+	}
+}
@@ -2,15 +2,35 @@ package apa
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/jschaf/bibtex"
 	bibtexAst "github.com/jschaf/bibtex/ast"
+	"github.com/lmondada/goldmark-bibtex/cite"
 )
 
+// defaultEtAl is the APA 7 truncation: "Smith et al." for three or more
+// authors in the text, and the first 19 authors, an ellipsis and the last
+// author for 21 or more authors in the reference list.
+var defaultEtAl = cite.EtAl{
+	Inline:    cite.Truncation{Min: 3, UseFirst: 1},
+	Reference: cite.Truncation{Min: 21, UseFirst: 19, UseLast: true},
+}
+
 // FormatAuthors formats a list of authors according to APA style
 func FormatAuthors(authors bibtexAst.Authors) string {
+	return formatAuthors(authors, defaultEtAl.Reference)
+}
+
+func formatAuthors(authors bibtexAst.Authors, etAl cite.Truncation) string {
+	formatAuthor := func(author *bibtexAst.Author) string {
+		return fmt.Sprintf(`<span class="author">%s</span>`,
+			strings.Join(nonEmpty(cite.Text(author.Last), cite.Text(author.First)), " "))
+	}
+
+	shown, truncated := etAl.Shown(len(authors))
 	var authorList string
-	for i, author := range authors {
+	for i, author := range authors[:shown] {
 		if i > 0 {
 			if i == len(authors)-1 {
 				authorList += ", & "
@@ -18,62 +38,195 @@ func FormatAuthors(authors bibtexAst.Authors) string {
 				authorList += ", "
 			}
 		}
-		authorList += fmt.Sprintf(`<span class="author">%s %s</span>`,
-			author.Last.(*bibtexAst.Text).Value,
-			author.First.(*bibtexAst.Text).Value)
+		authorList += formatAuthor(author)
+	}
+	switch {
+	case truncated && etAl.UseLast:
+		authorList += ", … " + formatAuthor(authors[len(authors)-1])
+	case truncated:
+		authorList += ", et al."
 	}
 	return authorList
 }
 
 // FormatCitationKey formats a short citation key
 func FormatCitationKey(entry *bibtex.Entry) string {
-	authors := entry.Tags["author"].(bibtexAst.Authors)
-	year := entry.Tags["year"].(*bibtexAst.Text)
-	firstAuthor := authors[0]
-	lastName := firstAuthor.Last.(*bibtexAst.Text).Value
-	return fmt.Sprintf(`<span data-bibtex-key="%s" class="citation-key">%s, %s</span>`,
-		entry.Key,
-		TrimLastName(lastName),
-		year.Value)
+	return formatCitationKey(entry, cite.Disambiguation{}, defaultEtAl.Inline)
+}
+
+func formatCitationKey(entry *bibtex.Entry, d cite.Disambiguation, etAl cite.Truncation) string {
+	return formatKeySpan(entry, formatAuthorLabel(entry, d, etAl, "&")+", "+formatYearLabel(entry, d))
+}
+
+// FormatInlineCitation formats the inline citation of a group of cited items,
+// like "(see Smith, 2023, p. 4; Doe, 2020)". A single item cited in the
+// ModeAuthorInText mode is formatted as a narrative citation, like
+// "Smith (2023, p. 4)".
+func FormatInlineCitation(items []cite.Item) string {
+	return formatInlineCitation(items, defaultEtAl.Inline)
+}
+
+func formatInlineCitation(items []cite.Item, etAl cite.Truncation) string {
+	if len(items) == 1 && items[0].Mode == cite.ModeAuthorInText {
+		item := items[0]
+		if item.Entry == nil {
+			return "?"
+		}
+		label := fmt.Sprintf("%s (%s)", formatAuthorLabel(item.Entry, item.Disambiguation, etAl, "and"), item.Decorate(formatYearLabel(item.Entry, item.Disambiguation)))
+		return formatKeySpan(item.Entry, label)
+	}
+
+	parts := make([]string, len(items))
+	for i, item := range items {
+		switch {
+		case item.Entry == nil:
+			parts[i] = item.Decorate("?")
+		case item.Mode == cite.ModeSuppressAuthor:
+			parts[i] = item.Decorate(formatKeySpan(item.Entry, formatYearLabel(item.Entry, item.Disambiguation)))
+		default:
+			parts[i] = item.Decorate(formatCitationKey(item.Entry, item.Disambiguation, etAl))
+		}
+	}
+	return "(" + strings.Join(parts, "; ") + ")"
+}
+
+func formatKeySpan(entry *bibtex.Entry, label string) string {
+	return fmt.Sprintf(`<span data-bibtex-key="%s" class="citation-key">%s</span>`, entry.Key, label)
+}
+
+// formatAuthorLabel formats the author part of a short citation key, like
+// "Smith", "Smith & Jones" or "Smith et al.", with "and" instead of "&" in
+// narrative citations. The disambiguation may add the initials of the first
+// author, like "J. Smith", or more names, like "Smith, Jones, et al." Works
+// without authors are cited by their editors, organization or title.
+func formatAuthorLabel(entry *bibtex.Entry, d cite.Disambiguation, etAl cite.Truncation, and string) string {
+	lead := cite.LeadOf(entry)
+	authors := lead.Names
+	if len(authors) == 0 {
+		return lead.Label()
+	}
+	shown, truncated := etAl.Shown(len(authors))
+	if d.Names > shown {
+		shown = min(d.Names, len(authors))
+		truncated = shown < len(authors)
+	}
+
+	names := make([]string, shown)
+	for i, author := range authors[:shown] {
+		names[i] = TrimLastName(cite.Text(author.Last))
+	}
+	if initials := cite.Initials(cite.Text(authors[0].First)); d.Initials && initials != "" {
+		names[0] = initials + " " + names[0]
+	}
+
+	switch {
+	case truncated && shown == 1:
+		return names[0] + " et al."
+	case truncated:
+		return strings.Join(names, ", ") + ", et al."
+	case shown == 1:
+		return names[0]
+	case shown == 2:
+		return names[0] + " " + and + " " + names[1]
+	default:
+		return strings.Join(names[:shown-1], ", ") + ", " + and + " " + names[shown-1]
+	}
+}
+
+// formatYearLabel formats the year part of a short citation key, with the
+// year suffix of the disambiguation, like "2020a", or "n.d." for works
+// without a year.
+func formatYearLabel(entry *bibtex.Entry, d cite.Disambiguation) string {
+	return cite.Year(entry) + d.YearSuffix
 }
 
 // TrimLastName trims an author's last name to 6 characters if it's longer
 func TrimLastName(name string) string {
-	if len(name) > 7 {
-		return name[:6] + "."
+	if runes := []rune(name); len(runes) > 7 {
+		return string(runes[:6]) + "."
 	}
 	return name
 }
 
 // FormatCitation formats a full citation in APA style
 func FormatCitation(entry *bibtex.Entry) string {
-	authors := entry.Tags["author"].(bibtexAst.Authors)
-	year := entry.Tags["year"].(*bibtexAst.Text)
-	authorList := FormatAuthors(authors)
+	return formatCitation(entry, defaultEtAl.Reference)
+}
+
+func formatCitation(entry *bibtex.Entry, etAl cite.Truncation) string {
+	lead := cite.LeadOf(entry)
 
 	var citation string
 	switch entry.Type {
 	case bibtex.EntryArticle:
-		citation = formatArticle(authorList, year.Value, entry)
+		citation = formatArticle(formatHead(entry, lead, etAl, false), entry)
 	case bibtex.EntryInProceedings:
-		citation = formatProceedings(authorList, year.Value, entry)
+		citation = formatProceedings(formatHead(entry, lead, etAl, false), entry)
 	case bibtex.EntryBook:
-		citation = formatBook(authorList, year.Value, entry)
+		citation = formatBook(formatHead(entry, lead, etAl, true), entry)
+	case bibtex.EntryTechReport:
+		citation = formatReport(formatHead(entry, lead, etAl, true), entry)
+	case bibtex.EntryPhDThesis:
+		citation = formatThesis(formatHead(entry, lead, etAl, true), entry, "Doctoral dissertation")
+	case bibtex.EntryMastersThesis:
+		citation = formatThesis(formatHead(entry, lead, etAl, true), entry, "Master's thesis")
+	case "thesis":
+		citation = formatThesis(formatHead(entry, lead, etAl, true), entry, getFieldText(entry, "type"))
 	default:
-		citation = formatDefault(authorList, year.Value, entry)
+		citation = formatDefault(formatHead(entry, lead, etAl, false), entry)
 	}
 
 	return citation
 }
 
-func formatArticle(authors, year string, entry *bibtex.Entry) string {
-	title := getFieldText(entry, "title")
-	journal := getFieldText(entry, "journal")
+// formatHead formats the beginning of a reference: the authors, the year and
+// the title, like "Smith J. (2023). Title". Works without authors are led by
+// their editors or organization, or else by their title, like "Title (2023)".
+func formatHead(entry *bibtex.Entry, lead cite.Lead, etAl cite.Truncation, emph bool) string {
+	year := cite.Year(entry)
+	title := getTitle(entry, "title", cite.SentenceCase)
+	if title != "" && emph {
+		title = "<em>" + title + "</em>"
+	}
+	if title != "" {
+		title = `<span class="title">` + title + `</span>`
+	}
+
+	switch {
+	case len(lead.Names) > 0:
+		authors := formatAuthors(lead.Names, etAl)
+		if lead.Editors && len(lead.Names) == 1 {
+			authors += " (Ed.)"
+		} else if lead.Editors {
+			authors += " (Eds.)"
+		}
+		return joinHead(fmt.Sprintf("%s (%s)", authors, year), title)
+	case lead.Organization != "":
+		return joinHead(fmt.Sprintf(`<span class="author">%s</span> (%s)`, lead.Organization, year), title)
+	case title != "":
+		return fmt.Sprintf("%s (%s)", title, year)
+	default:
+		return fmt.Sprintf("%s (%s)", lead.Label(), year)
+	}
+}
+
+// joinHead joins the author and year of a head with its title, if any.
+func joinHead(authors, title string) string {
+	if title == "" {
+		return authors
+	}
+	return authors + ". " + title
+}
+
+func formatArticle(head string, entry *bibtex.Entry) string {
+	journal := getTitle(entry, "journal", cite.TitleCase)
 	volume := getFieldText(entry, "volume")
 	pages := getFieldText(entry, "pages")
 
-	citation := fmt.Sprintf(`<span class="citation-full">%s (%s). <span class="title">%s</span>. <span class="journal">%s</span>`,
-		authors, year, title, journal)
+	citation := `<span class="citation-full">` + head
+	if journal != "" {
+		citation += fmt.Sprintf(`. <span class="journal">%s</span>`, journal)
+	}
 	if volume != "" {
 		citation += fmt.Sprintf(`, <span class="volume">%s</span>`, volume)
 	}
@@ -84,13 +237,14 @@ func formatArticle(authors, year string, entry *bibtex.Entry) string {
 	return citation
 }
 
-func formatProceedings(authors, year string, entry *bibtex.Entry) string {
-	title := getFieldText(entry, "title")
-	booktitle := getFieldText(entry, "booktitle")
+func formatProceedings(head string, entry *bibtex.Entry) string {
+	booktitle := getTitle(entry, "booktitle", cite.SentenceCase)
 	pages := getFieldText(entry, "pages")
 
-	citation := fmt.Sprintf(`<span class="citation-full">%s (%s). <span class="title">%s</span>. In <span class="booktitle">%s</span>`,
-		authors, year, title, booktitle)
+	citation := `<span class="citation-full">` + head
+	if booktitle != "" {
+		citation += fmt.Sprintf(`. In <span class="booktitle">%s</span>`, booktitle)
+	}
 	if pages != "" {
 		citation += fmt.Sprintf(` (pp. <span class="pages">%s</span>)`, pages)
 	}
@@ -98,12 +252,10 @@ func formatProceedings(authors, year string, entry *bibtex.Entry) string {
 	return citation
 }
 
-func formatBook(authors, year string, entry *bibtex.Entry) string {
-	title := getFieldText(entry, "title")
+func formatBook(head string, entry *bibtex.Entry) string {
 	publisher := getFieldText(entry, "publisher")
 
-	citation := fmt.Sprintf(`<span class="citation-full">%s (%s). <span class="title"><em>%s</em></span>`,
-		authors, year, title)
+	citation := fmt.Sprintf(`<span class="citation-full">%s`, head)
 	if publisher != "" {
 		citation += fmt.Sprintf(`. <span class="publisher">%s</span>`, publisher)
 	}
@@ -111,15 +263,109 @@ func formatBook(authors, year string, entry *bibtex.Entry) string {
 	return citation
 }
 
-func formatDefault(authors, year string, entry *bibtex.Entry) string {
-	title := getFieldText(entry, "title")
-	return fmt.Sprintf(`<span class="citation-full">%s (%s). <span class="title">%s</span>.</span>`,
-		authors, year, title)
+// formatReport formats a report with its number, if any, and institution.
+// Example: Doe, J. (2022). Graph search (Report No. 42). University of Oxford.
+func formatReport(head string, entry *bibtex.Entry) string {
+	kind := getFieldText(entry, "type")
+	institution := getFieldText(entry, "institution")
+
+	citation := `<span class="citation-full">` + head
+	if number := getFieldText(entry, "number"); number != "" {
+		if kind == "" {
+			kind = "Report"
+		}
+		kind += " No. " + number
+	}
+	if kind != "" {
+		citation += fmt.Sprintf(" (%s)", kind)
+	}
+	if institution != "" {
+		citation += fmt.Sprintf(`. <span class="publisher">%s</span>`, institution)
+	}
+	citation += ".</span>"
+	return citation
+}
+
+// formatThesis formats a thesis of a kind, like "Master's thesis", with its
+// school in brackets.
+// Example: Doe, J. (2019). Graph search [Master's thesis, University of Oxford].
+func formatThesis(head string, entry *bibtex.Entry, kind string) string {
+	citation := `<span class="citation-full">` + head
+	if description := nonEmpty(kind, getFieldText(entry, "school")); len(description) > 0 {
+		citation += fmt.Sprintf(" [%s]", strings.Join(description, ", "))
+	}
+	citation += ".</span>"
+	return citation
 }
 
+// formatDefault formats other types of citations with their head, and the
+// URL with its retrieval date if the entry has a urldate.
+// Example: Doe, J. (2021). Project page. Retrieved March 15, 2021, from https://example.com
+func formatDefault(head string, entry *bibtex.Entry) string {
+	url := cite.Field(entry, "url")
+	if date, ok := cite.URLDate(entry); ok && url != "" {
+		return fmt.Sprintf(`<span class="citation-full">%s. Retrieved %s, from <a href="%s">%s</a></span>`, head, date, url, url)
+	}
+	return fmt.Sprintf(`<span class="citation-full">%s.</span>`, head)
+}
+
+// getFieldText returns the text of a field, or "" if the entry has no
+// usable value for it.
 func getFieldText(entry *bibtex.Entry, field string) string {
-	if v, ok := entry.Tags[field]; ok {
-		return v.(*bibtexAst.Text).Value
+	return cite.Field(entry, field)
+}
+
+// getTitle returns a title field in a case: sentence case for the titles of
+// works, and title case for the names of periodicals.
+func getTitle(entry *bibtex.Entry, field string, c cite.TextCase) string {
+	return cite.Title(entry, field, c)
+}
+
+func nonEmpty(list ...string) []string {
+	var result []string
+	for _, s := range list {
+		if s != "" {
+			result = append(result, s)
+		}
 	}
-	return fmt.Sprintf("??%s??", field)
+	return result
+}
+
+// Style is the APA citation style. It implements the Style interface of the
+// goldmark-bibtex extension.
+type Style struct {
+	// EtAl overrides the APA 7 truncation of author lists, see defaultEtAl.
+	EtAl cite.EtAl
+}
+
+// FormatCitation formats an inline citation, see FormatInlineCitation.
+func (s Style) FormatCitation(items []cite.Item) string {
+	return formatInlineCitation(items, s.EtAl.Or(defaultEtAl).Inline)
+}
+
+// FormatReference formats a full reference, see FormatCitation.
+func (s Style) FormatReference(ref cite.Reference) string {
+	return formatCitation(cite.WithYearSuffix(ref.Entry, ref.YearSuffix), s.EtAl.Or(defaultEtAl).Reference)
+}
+
+// Less orders the reference list alphabetically by author, then by year.
+func (Style) Less(a, b *bibtex.Entry) bool {
+	return cite.LessAuthorYear(a, b)
+}
+
+// Disambiguate tells apart works with the same short citation key as APA
+// requires: with the initials of first authors who share a last name, with
+// more author names, then with year suffixes, like "Smith, 2020a".
+func (s Style) Disambiguate(refs []cite.Reference) {
+	cite.Disambiguate(refs, cite.DisambiguationRules{
+		Truncation:    s.EtAl.Or(defaultEtAl).Inline,
+		AddInitials:   true,
+		AddNames:      true,
+		AddYearSuffix: true,
+	})
+}
+
+// FormatBibliography formats the reference list as an unordered list.
+func (s Style) FormatBibliography(refs []cite.Reference) string {
+	return cite.FormatList("ul", refs, s.FormatReference)
 }
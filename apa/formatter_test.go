@@ -0,0 +1,80 @@
+package apa
+
+import (
+	"testing"
+
+	"github.com/jschaf/bibtex"
+	bibtexAst "github.com/jschaf/bibtex/ast"
+	"github.com/lmondada/goldmark-bibtex/cite"
+)
+
+func TestTrimLastName(t *testing.T) {
+	tests := map[string]string{
+		"Smith":     "Smith",
+		"Johansen":  "Johans.",
+		"Gödelbach": "Gödelb.",
+		"Øverholm":  "Øverho.",
+		"Schröder":  "Schröd.",
+		"":          "",
+	}
+	for name, want := range tests {
+		if got := TrimLastName(name); got != want {
+			t.Errorf("TrimLastName(%q) = %q; want %q", name, got, want)
+		}
+	}
+}
+
+func TestFormatReference(t *testing.T) {
+	author := bibtexAst.Authors{cite.ParseName("Doe, Jane")}
+	tests := []struct {
+		name   string
+		typ    string
+		fields map[string]string
+		want   string
+	}{
+		{
+			"report",
+			"techreport",
+			map[string]string{"title": "Graph search", "year": "2022", "number": "42", "institution": "University of Oxford"},
+			`<span class="citation-full"><span class="author">Doe Jane</span> (2022). <span class="title"><em>Graph search</em></span> ` +
+				`(Report No. 42). <span class="publisher">University of Oxford</span>.</span>`,
+		},
+		{
+			"report type",
+			"techreport",
+			map[string]string{"title": "Graph search", "year": "2022", "type": "Working Paper"},
+			`<span class="citation-full"><span class="author">Doe Jane</span> (2022). <span class="title"><em>Graph search</em></span> ` +
+				`(Working Paper).</span>`,
+		},
+		{
+			"doctoral dissertation",
+			"phdthesis",
+			map[string]string{"title": "Graph search", "year": "2019", "school": "ETH Zurich"},
+			`<span class="citation-full"><span class="author">Doe Jane</span> (2019). <span class="title"><em>Graph search</em></span> ` +
+				`[Doctoral dissertation, ETH Zurich].</span>`,
+		},
+		{
+			"master's thesis",
+			"mastersthesis",
+			map[string]string{"title": "Graph search", "year": "2019", "school": "University of Oxford"},
+			`<span class="citation-full"><span class="author">Doe Jane</span> (2019). <span class="title"><em>Graph search</em></span> ` +
+				`[Master's thesis, University of Oxford].</span>`,
+		},
+		{
+			"thesis",
+			"thesis",
+			map[string]string{"title": "Graph search", "year": "2019", "type": "Habilitation", "school": "ETH Zurich"},
+			`<span class="citation-full"><span class="author">Doe Jane</span> (2019). <span class="title"><em>Graph search</em></span> ` +
+				`[Habilitation, ETH Zurich].</span>`,
+		},
+	}
+	for _, tt := range tests {
+		entry := &bibtex.Entry{Type: tt.typ, Key: "doe", Tags: map[string]bibtexAst.Expr{"author": author}}
+		for field, value := range tt.fields {
+			entry.Tags[field] = &bibtexAst.Text{Value: value}
+		}
+		if got := (Style{}).FormatReference(cite.Reference{Entry: entry}); got != tt.want {
+			t.Errorf("%s: FormatReference = %q; want %q", tt.name, got, tt.want)
+		}
+	}
+}
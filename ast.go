@@ -1,6 +1,11 @@
 package bibtex
 
 import (
+	"strconv"
+	"strings"
+
+	"github.com/jschaf/bibtex"
+	"github.com/lmondada/goldmark-bibtex/cite"
 	"github.com/yuin/goldmark/ast"
 )
 
@@ -9,6 +14,33 @@ type Citation struct {
 	ast.BaseInline
 	Key     string
 	RawText string
+	// Entry is the cited entry, set by the bibliography transformer. It is nil
+	// if the key is not in the bibliography.
+	Entry *bibtex.Entry
+	// Number is the position of the entry in the reference list, starting at
+	// 1, set by the bibliography transformer.
+	Number int
+	// Mode is how the work is cited: narrative for a bare `@key`,
+	// parenthetical inside a citation group and author-suppressed for `-@key`.
+	Mode cite.Mode
+	// Prefix is the text before the key inside a citation group, like "see".
+	Prefix string
+	// Locator points into the cited work, like "pp. 33-35" or "ch. 1".
+	Locator string
+	// Suffix is the text after the key and locator inside a citation group,
+	// including its leading punctuation, like ", and passim".
+	Suffix string
+	// First reports whether this is the first citation of the entry in the
+	// document, set by the bibliography transformer.
+	First bool
+	// Note is the number of the footnote of a citation outside a group in a
+	// note style, set by the bibliography transformer. It is 0 otherwise.
+	Note int
+	// Disambiguation is the disambiguation of the reference of the entry, set
+	// by the bibliography transformer for styles that disambiguate.
+	Disambiguation cite.Disambiguation
+
+	options *documentOptions
 }
 
 var CitationKind = ast.NewNodeKind("Citation")
@@ -19,5 +51,95 @@ func (n *Citation) Kind() ast.NodeKind {
 
 // Dump implements Node.Dump.
 func (n *Citation) Dump(source []byte, level int) {
-	ast.DumpHelper(n, source, level, nil, nil)
+	ast.DumpHelper(n, source, level, map[string]string{
+		"Key":     n.Key,
+		"Number":  strconv.Itoa(n.Number),
+		"Mode":    n.Mode.String(),
+		"Prefix":  n.Prefix,
+		"Locator": n.Locator,
+		"Suffix":  n.Suffix,
+		"First":   strconv.FormatBool(n.First),
+		"Note":    strconv.Itoa(n.Note),
+	}, nil)
+}
+
+// CitationGroup represents a bracketed list of citations, like
+// `[see @smith2023, pp. 33-35; also @doe2020]`. Its children are the Citation
+// nodes of the group, in source order.
+type CitationGroup struct {
+	ast.BaseInline
+	RawText string
+	// Note is the number of the footnote of the group in a note style, set by
+	// the bibliography transformer. It is 0 otherwise.
+	Note int
+	// Nocite reports whether the group is followed by `{.nocite}`. Its works
+	// are listed in the reference list without being cited, and the
+	// bibliography transformer removes the group. A `@*` key lists every
+	// entry of the bibliography.
+	Nocite bool
+
+	options *documentOptions
+}
+
+var CitationGroupKind = ast.NewNodeKind("CitationGroup")
+
+func (n *CitationGroup) Kind() ast.NodeKind {
+	return CitationGroupKind
+}
+
+// Dump implements Node.Dump.
+func (n *CitationGroup) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{
+		"Note":   strconv.Itoa(n.Note),
+		"Nocite": strconv.FormatBool(n.Nocite),
+	}, nil)
+}
+
+// Bibliography represents the generated reference list. It holds the cited
+// entries without duplicates and the uncited entries listed with nocite,
+// sorted in the order of the citation style.
+type Bibliography struct {
+	ast.BaseBlock
+	References []cite.Reference
+
+	options *documentOptions
+}
+
+var BibliographyKind = ast.NewNodeKind("Bibliography")
+
+func (n *Bibliography) Kind() ast.NodeKind {
+	return BibliographyKind
+}
+
+// Dump implements Node.Dump.
+func (n *Bibliography) Dump(source []byte, level int) {
+	keys := make([]string, len(n.References))
+	for i, ref := range n.References {
+		keys[i] = ref.Entry.Key
+	}
+	ast.DumpHelper(n, source, level, map[string]string{
+		"References": strings.Join(keys, ", "),
+	}, nil)
+}
+
+// CitationNotes represents the footnotes of the citations in a note style, in
+// the order of their numbers.
+type CitationNotes struct {
+	ast.BaseBlock
+	Notes [][]cite.Item
+
+	options *documentOptions
+}
+
+var CitationNotesKind = ast.NewNodeKind("CitationNotes")
+
+func (n *CitationNotes) Kind() ast.NodeKind {
+	return CitationNotesKind
+}
+
+// Dump implements Node.Dump.
+func (n *CitationNotes) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{
+		"Notes": strconv.Itoa(len(n.Notes)),
+	}, nil)
 }
@@ -1,9 +1,20 @@
 package bibtex
 
 import (
+	"errors"
+	"go/scanner"
+	"go/token"
+	"io"
+	"io/fs"
+	"maps"
 	"os"
 
 	"github.com/jschaf/bibtex"
+	bibtexAst "github.com/jschaf/bibtex/ast"
+	bibtexParser "github.com/jschaf/bibtex/parser"
+	"github.com/lmondada/goldmark-bibtex/acm"
+	"github.com/lmondada/goldmark-bibtex/cite"
+	"github.com/lmondada/goldmark-bibtex/latex"
 	"github.com/yuin/goldmark"
 	"github.com/yuin/goldmark/parser"
 	"github.com/yuin/goldmark/renderer"
@@ -13,40 +24,254 @@ import (
 // Extender is a goldmark extension for rendering BibTeX citations.
 type Extender struct {
 	Bibliography []bibtex.Entry
+	// Origins maps the key of each entry of the bibliography to the place
+	// where it is defined. It is nil for NewFromEntries.
+	Origins map[string]Origin
+	// Duplicates lists the entries dropped because their key was defined
+	// more than once, see WithDuplicatePolicy.
+	Duplicates []Duplicate
+	style      Style
+	warn       func(cite.Warning)
+	resolvers  []bibtex.Resolver
+	duplicates DuplicatePolicy
+	link       bool
+	nocite     []string
+	math       latex.MathMode
+	// minCrossrefs is the number of cited children that list their
+	// crossref parent, or 0
+	minCrossrefs int
+	// xdata maps the keys of the @xdata entries to the entries,
+	// which are not part of the bibliography
+	xdata map[string]bibtex.Entry
+	// root is the directory of the files named in front matter, or nil if
+	// front matter is ignored
+	root fs.FS
 }
 
-// New creates a new BibTeX extender with the given bibliography file.
-func New(bibFile string) (*Extender, error) {
-	f, err := os.Open(bibFile)
+// Option is a functional option to configure an Extender.
+type Option func(*Extender)
+
+// WithStyle sets the citation style. Defaults to acm.Style.
+func WithStyle(style Style) Option {
+	return func(e *Extender) {
+		e.style = style
+	}
+}
+
+// WithWarningHandler sets a function called with the problems found while
+// converting a document: citations of unknown keys, and cited entries that
+// the style works around, like an entry without a year, see cite.Check. It is
+// also called with the unknown LaTeX macros of the bibliography while it is
+// loaded. By default, problems are ignored.
+func WithWarningHandler(handler func(w cite.Warning)) Option {
+	return func(e *Extender) {
+		e.warn = handler
+	}
+}
+
+// WithLinkCitations sets whether the label of each cited entry in a citation
+// links to its reference in the reference list. Defaults to false.
+func WithLinkCitations(link bool) Option {
+	return func(e *Extender) {
+		e.link = link
+	}
+}
+
+// WithNocite lists the entries with the given keys in the reference list of
+// every document, even if they are not cited. The key "*" lists every entry.
+// Documents can also list entries with a `[@key]{.nocite}` group.
+func WithNocite(keys ...string) Option {
+	return func(e *Extender) {
+		e.nocite = append(e.nocite, keys...)
+	}
+}
+
+// WithMath sets the form of the math of field values, like $O(n^2)$ in a
+// title: text, or the markup that MathJax, KaTeX or browsers typeset, so
+// that it looks like the math of the document. Defaults to latex.MathText.
+// It has no effect on NewFromEntries, whose entries are already resolved.
+func WithMath(mode latex.MathMode) Option {
+	return func(e *Extender) {
+		e.math = mode
+	}
+}
+
+// WithResolvers adds resolvers run on every field of the bibliography after
+// the default ones, which decode LaTeX markup, parse names and simplify the
+// field text. It has no effect on NewFromEntries, whose entries are already
+// resolved.
+func WithResolvers(resolvers ...bibtex.Resolver) Option {
+	return func(e *Extender) {
+		e.resolvers = append(e.resolvers, resolvers...)
+	}
+}
+
+// New creates a new BibTeX extender with the given bibliography file. Errors
+// in the file are returned as *BibError, or joined with errors.Join if there
+// are several.
+func New(bibFile string, opts ...Option) (*Extender, error) {
+	src, err := os.ReadFile(bibFile)
 	if err != nil {
 		return nil, err
 	}
-	bib := bibtex.New(
-		bibtex.WithResolvers(
-			// NewAuthorResolver creates a resolver for the "author" field that parses
-			// author names into an ast.Authors node.
-			bibtex.NewAuthorResolver("author"),
-			// SimplifyEscapedTextResolver replaces ast.TextEscaped nodes with a plain
-			// ast.Text containing the value that was escaped. Meaning, `\&` is converted to
-			// `&`.
-			bibtex.ResolverFunc(bibtex.SimplifyEscapedTextResolver),
-			// RenderParsedTextResolver replaces ast.ParsedText with a simplified rendering
-			// of ast.Text.
-			bibtex.NewRenderParsedTextResolver(),
-		),
-	)
-	file, err := bib.Parse(f)
+	return NewFromSources([]Source{{Name: bibFile, Data: src}}, opts...)
+}
+
+// NewFromFS creates a new BibTeX extender with the bibliography file name in
+// fsys, like an embed.FS. Errors are reported as by New.
+func NewFromFS(fsys fs.FS, name string, opts ...Option) (*Extender, error) {
+	src, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	return NewFromSources([]Source{{Name: name, Data: src}}, opts...)
+}
+
+// NewFromReader creates a new BibTeX extender with the bibliography read
+// from r. Errors are reported as by New, without a file name.
+func NewFromReader(r io.Reader, opts ...Option) (*Extender, error) {
+	src, err := io.ReadAll(r)
 	if err != nil {
 		return nil, err
 	}
-	entries, err := bib.Resolve(file)
+	return NewFromSources([]Source{{Data: src}}, opts...)
+}
+
+// NewFromBytes creates a new BibTeX extender with the bibliography in src.
+// Errors are reported as by New, without a file name.
+func NewFromBytes(src []byte, opts ...Option) (*Extender, error) {
+	return NewFromSources([]Source{{Data: src}}, opts...)
+}
+
+// NewFromEntries creates a new BibTeX extender with already resolved
+// entries, for bibliographies that do not come from a BibTeX file. Their
+// BibLaTeX fields are normalized with cite.Normalize, and their crossref and
+// xdata fields resolved, like those of files. The bibliography has copies of
+// the entries, which are left unchanged.
+func NewFromEntries(entries []bibtex.Entry, opts ...Option) *Extender {
+	e := newExtender(opts)
+	e.Bibliography = make([]bibtex.Entry, len(entries))
+	for i, entry := range entries {
+		entry.Tags = maps.Clone(entry.Tags)
+		cite.Normalize(&entry)
+		e.Bibliography[i] = entry
+	}
+	e.inherit()
+	return e
+}
+
+// newExtender returns an extender configured with opts.
+func newExtender(opts []Option) *Extender {
+	e := &Extender{
+		style: acm.Style{},
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// newBiber returns the BibTeX resolver of entries, running the extra
+// resolvers after the default ones, which start with decoder.
+func newBiber(extra []bibtex.Resolver, decoder latex.Resolver) *bibtex.Biber {
+	resolvers := []bibtex.Resolver{
+		// The latex.Resolver decoder decodes LaTeX markup, like accents,
+		// dashes, \emph and math, before names are parsed.
+		decoder,
+		// NewAuthorResolver creates a resolver for the "author" and "editor"
+		// fields that parses names into an ast.Authors node.
+		bibtex.NewAuthorResolver("author", "editor"),
+		// SimplifyEscapedTextResolver replaces ast.TextEscaped nodes with a plain
+		// ast.Text containing the value that was escaped. Meaning, `\&` is converted to
+		// `&`.
+		bibtex.ResolverFunc(bibtex.SimplifyEscapedTextResolver),
+		// RenderParsedTextResolver replaces ast.ParsedText with a simplified rendering
+		// of ast.Text.
+		bibtex.NewRenderParsedTextResolver(),
+	}
+	return bibtex.New(bibtex.WithResolvers(append(resolvers, extra...)...))
+}
+
+// parseBibliography parses and resolves the entries of a bibliography file,
+// and returns them with their origins. Each field is resolved on its own, so
+// that errors point at the entry and field that caused them.
+func parseBibliography(name string, src []byte, resolvers []bibtex.Resolver, decoder latex.Resolver) ([]bibtex.Entry, []Origin, error) {
+	fset := token.NewFileSet()
+	file, err := bibtexParser.ParseFile(fset, name, src, bibtexParser.ParseStrings)
 	if err != nil {
-		panic(err.Error())
+		var list scanner.ErrorList
+		if !errors.As(err, &list) {
+			return nil, nil, &BibError{File: name, Err: err}
+		}
+		// The parser may report an error several times while it recovers
+		list.Sort()
+		var errs []error
+		for i, e := range list {
+			if i > 0 && e.Pos == list[i-1].Pos && e.Msg == list[i-1].Msg {
+				continue
+			}
+			errs = append(errs, &BibError{File: name, Line: e.Pos.Line, Err: errors.New(e.Msg)})
+		}
+		return nil, nil, joinErrors(errs)
+	}
+
+	biber := newBiber(resolvers, decoder)
+	var entries []bibtex.Entry
+	var origins []Origin
+	var errs []error
+	for _, node := range file.Entries {
+		decl, ok := node.(*bibtexAst.BibDecl)
+		if !ok {
+			continue
+		}
+		entry := bibtex.Entry{
+			Type: decl.Type,
+			Key:  decl.Key.Name,
+			Tags: make(map[string]bibtexAst.Expr, len(decl.Tags)),
+		}
+		for _, tag := range decl.Tags {
+			resolved, err := biber.Resolve(&bibtexAst.BibDecl{
+				Type: decl.Type,
+				Key:  decl.Key,
+				Tags: []*bibtexAst.TagStmt{tag},
+			})
+			if err != nil {
+				errs = append(errs, &BibError{
+					File:  name,
+					Line:  fset.Position(tag.NamePos).Line,
+					Key:   entry.Key,
+					Field: tag.Name,
+					Err:   err,
+				})
+				continue
+			}
+			entry.Tags[tag.Name] = resolved[0].Tags[tag.Name]
+		}
+		entries = append(entries, entry)
+		origins = append(origins, Origin{File: name, Line: fset.Position(decl.Entry).Line})
 	}
+	if len(errs) > 0 {
+		return nil, nil, joinErrors(errs)
+	}
+	return entries, origins, nil
+}
+
+// joinErrors returns a single error as is, and joins several errors.
+func joinErrors(errs []error) error {
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return errors.Join(errs...)
+}
 
-	return &Extender{
-		Bibliography: entries,
-	}, nil
+// newTransformer returns the bibliography transformer of the extender.
+func (e *Extender) newTransformer() *bibliographyTransformer {
+	t := newBibliographyTransformer(e.Bibliography, e.style, e.warn)
+	t.link = e.link
+	t.nocite = e.nocite
+	t.minCrossrefs = e.minCrossrefs
+	t.extender = e
+	return t
 }
 
 // Extend implements goldmark.Extender interface.
@@ -54,11 +279,16 @@ func (e *Extender) Extend(m goldmark.Markdown) {
 	m.Parser().AddOptions(
 		parser.WithInlineParsers(
 			util.Prioritized(NewCitationParser(), 100),
+			// Must run before the link parser, which also triggers on '['
+			util.Prioritized(NewCitationGroupParser(), 100),
+		),
+		parser.WithASTTransformers(
+			util.Prioritized(e.newTransformer(), 100),
 		),
 	)
 	m.Renderer().AddOptions(
 		renderer.WithNodeRenderers(
-			util.Prioritized(NewCitationRenderer(e.Bibliography), 100),
+			util.Prioritized(NewStyleCitationRenderer(e.style), 100),
 		),
 	)
 }
@@ -2,14 +2,32 @@ package bibtex
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/jschaf/bibtex"
 	"github.com/jschaf/bibtex/ast"
+	"github.com/lmondada/goldmark-bibtex/acm"
+	"github.com/lmondada/goldmark-bibtex/apa"
+	"github.com/lmondada/goldmark-bibtex/chicago"
+	"github.com/lmondada/goldmark-bibtex/cite"
+	"github.com/lmondada/goldmark-bibtex/csl"
+	"github.com/lmondada/goldmark-bibtex/ieee"
+	"github.com/lmondada/goldmark-bibtex/latex"
 	"github.com/yuin/goldmark"
+	meta "github.com/yuin/goldmark-meta"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
 )
 
 const testBibContent = `@InProceedings{Albert1989,
@@ -38,11 +56,9 @@ func createTempBibFile(t *testing.T, content string) string {
 	return tmpfile.Name()
 }
 
-func verifyBibliography(t *testing.T, bibExtender *Extender) {
+func findEntry(t *testing.T, bibExtender *Extender, key string) *bibtex.Entry {
 	t.Helper()
-	// Find entry
 	var entry *bibtex.Entry
-	key := "Albert1989"
 	for _, e := range bibExtender.Bibliography {
 		if e.Key == key {
 			if entry != nil {
@@ -54,6 +70,12 @@ func verifyBibliography(t *testing.T, bibExtender *Extender) {
 	if entry == nil {
 		t.Fatalf("Expected to find entry with key '%s'", key)
 	}
+	return entry
+}
+
+func verifyBibliography(t *testing.T, bibExtender *Extender) {
+	t.Helper()
+	entry := findEntry(t, bibExtender, "Albert1989")
 
 	// Verify the entry type
 	if entry.Type != "inproceedings" {
@@ -70,7 +92,7 @@ func verifyBibliography(t *testing.T, bibExtender *Extender) {
 		{"booktitle", "Foundations of Software Technology and Theoretical Computer Science, Ninth Conference, Bangalore, India, December 19-21, 1989, Proceedings"},
 		{"publisher", "Springer"},
 		{"year", "1989"},
-		{"pages", "223--241"},
+		{"pages", "223–241"},
 	}
 
 	for _, tt := range tests {
@@ -87,9 +109,8 @@ func verifyBibliography(t *testing.T, bibExtender *Extender) {
 				t.Errorf("Expected name to be %s, got %q", tt.expected, firstLastName)
 			}
 		case ast.KindText:
-			got := got.(*ast.Text)
-			if got.Value != tt.expected {
-				t.Errorf("Field %s = %q; want %s", tt.field, got.Value, tt.expected)
+			if got := cite.Text(got); got != tt.expected {
+				t.Errorf("Field %s = %q; want %s", tt.field, got, tt.expected)
 			}
 		default:
 			t.Errorf("Unexpected type for field %s: %T", tt.field, got)
@@ -109,8 +130,10 @@ func verifyMarkdownConversion(t *testing.T, bibExtender *Extender) {
 		t.Fatal(err)
 	}
 
-	citationExp := `<span class="citation-key">Albert, 1989</span><span class="citation-full"><span class="author">Albert Luc</span> (1989). <span class="title">Average Case Complexity Analysis of RETE Pattern-Match Algorithm and Average Size of Join in Database</span>. In <span class="booktitle">Foundations of Software Technology and Theoretical Computer Science, Ninth Conference, Bangalore, India, December 19-21, 1989, Proceedings</span> (pp. <span class="pages">223--241</span>).</span>`
-	expected := fmt.Sprintf("<p>As shown in %s, the results are significant.</p>\n", citationExp)
+	entry := findEntry(t, bibExtender, "Albert1989")
+	citationExp := `<span class="citation"><span data-bibtex-key="Albert1989" class="citation-key">Albert (1989)</span></span>`
+	referencesExp := fmt.Sprintf("<section class=\"references\">\n<ul>\n<li id=\"ref-Albert1989\">%s</li>\n</ul>\n</section>\n", acm.FormatCitation(entry))
+	expected := fmt.Sprintf("<p>As shown in %s, the results are significant.</p>\n%s", citationExp, referencesExp)
 	if got := buf.String(); got != expected {
 		t.Errorf("Markdown conversion = %s; want %s", got, expected)
 	}
@@ -140,3 +163,1300 @@ func TestBibTeXParsingFromFile(t *testing.T) {
 	verifyBibliography(t, bibExtender)
 	verifyMarkdownConversion(t, bibExtender)
 }
+
+func TestConstructors(t *testing.T) {
+	fsys := fstest.MapFS{"refs.bib": {Data: []byte(testBibContent)}}
+	fromFS, err := NewFromFS(fsys, "refs.bib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromReader, err := NewFromReader(strings.NewReader(testBibContent))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromBytes, err := NewFromBytes([]byte(testBibContent))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromEntries := NewFromEntries(fromBytes.Bibliography)
+
+	for _, bibExtender := range []*Extender{fromFS, fromReader, fromBytes, fromEntries} {
+		verifyBibliography(t, bibExtender)
+		verifyMarkdownConversion(t, bibExtender)
+	}
+
+	_, err = NewFromBytes([]byte("@Article{A,\n  title {x},\n}"))
+	if got, want := fmt.Sprint(err), "line 2: "; !strings.HasPrefix(got, want) {
+		t.Errorf("NewFromBytes error = %q; want prefix %q", got, want)
+	}
+}
+
+func TestNewFromEntriesCopies(t *testing.T) {
+	entries := []bibtex.Entry{{
+		Type: "report",
+		Key:  "Doe2021",
+		Tags: map[string]ast.Expr{
+			"title":       &ast.Text{Value: "A Report"},
+			"date":        &ast.Text{Value: "2021-03"},
+			"institution": &ast.Text{Value: "CERN"},
+		},
+	}}
+	for i := 0; i < 2; i++ {
+		bibExtender := NewFromEntries(entries)
+		entry := findEntry(t, bibExtender, "Doe2021")
+		if entry.Type != "techreport" || cite.Field(entry, "year") != "2021" || cite.Field(entry, "month") != "mar" {
+			t.Errorf("call %d: entry = @%s, year %q, month %q; want a normalized @techreport",
+				i+1, entry.Type, cite.Field(entry, "year"), cite.Field(entry, "month"))
+		}
+	}
+	// The entries of the caller are not normalized
+	if entries[0].Type != "report" || len(entries[0].Tags) != 3 {
+		t.Errorf("entries = @%s with %d fields; want them unchanged", entries[0].Type, len(entries[0].Tags))
+	}
+}
+
+func TestWithResolvers(t *testing.T) {
+	var fields []string
+	resolver := bibtex.ResolverFunc(func(n ast.Node) error {
+		for _, tag := range n.(*ast.BibDecl).Tags {
+			fields = append(fields, tag.Name)
+			if _, ok := tag.Value.(*ast.Text); !ok && tag.Name != "author" {
+				return fmt.Errorf("field %s not simplified: %T", tag.Name, tag.Value)
+			}
+		}
+		return nil
+	})
+	if _, err := NewFromBytes([]byte(testBibContent), WithResolvers(resolver)); err != nil {
+		t.Fatal(err)
+	}
+	if len(fields) != 6 {
+		t.Errorf("resolver called on fields %v; want the 6 fields of the entry", fields)
+	}
+
+	failing := bibtex.ResolverFunc(func(ast.Node) error { return errors.New("rejected") })
+	_, err := NewFromBytes([]byte(testBibContent), WithResolvers(failing))
+	var bibErr *BibError
+	if !errors.As(err, &bibErr) || bibErr.Key != "Albert1989" {
+		t.Errorf("NewFromBytes error = %v; want a *BibError for Albert1989", err)
+	}
+}
+
+func TestCitationGroup(t *testing.T) {
+	bibFile := createTempBibFile(t, testBibContent)
+	bibExtender, err := New(bibFile, WithStyle(apa.Style{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	markdown := goldmark.New(
+		goldmark.WithExtensions(bibExtender),
+	)
+
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{
+			source:   "[see @Albert1989, pp. 223-225, and passim; also @missing]",
+			expected: `<p><span class="citation">(see <span data-bibtex-key="Albert1989" class="citation-key">Albert, 1989</span>, pp. 223-225, and passim; also ?)</span></p>` + "\n",
+		},
+		{
+			source:   "Albert says [-@Albert1989, p. 230].",
+			expected: `<p>Albert says <span class="citation">(<span data-bibtex-key="Albert1989" class="citation-key">1989</span>, p. 230)</span>.</p>` + "\n",
+		},
+		{
+			source:   "@Albert1989 [p. 230] says",
+			expected: `<p><span class="citation"><span data-bibtex-key="Albert1989" class="citation-key">Albert (1989, p. 230)</span></span> says</p>` + "\n",
+		},
+		{
+			source:   "As shown [see @Albert1989;\n  also @missing, p. 4] and\n[@Albert1989]{.nocite} more.",
+			expected: `<p>As shown <span class="citation">(see <span data-bibtex-key="Albert1989" class="citation-key">Albert, 1989</span>; also ?, p. 4)</span> and` + "\n" + ` more.</p>` + "\n",
+		},
+		{
+			source:   "[a link](https://example.com) and [no citation]",
+			expected: `<p><a href="https://example.com">a link</a> and [no citation]</p>` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := markdown.Convert([]byte(tt.source), &buf); err != nil {
+			t.Fatal(err)
+		}
+		// The reference list is covered by verifyMarkdownConversion
+		got, _, _ := strings.Cut(buf.String(), `<section class="references">`)
+		if got != tt.expected {
+			t.Errorf("Markdown conversion of %q = %s; want %s", tt.source, got, tt.expected)
+		}
+	}
+}
+
+func TestSplitLocator(t *testing.T) {
+	tests := []struct {
+		rest    string
+		locator string
+		suffix  string
+	}{
+		{", pp. 33-35, 38, and passim", "pp. 33-35, 38", ", and passim"},
+		{", ch. 1", "ch. 1", ""},
+		{" and elsewhere", "", " and elsewhere"},
+		{", chapters and more", "", ", chapters and more"},
+		{", §12", "§12", ""},
+	}
+	for _, tt := range tests {
+		locator, suffix := splitLocator(tt.rest)
+		if locator != tt.locator || suffix != tt.suffix {
+			t.Errorf("splitLocator(%q) = %q, %q; want %q, %q", tt.rest, locator, suffix, tt.locator, tt.suffix)
+		}
+	}
+}
+
+func TestBibliographyTransformer(t *testing.T) {
+	bibExtender, err := New(filepath.Join("testdata", "refs.bib"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	markdown := goldmark.New(
+		goldmark.WithExtensions(bibExtender),
+	)
+
+	source := []byte("@Ren2016 and [@Albert1989; @missing].\n\nAgain @Ren2016 [p. 3].")
+	doc := markdown.Parser().Parse(text.NewReader(source))
+
+	bib, ok := doc.LastChild().(*Bibliography)
+	if !ok {
+		t.Fatalf("Expected the last node to be a Bibliography, got %T", doc.LastChild())
+	}
+	var keys []string
+	for _, ref := range bib.References {
+		keys = append(keys, ref.Entry.Key)
+	}
+	// Sorted alphabetically by the default style
+	if got, want := strings.Join(keys, ","), "Albert1989,Ren2016"; got != want {
+		t.Errorf("Bibliography entries = %s; want %s", got, want)
+	}
+}
+
+func TestBibliographyMarker(t *testing.T) {
+	bibExtender, err := New(filepath.Join("testdata", "refs.bib"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	markdown := goldmark.New(
+		goldmark.WithExtensions(bibExtender),
+	)
+
+	markers := []string{
+		"[bibliography]",
+		"::: {#refs}\n:::",
+		`<div id="refs"></div>`,
+	}
+	for _, marker := range markers {
+		source := []byte("# Text\n\nSee @Ren2016.\n\n# References\n\n" + marker + "\n\n# Appendix\n\nMore text.\n")
+		doc := markdown.Parser().Parse(text.NewReader(source))
+
+		var kinds []string
+		for c := doc.FirstChild(); c != nil; c = c.NextSibling() {
+			kinds = append(kinds, c.Kind().String())
+		}
+		got := strings.Join(kinds, ",")
+		want := "Heading,Paragraph,Heading,Bibliography,Heading,Paragraph"
+		if got != want {
+			t.Errorf("Document with marker %q = %s; want %s", marker, got, want)
+		}
+	}
+}
+
+func TestCSLStyle(t *testing.T) {
+	style, err := csl.LoadFS(os.DirFS("testdata"), "author-date.csl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := style.Title(), "Author-Date Test Style"; got != want {
+		t.Errorf("Title() = %q; want %q", got, want)
+	}
+
+	bibExtender, err := New(filepath.Join("testdata", "refs.bib"), WithStyle(style))
+	if err != nil {
+		t.Fatal(err)
+	}
+	markdown := goldmark.New(
+		goldmark.WithExtensions(bibExtender),
+	)
+
+	source := []byte("@Bunke1990 [p. 3] and [see @Mahfoud2020, pp. 3-5; -@Raussendorf_2001]")
+	var buf bytes.Buffer
+	if err := markdown.Convert(source, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<p><span class="citation"><span data-bibtex-key="Bunke1990" class="citation-key">Bunke et al. (1990, p. 3)</span></span> and ` +
+		`<span class="citation">(see <span data-bibtex-key="Mahfoud2020" class="citation-key">Mahfoud, 2020, pp. 3–5</span>; <span data-bibtex-key="Raussendorf_2001" class="citation-key">2001</span>)</span></p>
+<section class="references">
+<ul>
+<li id="ref-Bunke1990"><span class="citation-full">Bunke, H., Glauser, T., &amp; Tran, T.-H. (1990). An Efficient Implementation of Graph Grammars Based on the RETE Matching Algorithm. In <i>Graph-Grammars and Their Application to Computer Science, 4th International Workshop, Bremen, Germany, March 5-9, 1990, Proceedings</i> (pp. 174–189). Springer.</span></li>
+<li id="ref-Mahfoud2020"><span class="citation-full">Mahfoud, H. (2020). Graph pattern matching with counting quantifiers and label-repetition constraints. <i>Cluster Computing</i>, <i>23</i>(3), 1529–1553.</span></li>
+<li id="ref-Raussendorf_2001"><span class="citation-full">Raussendorf, R., &amp; Briegel, H. J. (2001). A One-Way Quantum Computer. <i>Physical Review Letters</i>, <i>86</i>(22), 5188–5191. American Physical Society (APS).</span></li>
+</ul>
+</section>
+`
+	if got := buf.String(); got != expected {
+		t.Errorf("Markdown conversion = %s; want %s", got, expected)
+	}
+}
+
+func TestNumericStyle(t *testing.T) {
+	bibExtender, err := New(filepath.Join("testdata", "refs.bib"), WithStyle(acm.Style{Numeric: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	markdown := goldmark.New(
+		goldmark.WithExtensions(bibExtender),
+	)
+
+	source := []byte("[@Ren2016; @Albert1989; @Bunke1990] then [@Mahfoud2020; @Albert1989; @Ren2016; @Bunke1990] and @Ren2016 [p. 3].")
+	var buf bytes.Buffer
+	if err := markdown.Convert(source, &buf); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+
+	expected := `<p><span class="citation">[<span data-bibtex-key="Ren2016 Albert1989 Bunke1990" class="citation-key">1–3</span>]</span> then ` +
+		`<span class="citation">[<span data-bibtex-key="Ren2016 Albert1989 Bunke1990 Mahfoud2020" class="citation-key">1–4</span>]</span> and ` +
+		`<span class="citation">Ren and Wang [<span data-bibtex-key="Ren2016" class="citation-key">1</span>, p. 3]</span>.</p>`
+	if citations, _, _ := strings.Cut(got, "\n"); citations != expected {
+		t.Errorf("Numeric citations = %s; want %s", citations, expected)
+	}
+
+	// The reference list is numbered in order of first citation
+	var keys []string
+	for _, key := range []string{"Ren2016", "Albert1989", "Bunke1990", "Mahfoud2020"} {
+		keys = append(keys, fmt.Sprintf(`<li id="ref-%s">`, key))
+	}
+	if !strings.Contains(got, "<ol>\n") {
+		t.Errorf("Expected a numbered reference list, got %s", got)
+	}
+	last := -1
+	for _, key := range keys {
+		i := strings.Index(got, key)
+		if i <= last {
+			t.Errorf("Expected %s after the previous reference, got %s", key, got)
+		}
+		last = i
+	}
+}
+
+func TestIEEEStyle(t *testing.T) {
+	bibExtender, err := New(filepath.Join("testdata", "refs.bib"), WithStyle(ieee.Style{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key      string
+		expected string
+	}{
+		{
+			key: "Raussendorf_2001",
+			expected: `<span class="citation-full"><span class="authors">R. <span class="last-name">Raussendorf</span> and H. J. <span class="last-name">Briegel</span></span>, “A One-Way Quantum Computer,” <em>Physical Review Letters</em>, vol. 86, no. 22, pp. 5188–5191, May 2001, ` +
+				`doi: <a href="https://doi.org/10.1103/PhysRevLett.86.5188">10.1103/PhysRevLett.86.5188</a>.</span>`,
+		},
+		{
+			key: "Albert1989",
+			expected: `<span class="citation-full"><span class="authors">L. <span class="last-name">Albert</span></span>, “Average Case Complexity Analysis of RETE Pattern-Match Algorithm and Average Size of Join in Database,” ` +
+				`in <em>Foundations of Software Technology and Theoretical Computer Science, Ninth Conference, Bangalore, India, December 19-21, 1989, Proceedings</em>, Springer, 1989, pp. 223–241, ` +
+				`doi: <a href="https://doi.org/10.1007/3-540-52048-1_46">10.1007/3-540-52048-1_46</a>.</span>`,
+		},
+		{
+			key: "Brown2310",
+			expected: `<span class="citation-full"><span class="authors">N. C. <span class="last-name">Brown</span> <i>et al.</i></span>, “Advances in compilation for quantum hardware – A demonstration of magic state distillation and repeat-until-success protocols,” 2023, ` +
+				`doi: <a href="https://doi.org/10.48550/ARXIV.2310.12106">10.48550/ARXIV.2310.12106</a>.</span>`,
+		},
+	}
+	for _, tt := range tests {
+		if got := ieee.FormatCitation(findEntry(t, bibExtender, tt.key)); got != tt.expected {
+			t.Errorf("ieee.FormatCitation(%s) = %s; want %s", tt.key, got, tt.expected)
+		}
+	}
+
+	markdown := goldmark.New(
+		goldmark.WithExtensions(bibExtender),
+	)
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte("@Raussendorf_2001 and [@Mahfoud2020, p. 4]"), &buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := `<p><span class="citation">Raussendorf and Briegel [<span data-bibtex-key="Raussendorf_2001" class="citation-key">1</span>]</span> and ` +
+		`<span class="citation">[<span data-bibtex-key="Mahfoud2020" class="citation-key">2</span>, p. 4]</span></p>`
+	if got, _, _ := strings.Cut(buf.String(), "\n"); got != expected {
+		t.Errorf("Markdown conversion = %s; want %s", got, expected)
+	}
+}
+
+func TestChicagoStyle(t *testing.T) {
+	convert := func(style Style, source string) string {
+		bibExtender, err := New(filepath.Join("testdata", "refs.bib"), WithStyle(style))
+		if err != nil {
+			t.Fatal(err)
+		}
+		markdown := goldmark.New(
+			goldmark.WithExtensions(bibExtender),
+		)
+		var buf bytes.Buffer
+		if err := markdown.Convert([]byte(source), &buf); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	got := convert(chicago.AuthorDate{}, "[see @Mahfoud2020, p. 1530; @Raussendorf_2001]")
+	expected := `<p><span class="citation">(see <span data-bibtex-key="Mahfoud2020" class="citation-key">Mahfoud 2020</span>, 1530; ` +
+		`<span data-bibtex-key="Raussendorf_2001" class="citation-key">Raussendorf and Briegel 2001</span>)</span></p>`
+	if got, _, _ := strings.Cut(got, "\n"); got != expected {
+		t.Errorf("Author-date citation = %s; want %s", got, expected)
+	}
+
+	got = convert(chicago.Notes{}, "A claim.[@Raussendorf_2001, p. 5189] As @Raussendorf_2001 show.")
+	expected = `<p>A claim.<span class="citation"><sup class="footnote-ref"><a href="#cite-note-1" id="cite-noteref-1">1</a></sup></span> ` +
+		`As <span class="citation"><span data-bibtex-key="Raussendorf_2001" class="citation-key">Raussendorf and Briegel</span><sup class="footnote-ref"><a href="#cite-note-2" id="cite-noteref-2">2</a></sup></span> show.</p>
+<section class="footnotes citation-notes">
+<ol>
+<li id="cite-note-1"><span data-bibtex-key="Raussendorf_2001" class="citation-key">Robert Raussendorf and Hans J. Briegel, “A One-Way Quantum Computer,” <em>Physical Review Letters</em> 86, no. 22 (May 2001): 5189, ` +
+		`<a href="https://doi.org/10.1103/PhysRevLett.86.5188">https://doi.org/10.1103/PhysRevLett.86.5188</a></span>. <a href="#cite-noteref-1" class="footnote-backref">↩︎</a></li>
+<li id="cite-note-2"><span data-bibtex-key="Raussendorf_2001" class="citation-key">Raussendorf and Briegel, “A One-Way Quantum Computer.”</span> <a href="#cite-noteref-2" class="footnote-backref">↩︎</a></li>
+</ol>
+</section>
+<section class="references">
+<ul>
+<li id="ref-Raussendorf_2001"><span class="citation-full">Raussendorf, Robert, and Hans J. Briegel. “A One-Way Quantum Computer.” <em>Physical Review Letters</em> 86, no. 22 (May 2001): 5188–5191. ` +
+		`<a href="https://doi.org/10.1103/PhysRevLett.86.5188">https://doi.org/10.1103/PhysRevLett.86.5188</a>.</span></li>
+</ul>
+</section>
+`
+	if got != expected {
+		t.Errorf("Notes-bibliography conversion = %s; want %s", got, expected)
+	}
+}
+
+func TestDisambiguation(t *testing.T) {
+	keySpan := regexp.MustCompile(`<span data-bibtex-key="[^"]*" class="citation-key">([^<]*)</span>`)
+	cslStyle, err := csl.LoadFS(os.DirFS("testdata"), "author-date.csl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		style    Style
+		expected string
+	}{
+		{
+			style:    apa.Style{},
+			expected: `(J. Smith & Doe, 2020b; J. Smith & Doe, 2020a; A. Smith, 2019; Lee, Kim, et al., 2021; Lee, Cho, et al., 2021)`,
+		},
+		{
+			style:    acm.Style{},
+			expected: `(Smith and Doe 2020b; Smith and Doe 2020a; Smith 2019; Lee et al. 2021a; Lee et al. 2021b)`,
+		},
+		{
+			style:    cslStyle,
+			expected: `(J. Smith &amp; Doe, 2020a; J. Smith &amp; Doe, 2020b; A. Smith, 2019; Lee, Kim, et al., 2021; Lee, Cho, et al., 2021)`,
+		},
+	}
+	for _, tt := range tests {
+		bibExtender, err := New(filepath.Join("testdata", "disambiguation.bib"), WithStyle(tt.style))
+		if err != nil {
+			t.Fatal(err)
+		}
+		markdown := goldmark.New(
+			goldmark.WithExtensions(bibExtender),
+		)
+		var buf bytes.Buffer
+		source := []byte("[@SmithB2020; @SmithA2020; @SmithAnna2019; @LeeKim2021; @LeeCho2021]")
+		if err := markdown.Convert(source, &buf); err != nil {
+			t.Fatal(err)
+		}
+
+		citation, references, _ := strings.Cut(buf.String(), "\n")
+		got := keySpan.ReplaceAllString(citation, "$1")
+		want := `<p><span class="citation">` + tt.expected + `</span></p>`
+		if got != want {
+			t.Errorf("%T citation = %s; want %s", tt.style, got, want)
+		}
+		// The reference list shows the same year suffixes
+		for _, suffix := range []string{"2020a", "2020b"} {
+			if !strings.Contains(references, suffix) {
+				t.Errorf("%T reference list has no %s: %s", tt.style, suffix, references)
+			}
+		}
+	}
+}
+
+func TestEtAl(t *testing.T) {
+	keySpan := regexp.MustCompile(`<span data-bibtex-key="[^"]*" class="citation-key">([^<]*)</span>`)
+	tests := []struct {
+		style     Style
+		source    string
+		citation  string
+		reference string
+	}{
+		{
+			style:     apa.Style{},
+			source:    "[@LeeKim2021; @SmithA2020]",
+			citation:  `(Lee et al., 2021; Smith & Doe, 2020)`,
+			reference: `<span class="author">Kim Ji</span>, & <span class="author">Park Soo</span> (2021)`,
+		},
+		{
+			style:     apa.Style{EtAl: cite.EtAl{Reference: cite.Truncation{Min: 3, UseFirst: 1}}},
+			source:    "@LeeKim2021",
+			citation:  `Lee et al. (2021)`,
+			reference: `<span class="author">Lee Min</span>, et al. (2021)`,
+		},
+		{
+			style:     acm.Style{EtAl: cite.EtAl{Inline: cite.Truncation{Min: 4, UseFirst: 1}}},
+			source:    "[@LeeKim2021]",
+			citation:  `(Lee, Kim and Park 2021)`,
+			reference: `<span class="last-name">Kim</span> and Soo  <span class="last-name">Park</span>`,
+		},
+		{
+			style:     ieee.Style{EtAl: cite.EtAl{Reference: cite.Truncation{Min: 3, UseFirst: 1}}},
+			source:    "@LeeKim2021",
+			citation:  `Lee <i>et al.</i> [1]`,
+			reference: `<span class="last-name">Lee</span> <i>et al.</i></span>, “Quantum dots,”`,
+		},
+		{
+			style:     chicago.AuthorDate{EtAl: cite.EtAl{Inline: cite.Truncation{Min: 2, UseFirst: 1}}},
+			source:    "[@SmithA2020]",
+			citation:  `(Smith et al. 2020)`,
+			reference: `Smith, John, and Jane Doe. 2020.`,
+		},
+	}
+	for _, tt := range tests {
+		bibExtender, err := New(filepath.Join("testdata", "disambiguation.bib"), WithStyle(tt.style))
+		if err != nil {
+			t.Fatal(err)
+		}
+		markdown := goldmark.New(
+			goldmark.WithExtensions(bibExtender),
+		)
+		var buf bytes.Buffer
+		if err := markdown.Convert([]byte(tt.source), &buf); err != nil {
+			t.Fatal(err)
+		}
+
+		citation, references, _ := strings.Cut(buf.String(), "\n")
+		got := keySpan.ReplaceAllString(citation, "$1")
+		want := `<p><span class="citation">` + tt.citation + `</span></p>`
+		if got != want {
+			t.Errorf("%T citation = %s; want %s", tt.style, got, want)
+		}
+		if !strings.Contains(references, tt.reference) {
+			t.Errorf("%T reference list has no %s: %s", tt.style, tt.reference, references)
+		}
+	}
+}
+
+func TestIncompleteEntries(t *testing.T) {
+	cslStyle, err := csl.LoadFS(os.DirFS("testdata"), "author-date.csl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := []byte("[@NoAuthor; @EditedBook; @OrgReport]\n\n[@Missing]")
+
+	tests := []struct {
+		style    Style
+		citation string
+	}{
+		{apa.Style{}, `(Anonymous Notes, n.d.; Doe & Roe, 2018; World Health Organization, 2022)`},
+		{acm.Style{}, `(Anonymous Notes n.d.; Doe and Roe 2018; World Health Organization 2022)`},
+		{ieee.Style{}, `[1–3]`},
+		{chicago.AuthorDate{}, `(“Anonymous Notes” n.d.; Doe and Roe 2018; World Health Organization 2022)`},
+		{chicago.Notes{}, `<sup class="footnote-ref"><a href="#cite-note-1" id="cite-noteref-1">1</a></sup>`},
+		{cslStyle, `(<i>Anonymous Notes</i>, n.d.; Doe &amp; Roe, 2018; <i>Annual Report</i>, 2022)`},
+	}
+	keySpan := regexp.MustCompile(`<span data-bibtex-key="[^"]*" class="citation-key">(.*?)</span>`)
+	for _, tt := range tests {
+		var warnings []string
+		bibExtender, err := New(filepath.Join("testdata", "incomplete.bib"), WithStyle(tt.style), WithWarningHandler(func(w cite.Warning) {
+			warnings = append(warnings, w.Error())
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		markdown := goldmark.New(
+			goldmark.WithExtensions(bibExtender),
+		)
+		var buf bytes.Buffer
+		if err := markdown.Convert(source, &buf); err != nil {
+			t.Fatal(err)
+		}
+
+		citation, _, _ := strings.Cut(buf.String(), "\n")
+		got := keySpan.ReplaceAllString(citation, "$1")
+		want := `<p><span class="citation">` + tt.citation + `</span></p>`
+		if got != want {
+			t.Errorf("%T citation = %s; want %s", tt.style, got, want)
+		}
+
+		expected := []string{
+			`entry "NoAuthor": field "author": no author, editor or organization, the title is used instead`,
+			`entry "NoAuthor": field "year": no year, n.d. is used instead`,
+			`entry "Missing": not found in the bibliography`,
+		}
+		if strings.Join(warnings, "\n") != strings.Join(expected, "\n") {
+			t.Errorf("%T warnings = %q; want %q", tt.style, warnings, expected)
+		}
+	}
+
+	// Fields of unexpected shapes are ignored
+	entry := &bibtex.Entry{
+		Type: "misc",
+		Key:  "Unresolved",
+		Tags: map[string]ast.Expr{
+			"author": &ast.Text{Value: "Smith, John"},
+			"title":  ast.Authors{},
+			"year":   &ast.Number{Value: "2020"},
+		},
+	}
+	if got, want := apa.FormatCitation(entry), `<span class="citation-full">? (2020).</span>`; got != want {
+		t.Errorf("apa.FormatCitation = %s; want %s", got, want)
+	}
+	if got, want := acm.FormatCitation(entry), `<span class="citation-full">2020.</span>`; got != want {
+		t.Errorf("acm.FormatCitation = %s; want %s", got, want)
+	}
+
+	// Missing fields are left out, and references end with one period
+	titled := &bibtex.Entry{
+		Type: "article",
+		Key:  "OnlyTitle",
+		Tags: map[string]ast.Expr{
+			"title": &ast.Text{Value: "Only a title"},
+		},
+	}
+	if got, want := apa.FormatCitation(titled), `<span class="citation-full"><span class="title">Only a title</span> (n.d.).</span>`; got != want {
+		t.Errorf("apa.FormatCitation = %s; want %s", got, want)
+	}
+	if got, want := acm.FormatCitation(titled), `<span class="citation-full">Only a Title. n.d.</span>`; got != want {
+		t.Errorf("acm.FormatCitation = %s; want %s", got, want)
+	}
+	if got := cite.Check(entry); len(got) != 2 || got[0].Field != "author" {
+		t.Errorf("cite.Check = %v; want warnings for the author and the missing lead", got)
+	}
+}
+
+func TestBibError(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    BibError
+		count   int
+	}{
+		{
+			name: "field",
+			content: `@Article{Good2020,
+  author = {Smith, John},
+  year   = {2020},
+}
+
+@Article{Bad2021,
+  title  = {Numbers},
+  author = 2021,
+}`,
+			want:  BibError{Line: 8, Key: "Bad2021", Field: "author"},
+			count: 1,
+		},
+		{
+			name: "syntax",
+			content: `@Article{Good2020,
+  author = {Smith, John},
+  year   = {2020},
+  title  {Missing equals sign},
+}`,
+			want:  BibError{Line: 4},
+			count: 1,
+		},
+		{
+			name: "unterminated",
+			content: `@Article{Bad2021,
+  title  = {Unterminated
+`,
+			want:  BibError{Line: 2},
+			count: 1,
+		},
+	}
+	for _, tt := range tests {
+		bibFile := createTempBibFile(t, tt.content)
+		_, err := New(bibFile)
+		var bibErr *BibError
+		if !errors.As(err, &bibErr) {
+			t.Fatalf("%s: New error = %v; want a *BibError", tt.name, err)
+		}
+		if bibErr.File != bibFile || bibErr.Line != tt.want.Line || bibErr.Key != tt.want.Key || bibErr.Field != tt.want.Field {
+			t.Errorf("%s: New error = %+v; want %+v", tt.name, *bibErr, tt.want)
+		}
+		if !strings.HasPrefix(err.Error(), fmt.Sprintf("%s:%d: ", bibFile, tt.want.Line)) {
+			t.Errorf("%s: New error = %q; want a file:line: prefix", tt.name, err)
+		}
+		// Each error is reported once
+		count := 1
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			count = len(joined.Unwrap())
+		}
+		if count != tt.count {
+			t.Errorf("%s: New returned %d errors; want %d:\n%v", tt.name, count, tt.count, err)
+		}
+	}
+}
+
+func TestNewFromSources(t *testing.T) {
+	shared := Source{Name: "shared.bib", Data: []byte(`@Book{Smith2020,
+  author = {Smith, John},
+  title  = {Shared},
+  year   = {2020},
+}
+
+@Book{Doe2019,
+  author = {Doe, Jane},
+  title  = {Only Shared},
+  year   = {2019},
+}`)}
+	project := Source{Name: "project.bib", Data: []byte(`@Book{Smith2020,
+  author = {Smith, John},
+  title  = {Project},
+  year   = {2020},
+}`)}
+
+	tests := []struct {
+		policy  DuplicatePolicy
+		title   string
+		kept    Origin
+		dropped Origin
+	}{
+		{DuplicateFirstWins, "Shared", Origin{"shared.bib", 1}, Origin{"project.bib", 1}},
+		{DuplicateLastWins, "Project", Origin{"project.bib", 1}, Origin{"shared.bib", 1}},
+	}
+	for _, tt := range tests {
+		bibExtender, err := NewFromSources([]Source{shared, project}, WithDuplicatePolicy(tt.policy))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(bibExtender.Bibliography) != 2 {
+			t.Fatalf("policy %d: got %d entries; want 2", tt.policy, len(bibExtender.Bibliography))
+		}
+		if got := cite.Field(findEntry(t, bibExtender, "Smith2020"), "title"); got != tt.title {
+			t.Errorf("policy %d: title = %q; want %q", tt.policy, got, tt.title)
+		}
+		if got := bibExtender.Origins["Doe2019"]; got != (Origin{"shared.bib", 7}) {
+			t.Errorf("policy %d: origin of Doe2019 = %v; want shared.bib:7", tt.policy, got)
+		}
+		want := []Duplicate{{Key: "Smith2020", Kept: tt.kept, Dropped: tt.dropped}}
+		if !reflect.DeepEqual(bibExtender.Duplicates, want) {
+			t.Errorf("policy %d: duplicates = %v; want %v", tt.policy, bibExtender.Duplicates, want)
+		}
+	}
+
+	_, err := NewFromSources([]Source{shared, project}, WithDuplicatePolicy(DuplicateError))
+	var bibErr *BibError
+	if !errors.As(err, &bibErr) || !errors.Is(err, ErrDuplicateKey) {
+		t.Fatalf("NewFromSources error = %v; want a duplicate key *BibError", err)
+	}
+	if want := `project.bib:1: entry "Smith2020": duplicate entry key, first defined at shared.bib:1`; err.Error() != want {
+		t.Errorf("NewFromSources error = %q; want %q", err, want)
+	}
+}
+
+func TestFrontMatter(t *testing.T) {
+	bibExtender, err := NewFromBytes([]byte(testBibContent), WithFrontMatter(os.DirFS("testdata")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	markdown := goldmark.New(
+		goldmark.WithExtensions(meta.Meta, bibExtender),
+	)
+
+	source := []byte(`---
+bibliography: refs.bib
+csl: author-date.csl
+link-citations: true
+nocite: "@Mahfoud2020"
+---
+[@Albert1989; @Bunke1990]`)
+	var buf bytes.Buffer
+	if err := markdown.Convert(source, &buf); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	for _, want := range []string{
+		`<span class="citation">(<span data-bibtex-key="Albert1989" class="citation-key"><a href="#ref-Albert1989">Albert, 1989</a></span>; ` +
+			`<span data-bibtex-key="Bunke1990" class="citation-key"><a href="#ref-Bunke1990">Bunke et al., 1990</a></span>)</span>`,
+		`<li id="ref-Albert1989"><span class="citation-full">Albert, L. (1989).`,
+		`<li id="ref-Mahfoud2020">`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Markdown conversion = %s; want it to contain %s", got, want)
+		}
+	}
+
+	// Documents without front matter use the extender as is
+	buf.Reset()
+	if err := markdown.Convert([]byte("[@Albert1989; @Bunke1990]"), &buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); strings.Contains(got, "ref-Bunke1990") || strings.Contains(got, "<a href") {
+		t.Errorf("Markdown conversion = %s; want Bunke1990 unresolved and no links", got)
+	}
+}
+
+func TestBibliographyBlock(t *testing.T) {
+	var warnings []string
+	bibExtender := NewFromEntries(nil, WithWarningHandler(func(w cite.Warning) {
+		warnings = append(warnings, w.Error())
+	}))
+	markdown := goldmark.New(
+		goldmark.WithExtensions(bibExtender),
+	)
+
+	source := []byte("As shown in @Albert1989.\n\n```bibtex\n" + testBibContent + "\n```\n")
+	var buf bytes.Buffer
+	if err := markdown.Convert(source, &buf); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	if strings.Contains(got, "<pre>") || !strings.Contains(got, `<li id="ref-Albert1989">`) {
+		t.Errorf("Markdown conversion = %s; want the block replaced by the reference to Albert1989", got)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("warnings = %v; want none", warnings)
+	}
+
+	// The entries of a block are only available in its document
+	buf.Reset()
+	if err := markdown.Convert([]byte("As shown in @Albert1989."), &buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); !strings.Contains(got, "[?]") {
+		t.Errorf("Markdown conversion = %s; want Albert1989 unresolved", got)
+	}
+
+	// Errors are reported at the lines of the document
+	warnings = nil
+	buf.Reset()
+	if err := markdown.Convert([]byte("Text.\n\n```bib\n@Article{A,\n  title {x},\n}\n```\n"), &buf); err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || !strings.HasPrefix(warnings[0], "line 5: ") {
+		t.Errorf("warnings = %v; want an error at line 5", warnings)
+	}
+}
+
+func TestNocite(t *testing.T) {
+	bibFile := filepath.Join("testdata", "refs.bib")
+	tests := []struct {
+		name   string
+		opts   []Option
+		source string
+		want   []string
+	}{
+		{
+			name:   "group",
+			source: "As shown in @Albert1989.\n\n[@Mahfoud2020; @Bunke1990]{.nocite}\n",
+			want:   []string{"Albert1989", "Bunke1990", "Mahfoud2020"},
+		},
+		{
+			name:   "inline",
+			source: "As shown in @Albert1989.[@Bunke1990]{.nocite}",
+			want:   []string{"Albert1989", "Bunke1990"},
+		},
+		{
+			name:   "option",
+			opts:   []Option{WithNocite("Mahfoud2020")},
+			source: "As shown in @Albert1989.",
+			want:   []string{"Albert1989", "Mahfoud2020"},
+		},
+	}
+	ids := regexp.MustCompile(`<li id="ref-([^"]+)">`)
+	for _, tt := range tests {
+		bibExtender, err := New(bibFile, tt.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		markdown := goldmark.New(
+			goldmark.WithExtensions(bibExtender),
+		)
+		var buf bytes.Buffer
+		if err := markdown.Convert([]byte(tt.source), &buf); err != nil {
+			t.Fatal(err)
+		}
+		got := buf.String()
+		var keys []string
+		for _, m := range ids.FindAllStringSubmatch(got, -1) {
+			keys = append(keys, m[1])
+		}
+		if !reflect.DeepEqual(keys, tt.want) {
+			t.Errorf("%s: references = %v; want %v", tt.name, keys, tt.want)
+		}
+		if strings.Contains(got, "nocite") || strings.Contains(got, "<p></p>") {
+			t.Errorf("%s: Markdown conversion = %s; want the nocite group removed", tt.name, got)
+		}
+	}
+
+	bibExtender, err := New(bibFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	markdown := goldmark.New(
+		goldmark.WithExtensions(bibExtender),
+	)
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte("[@*]{.nocite}"), &buf); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(ids.FindAllString(buf.String(), -1)), len(bibExtender.Bibliography); got != want {
+		t.Errorf("@*: got %d references; want all %d entries", got, want)
+	}
+}
+
+func TestImportFormats(t *testing.T) {
+	fromBib, err := New(filepath.Join("testdata", "refs.bib"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"refs.json", "refs.ris", "refs.xml"} {
+		imported, err := New(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := len(imported.Bibliography); got != 2 {
+			t.Fatalf("%s: got %d entries; want 2", name, got)
+		}
+		for _, style := range []Style{apa.Style{}, acm.Style{}, ieee.Style{}} {
+			for _, key := range []string{"Mahfoud2020", "Bunke1990"} {
+				want := style.FormatReference(cite.Reference{Entry: findEntry(t, fromBib, key)})
+				if got := style.FormatReference(cite.Reference{Entry: findEntry(t, imported, key)}); got != want {
+					t.Errorf("%s: %T: reference of %s = %s; want %s", name, style, key, got, want)
+				}
+			}
+		}
+	}
+
+	tests := []struct {
+		name, content, want string
+	}{
+		{"refs.json", "[\n  {\"type\": \"book\"}\n]", "refs.json:2: missing id"},
+		{"refs.ris", "TY  - BOOK\nTI  - Title\n", "refs.ris:1: record without ER"},
+		{"refs.xml", "<xml>\n<records>\n<record>\n</records>", "refs.xml:4: "},
+	}
+	for _, tt := range tests {
+		_, err = NewFromSources([]Source{{Name: tt.name, Data: []byte(tt.content)}})
+		if !strings.HasPrefix(fmt.Sprint(err), tt.want) {
+			t.Errorf("NewFromSources error = %v; want %s", err, tt.want)
+		}
+	}
+
+	// Keys made for names with accents can be cited
+	imported, err := NewFromSources([]Source{{Name: "refs.ris", Data: []byte("TY  - BOOK\nAU  - Müller, Hans\nTI  - Title\nPY  - 2020\nER  - \n")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := goldmark.New(goldmark.WithExtensions(imported)).Convert([]byte("[@Muller2020]"), &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `data-bibtex-key="Muller2020"`) || !strings.Contains(buf.String(), "Müller") {
+		t.Errorf("citation of Muller2020 = %s", buf.String())
+	}
+}
+
+func TestImportEscaping(t *testing.T) {
+	sources := []Source{
+		{Name: "refs.json", Data: []byte(`[{"id": "Bounds2020", "type": "book", "title": "Bounds for n < m & <script>alert(1)</script>",
+			"author": [{"family": "Smith & <b>Sons</b>"}], "issued": {"date-parts": [[2020]]}}]`)},
+		{Name: "refs.ris", Data: []byte("TY  - BOOK\nID  - Bounds2020\nAU  - Smith & <b>Sons</b>, Pat\n" +
+			"TI  - Bounds for n < m & <script>alert(1)</script>\nPY  - 2020\nER  - \n")},
+		{Name: "refs.xml", Data: []byte(`<xml><records><record><ref-type>6</ref-type><label>Bounds2020</label>` +
+			`<contributors><authors><author>Smith &amp; &lt;b&gt;Sons&lt;/b&gt;, Pat</author></authors></contributors>` +
+			`<titles><title>Bounds for n &lt; m &amp; &lt;script&gt;alert(1)&lt;/script&gt;</title></titles>` +
+			`<dates><year>2020</year></dates></record></records></xml>`)},
+	}
+	for _, source := range sources {
+		imported, err := NewFromSources([]Source{source})
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry := findEntry(t, imported, "Bounds2020")
+		for _, style := range []Style{apa.Style{}, acm.Style{}, ieee.Style{}, chicago.AuthorDate{}} {
+			got := strings.ToLower(style.FormatReference(cite.Reference{Entry: entry}))
+			if strings.Contains(got, "<script") || strings.Contains(got, "<b>") ||
+				!strings.Contains(got, "n &lt; m &amp; &lt;script&gt;") || !strings.Contains(got, "smith &amp; &lt;b&gt;sons") {
+				t.Errorf("%s: %T: reference = %s; want the title and author escaped", source.Name, style, got)
+			}
+		}
+	}
+}
+
+func TestBibLaTeX(t *testing.T) {
+	fromBib, err := New(filepath.Join("testdata", "refs.bib"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	biblatex, err := New(filepath.Join("testdata", "biblatex.bib"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, style := range []Style{apa.Style{}, acm.Style{}, ieee.Style{}} {
+		want := style.FormatReference(cite.Reference{Entry: findEntry(t, fromBib, "Mahfoud2020")})
+		if got := style.FormatReference(cite.Reference{Entry: findEntry(t, biblatex, "Mahfoud2020")}); got != want {
+			t.Errorf("%T: reference = %s; want %s", style, got, want)
+		}
+	}
+
+	fields := []struct {
+		key, typ string
+		fields   map[string]string
+	}{
+		{"Goldmark", "misc", map[string]string{"year": "2021", "day": "15"}},
+		{"Smith2019", "mastersthesis", map[string]string{"year": "2019", "school": "University of Oxford", "address": "Oxford", "type": ""}},
+		{"Doe2022", "techreport", map[string]string{"year": "2022", "type": ""}},
+		{"Vaswani2017", "misc", map[string]string{"archiveprefix": "arXiv", "primaryclass": "cs.CL"}},
+	}
+	for _, tt := range fields {
+		entry := findEntry(t, biblatex, tt.key)
+		if entry.Type != tt.typ {
+			t.Errorf("%s: type = %s; want %s", tt.key, entry.Type, tt.typ)
+		}
+		for field, want := range tt.fields {
+			if got := cite.Field(entry, field); got != want {
+				t.Errorf("%s: %s = %q; want %q", tt.key, field, got, want)
+			}
+		}
+	}
+
+	online := findEntry(t, biblatex, "Goldmark")
+	references := []struct {
+		style Style
+		want  string
+	}{
+		{apa.Style{}, "Retrieved January 5, 2024, from"},
+		{acm.Style{}, "Retrieved January 5, 2024 from"},
+		{ieee.Style{}, "Accessed: Jan. 5, 2024. [Online]."},
+		{chicago.AuthorDate{}, "Accessed January 5, 2024."},
+	}
+	for _, tt := range references {
+		got := tt.style.FormatReference(cite.Reference{Entry: online})
+		if !strings.Contains(got, tt.want) || strings.Contains(got, "??") {
+			t.Errorf("%T: reference = %s; want it to contain %q", tt.style, got, tt.want)
+		}
+	}
+
+	dates := map[string]cite.Date{
+		"2021":                {Year: 2021},
+		"2021-03":             {Year: 2021, Month: 3},
+		"2021-03-15":          {Year: 2021, Month: 3, Day: 15},
+		"2021-03-15T10:00:00": {Year: 2021, Month: 3, Day: 15},
+		"2021-21":             {Year: 2021},
+		"2020?/2021":          {Year: 2020},
+		"2019-06~":            {Year: 2019, Month: 6},
+	}
+	for s, want := range dates {
+		if got, ok := cite.ParseDate(s); !ok || got != want {
+			t.Errorf("ParseDate(%q) = %v, %v; want %v", s, got, ok, want)
+		}
+	}
+	if _, ok := cite.ParseDate("March 2021"); ok {
+		t.Error("ParseDate(\"March 2021\") succeeded")
+	}
+
+	// Theses of other types keep their type
+	theses := []struct {
+		kind, typ, reference string
+	}{
+		{"phdthesis", "phdthesis", "Ph.D. dissertation"},
+		{"Doctoral dissertation", "phdthesis", "Ph.D. dissertation"},
+		{"Master’s thesis", "mastersthesis", "M.S. thesis"},
+		{"Bachelor's thesis", "thesis", "Bachelor&#39;s thesis"},
+		{"Diploma thesis", "thesis", "Diploma thesis"},
+	}
+	for _, tt := range theses {
+		source := fmt.Sprintf("@thesis{Lee2020, author = {Ann Lee}, title = {Graphs}, type = {%s}, institution = {MIT}, date = {2020}}", tt.kind)
+		ext, err := NewFromSources([]Source{{Name: "theses.bib", Data: []byte(source)}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry := findEntry(t, ext, "Lee2020")
+		if entry.Type != tt.typ {
+			t.Errorf("%s: type = %s; want %s", tt.kind, entry.Type, tt.typ)
+		}
+		got := ieee.Style{}.FormatReference(cite.Reference{Entry: entry})
+		if !strings.Contains(got, tt.reference+", MIT") {
+			t.Errorf("%s: reference = %s; want it to contain %q", tt.kind, got, tt.reference)
+		}
+	}
+}
+
+func TestCrossref(t *testing.T) {
+	bibFile := filepath.Join("testdata", "crossref.bib")
+	bibExtender, err := New(bibFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key    string
+		fields map[string]string
+	}{
+		{"Bunke1990", map[string]string{
+			"booktitle": "Graph-Grammars and Their Application to Computer Science, 4th International Workshop",
+			"title":     "An Efficient Implementation of Graph Grammars Based on the RETE Matching Algorithm",
+			"publisher": "Springer",
+			"year":      "1990",
+			"volume":    "532",
+		}},
+		{"Courcelle1990", map[string]string{"year": "1991", "series": "Lecture Notes in Computer Science"}},
+		{"Rozenberg1997", map[string]string{"publisher": "Springer", "address": "Berlin", "series": "Lecture Notes in Computer Science"}},
+	}
+	for _, tt := range tests {
+		entry := findEntry(t, bibExtender, tt.key)
+		for field, want := range tt.fields {
+			if got := cite.Field(entry, field); got != want {
+				t.Errorf("%s: %s = %q; want %q", tt.key, field, got, want)
+			}
+		}
+	}
+	for _, entry := range bibExtender.Bibliography {
+		if strings.EqualFold(entry.Type, "xdata") {
+			t.Errorf("@xdata entry %s in the bibliography", entry.Key)
+		}
+	}
+	reference := acm.Style{}.FormatReference(cite.Reference{Entry: findEntry(t, bibExtender, "Bunke1990")})
+	if !strings.Contains(reference, "Springer") || strings.Contains(reference, "??") {
+		t.Errorf("reference = %s; want the fields of the proceedings", reference)
+	}
+
+	ids := regexp.MustCompile(`<li id="ref-([^"]+)">`)
+	for _, n := range []int{0, 2, 3} {
+		var warnings []string
+		bibExtender, err := New(bibFile,
+			WithMinCrossrefs(n),
+			WithWarningHandler(func(w cite.Warning) { warnings = append(warnings, w.Error()) }),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		source := "See @Bunke1990, @Courcelle1990 and @Orphan2000."
+		if err := goldmark.New(goldmark.WithExtensions(bibExtender)).Convert([]byte(source), &buf); err != nil {
+			t.Fatal(err)
+		}
+		listed := false
+		for _, m := range ids.FindAllStringSubmatch(buf.String(), -1) {
+			listed = listed || m[1] == "conf/gg/1990"
+		}
+		if want := n == 2; listed != want {
+			t.Errorf("WithMinCrossrefs(%d): parent listed = %v; want %v", n, listed, want)
+		}
+		want := `entry "Orphan2000": field "crossref": parent "conf/missing/2000" not found in the bibliography`
+		if !slices.Contains(warnings, want) {
+			t.Errorf("WithMinCrossrefs(%d): warnings = %q; want %q", n, warnings, want)
+		}
+	}
+}
+
+func TestLaTeX(t *testing.T) {
+	src := `@Article{Kreowski1990,
+  author  = {Hans{-}J{\"{o}}rg Kreowski and Paul Erd\H{o}s and \v{C}ech, Eduard and J{\o}rn Stra{\ss}e},
+  title   = {The {\em Quick} \emph{Fox} -- \textbf{Bold} \textsc{Caps} $\alpha^{2}$ \'el\` + "`" + `eve \c{c}a ` + "``quoted''" + ` \unknown{arg}},
+  journal = {Gra\ss{} Z\"urich---Fa\c{c}ade},
+  pages   = {1--10},
+  url     = {https://example.com/a--b},
+}`
+	var warnings []string
+	bibExtender, err := NewFromBytes([]byte(src), WithWarningHandler(func(w cite.Warning) {
+		warnings = append(warnings, w.Error())
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := findEntry(t, bibExtender, "Kreowski1990")
+
+	var names []string
+	for _, name := range cite.Names(entry, "author") {
+		names = append(names, cite.Text(name.First)+" "+cite.Text(name.Last))
+	}
+	if got, want := strings.Join(names, "; "), "Hans-Jörg Kreowski; Paul Erdős; Eduard Čech; Jørn Straße"; got != want {
+		t.Errorf("authors = %q; want %q", got, want)
+	}
+	fields := map[string]string{
+		"title": `The <em>Quick</em> <em>Fox</em> – <b>Bold</b> <span style="font-variant: small-caps">Caps</span> ` +
+			`α<sup>2</sup> élève ça “quoted” \unknown{arg}`,
+		"journal": "Graß Zürich—Façade",
+		"pages":   "1–10",
+		"url":     "https://example.com/a--b",
+	}
+	for field, want := range fields {
+		if got := cite.Field(entry, field); got != want {
+			t.Errorf("%s = %q; want %q", field, got, want)
+		}
+	}
+	want := []string{`entry "Kreowski1990": field "title": unknown LaTeX macro \unknown`}
+	if !reflect.DeepEqual(warnings, want) {
+		t.Errorf("warnings = %q; want %q", warnings, want)
+	}
+}
+
+func TestMath(t *testing.T) {
+	src := `@Article{Aaronson2004,
+  author = {Scott Aaronson},
+  title  = {$O(n^2)$ algorithms for $\mathsf{QMA}$ with $x_i < \frac{1}{2}$},
+}`
+	tests := []struct {
+		mode latex.MathMode
+		want string
+	}{
+		{latex.MathText, `O(n<sup>2</sup>) algorithms for QMA with x<sub>i</sub> &lt; 1/2`},
+		{latex.MathJax, `\(O(n^2)\) algorithms for \(\mathsf{QMA}\) with \(x_i &lt; \frac{1}{2}\)`},
+		{latex.MathKaTeX, `<span class="math inline">O(n^2)</span> algorithms for ` +
+			`<span class="math inline">\mathsf{QMA}</span> with <span class="math inline">x_i &lt; \frac{1}{2}</span>`},
+		{latex.MathML, `<math><mi>O</mi><mo>(</mo><msup><mi>n</mi><mrow><mn>2</mn></mrow></msup><mo>)</mo></math> algorithms for ` +
+			`<math><mrow><mi mathvariant="sans-serif">QMA</mi></mrow></math> with ` +
+			`<math><msub><mi>x</mi><mrow><mi>i</mi></mrow></msub><mo>&lt;</mo><mfrac><mrow><mn>1</mn></mrow><mrow><mn>2</mn></mrow></mfrac></math>`},
+	}
+	for _, tt := range tests {
+		var warnings []cite.Warning
+		bibExtender, err := NewFromBytes([]byte(src), WithMath(tt.mode), WithWarningHandler(func(w cite.Warning) {
+			warnings = append(warnings, w)
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry := findEntry(t, bibExtender, "Aaronson2004")
+		// Math keeps its case, like protected groups
+		if got := cite.ChangeCase(cite.Field(entry, "title"), cite.AsIs); got != tt.want {
+			t.Errorf("mode %d: title = %q; want %q", tt.mode, got, tt.want)
+		}
+		if len(warnings) > 0 {
+			t.Errorf("mode %d: warnings = %v", tt.mode, warnings)
+		}
+	}
+}
+
+func TestLaTeXEscaping(t *testing.T) {
+	src := `@Article{Smith2020,
+  author  = {John Smith},
+  title   = {{R}\&{D} of <script> for {RETE}: when x < y},
+  journal = {Research \& Development},
+  year    = {2020},
+}`
+	bibExtender, err := NewFromBytes([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := findEntry(t, bibExtender, "Smith2020")
+	if got, want := cite.Field(entry, "journal"), "Research &amp; Development"; got != want {
+		t.Errorf("journal = %q; want %q", got, want)
+	}
+
+	for _, style := range []Style{apa.Style{}, acm.Style{}, ieee.Style{}, chicago.AuthorDate{}} {
+		// Title case capitalizes the escaped words too
+		got := style.FormatReference(cite.Reference{Entry: entry})
+		if lower := strings.ToLower(got); !strings.Contains(lower, "&lt;script&gt;") || !strings.Contains(lower, "x &lt; y") || !strings.Contains(got, "R&amp;D") {
+			t.Errorf("%T: reference = %s; want the title escaped", style, got)
+		}
+		if strings.Contains(got, "nocase") || strings.Contains(got, "<script>") {
+			t.Errorf("%T: reference = %s; want no nocase spans or script elements", style, got)
+		}
+	}
+}
+
+func TestChangeCase(t *testing.T) {
+	title := `the art of computer programming: a state-of-the-art survey of {RETE} and {Boolean} logic in \emph{practice}`
+	src := `@Article{Knuth1997,
+  author  = {Donald E. Knuth},
+  title   = {` + title + `},
+  journal = {journal of {ACM} research},
+  year    = {1997},
+}`
+	bibExtender, err := NewFromBytes([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The braced words are in nocase spans in the field value, which Field
+	// removes
+	entry := findEntry(t, bibExtender, "Knuth1997")
+	marked := `the art of computer programming: a state-of-the-art survey of <span class="nocase">RETE</span> and ` +
+		`<span class="nocase">Boolean</span> logic in <em>practice</em>`
+	if got := entry.Tags["title"].(*ast.Text).Value; got != marked {
+		t.Fatalf("title value = %q; want %q", got, marked)
+	}
+	if got, want := cite.Field(entry, "title"), `the art of computer programming: a state-of-the-art survey of RETE and Boolean logic in <em>practice</em>`; got != want {
+		t.Fatalf("title = %q; want %q", got, want)
+	}
+	tests := []struct {
+		textCase cite.TextCase
+		want     string
+	}{
+		{cite.AsIs, `the art of computer programming: a state-of-the-art survey of RETE and Boolean logic in <em>practice</em>`},
+		{cite.SentenceCase, `The art of computer programming: A state-of-the-art survey of RETE and Boolean logic in <em>practice</em>`},
+		{cite.TitleCase, `The Art of Computer Programming: A State-of-the-Art Survey of RETE and Boolean Logic in <em>Practice</em>`},
+		{cite.Uppercase, `THE ART OF COMPUTER PROGRAMMING: A STATE-OF-THE-ART SURVEY OF RETE AND Boolean LOGIC IN <em>PRACTICE</em>`},
+	}
+	for _, tt := range tests {
+		if got := cite.ChangeCase(marked, tt.textCase); got != tt.want {
+			t.Errorf("ChangeCase(%d) = %q; want %q", tt.textCase, got, tt.want)
+		}
+		if got := cite.Title(entry, "title", tt.textCase); got != tt.want {
+			t.Errorf("Title(%d) = %q; want %q", tt.textCase, got, tt.want)
+		}
+	}
+	if got, want := cite.ChangeCase("Graph-Based Methods for the iPhone and R&amp;D", cite.SentenceCase), "Graph-based methods for the iPhone and R&amp;D"; got != want {
+		t.Errorf("ChangeCase(SentenceCase) = %q; want %q", got, want)
+	}
+
+	// The styles keep the case of the braced words
+	styles := []struct {
+		style Style
+		want  string
+	}{
+		{apa.Style{}, `<span class="title">The art of computer programming: A state-of-the-art survey of RETE and Boolean logic in <em>practice</em></span>. <span class="journal">Journal of ACM Research</span>`},
+		{chicago.AuthorDate{}, `“The Art of Computer Programming: A State-of-the-Art Survey of RETE and Boolean Logic in <em>Practice</em>.” <em>Journal of ACM Research</em>`},
+		{acm.Style{}, `The Art of Computer Programming: A State-of-the-Art Survey of RETE and Boolean Logic in <em>Practice</em>. <em>Journal of ACM Research</em>`},
+	}
+	for _, tt := range styles {
+		bibExtender, err := NewFromBytes([]byte(src), WithStyle(tt.style))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := goldmark.New(goldmark.WithExtensions(bibExtender)).Convert([]byte("[@Knuth1997]"), &buf); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); !strings.Contains(got, tt.want) || strings.Contains(got, "nocase") {
+			t.Errorf("%T: document = %s; want it to contain %s", tt.style, got, tt.want)
+		}
+
+		// The entries keep the case of their braced words without the
+		// extender that loaded them
+		md := goldmark.New(
+			goldmark.WithParserOptions(
+				parser.WithInlineParsers(util.Prioritized(NewCitationGroupParser(), 100)),
+				parser.WithASTTransformers(util.Prioritized(NewBibliographyTransformer(bibExtender.Bibliography, tt.style), 100)),
+			),
+			goldmark.WithRendererOptions(
+				renderer.WithNodeRenderers(util.Prioritized(NewStyleCitationRenderer(tt.style), 100)),
+			),
+		)
+		buf.Reset()
+		if err := md.Convert([]byte("[@Knuth1997]"), &buf); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); !strings.Contains(got, tt.want) || strings.Contains(got, "nocase") {
+			t.Errorf("%T: NewBibliographyTransformer: document = %s; want it to contain %s", tt.style, got, tt.want)
+		}
+	}
+}
+
+func TestNewCitationRenderer(t *testing.T) {
+	if r, ok := NewCitationRenderer(nil).(*CitationRenderer); !ok || r.style != (acm.Style{}) {
+		t.Errorf("NewCitationRenderer(nil) = %#v; want a CitationRenderer with acm.Style", r)
+	}
+	if r, ok := NewStyleCitationRenderer(apa.Style{}).(*CitationRenderer); !ok || r.style != (apa.Style{}) {
+		t.Errorf("NewStyleCitationRenderer(apa.Style{}) = %#v; want a CitationRenderer with apa.Style", r)
+	}
+}
@@ -0,0 +1,93 @@
+package chicago
+
+import (
+	"github.com/jschaf/bibtex"
+	"github.com/lmondada/goldmark-bibtex/cite"
+)
+
+// AuthorDate is the author-date system of the Chicago Manual of Style. It
+// implements the Style interface of the goldmark-bibtex extension.
+type AuthorDate struct {
+	// EtAl overrides the truncation of author lists, see defaultEtAl.
+	EtAl cite.EtAl
+}
+
+// FormatCitation formats an inline citation, see FormatInlineCitation.
+func (s AuthorDate) FormatCitation(items []cite.Item) string {
+	return formatInlineCitation(items, s.EtAl.Or(defaultEtAl).Inline)
+}
+
+// FormatReference formats a full reference, see FormatCitation.
+func (s AuthorDate) FormatReference(ref cite.Reference) string {
+	return formatCitation(cite.WithYearSuffix(ref.Entry, ref.YearSuffix), s.EtAl.Or(defaultEtAl).Reference)
+}
+
+// Less orders the reference list alphabetically by author, then by year.
+func (AuthorDate) Less(a, b *bibtex.Entry) bool {
+	return cite.LessAuthorYear(a, b)
+}
+
+// Disambiguate tells apart works with the same author-date citation with more
+// author names, then with year suffixes, like "Smith 2020a".
+func (s AuthorDate) Disambiguate(refs []cite.Reference) {
+	cite.Disambiguate(refs, cite.DisambiguationRules{
+		Truncation:    s.EtAl.Or(defaultEtAl).Inline,
+		AddNames:      true,
+		AddYearSuffix: true,
+	})
+}
+
+// FormatBibliography formats the reference list as an unordered list.
+func (s AuthorDate) FormatBibliography(refs []cite.Reference) string {
+	return cite.FormatList("ul", refs, s.FormatReference)
+}
+
+// Notes is the notes-bibliography system of the Chicago Manual of Style. It
+// implements the NoteStyle interface of the goldmark-bibtex extension: each
+// citation becomes a footnote, see FormatNote.
+type Notes struct {
+	// EtAl overrides the truncation of author lists, see defaultEtAl. Notes
+	// use the Inline truncation.
+	EtAl cite.EtAl
+}
+
+// FormatCitation formats the part of a citation that stays in the text: the
+// author names of a narrative citation, like "Smith and Doe". Other citations
+// are only footnotes.
+func (s Notes) FormatCitation(items []cite.Item) string {
+	if len(items) != 1 || items[0].Mode != cite.ModeAuthorInText {
+		return ""
+	}
+	if items[0].Entry == nil {
+		return "?"
+	}
+	label := formatShortNames(getWork(items[0].Entry).authors, 0, s.EtAl.Or(defaultEtAl).Inline)
+	if label == "" {
+		// Works without authors, editors or organization are named by their
+		// title
+		label = cite.LeadOf(items[0].Entry).Label()
+	}
+	return formatKeySpan(items[0].Entry, label)
+}
+
+// FormatNote formats the footnote of a citation: a full note for the first
+// citation of a work, see formatFullNote, and a shortened note for later
+// ones, see formatShortNote.
+func (s Notes) FormatNote(items []cite.Item) string {
+	return formatNote(items, s.EtAl.Or(defaultEtAl).Inline)
+}
+
+// FormatReference formats a full reference, see FormatBibliographyEntry.
+func (s Notes) FormatReference(ref cite.Reference) string {
+	return formatBibliographyEntry(ref.Entry, s.EtAl.Or(defaultEtAl).Reference)
+}
+
+// Less orders the bibliography alphabetically by author, then by year.
+func (Notes) Less(a, b *bibtex.Entry) bool {
+	return cite.LessAuthorYear(a, b)
+}
+
+// FormatBibliography formats the bibliography as an unordered list.
+func (s Notes) FormatBibliography(refs []cite.Reference) string {
+	return cite.FormatList("ul", refs, s.FormatReference)
+}
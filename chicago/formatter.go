@@ -0,0 +1,647 @@
+// Package chicago formats citations and references in the Chicago Manual of
+// Style, 17th edition. It offers both variants of the manual: the author-date
+// system, with inline citations like "(Smith 2023, 45)" and a reference list,
+// and the notes-bibliography system, where each citation becomes a footnote
+// with a full first note and shortened subsequent notes.
+package chicago
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jschaf/bibtex"
+	bibtexAst "github.com/jschaf/bibtex/ast"
+	"github.com/lmondada/goldmark-bibtex/cite"
+)
+
+// name is a personal name of an author or editor.
+type name struct {
+	first, prefix, last, suffix string
+}
+
+// work holds the fields of an entry used by the Chicago formats. Fields that
+// are not set are "".
+type work struct {
+	kind    string
+	authors []name
+	// edited reports whether the authors are the editors of a work without
+	// authors.
+	edited       bool
+	editors      []name
+	title        string
+	shortTitle   string
+	container    string
+	volume       string
+	number       string
+	pages        string
+	year         string
+	month        string
+	edition      string
+	publisher    string
+	address      string
+	howpublished string
+	eprint       string
+	doi          string
+	url          string
+	accessed     string
+}
+
+// kinds maps BibTeX entry types to the kinds of works formatted differently.
+var kinds = map[string]string{
+	"article":       "article",
+	"inproceedings": "chapter",
+	"conference":    "chapter",
+	"incollection":  "chapter",
+	"inbook":        "chapter",
+	"book":          "book",
+	"proceedings":   "book",
+	"phdthesis":     "thesis",
+	"mastersthesis": "thesis",
+	"thesis":        "thesis",
+	"techreport":    "report",
+}
+
+var monthNames = map[string]string{
+	"jan": "January", "feb": "February", "mar": "March", "apr": "April",
+	"may": "May", "jun": "June", "jul": "July", "aug": "August",
+	"sep": "September", "oct": "October", "nov": "November", "dec": "December",
+}
+
+func getWork(entry *bibtex.Entry) work {
+	w := work{
+		kind:         kinds[strings.ToLower(entry.Type)],
+		authors:      getNames(entry, "author"),
+		editors:      getNames(entry, "editor"),
+		title:        getTitle(entry, "title"),
+		shortTitle:   getTitle(entry, "shorttitle"),
+		volume:       getFieldText(entry, "volume"),
+		number:       getFieldText(entry, "number"),
+		pages:        cite.PageRange(getFieldText(entry, "pages")),
+		year:         cite.Year(entry),
+		edition:      getFieldText(entry, "edition"),
+		publisher:    getFieldText(entry, "publisher"),
+		address:      getFieldText(entry, "address"),
+		howpublished: getFieldText(entry, "howpublished"),
+		eprint:       getFieldText(entry, "eprint"),
+		doi:          getFieldText(entry, "doi"),
+		url:          getFieldText(entry, "url"),
+	}
+	if date, ok := cite.URLDate(entry); ok {
+		w.accessed = date.String()
+	}
+	if w.kind == "" {
+		w.kind = "misc"
+	}
+
+	// Works without authors are led by their editors or organization, or
+	// else by their title
+	if lead := cite.LeadOf(entry); len(w.authors) == 0 && lead.Editors {
+		w.authors, w.editors, w.edited = w.editors, nil, true
+	} else if len(w.authors) == 0 && lead.Organization != "" {
+		w.authors = []name{{last: lead.Organization}}
+	}
+
+	switch w.kind {
+	case "article":
+		w.container = getTitle(entry, "journal")
+	case "chapter":
+		w.container = getTitle(entry, "booktitle")
+	case "thesis":
+		w.publisher = getFieldText(entry, "school")
+		switch strings.ToLower(entry.Type) {
+		case "phdthesis":
+			w.container = "PhD diss."
+		case "mastersthesis":
+			w.container = "Master’s thesis"
+		default:
+			w.container = getFieldText(entry, "type")
+		}
+	case "report":
+		w.publisher = getFieldText(entry, "institution")
+	case "misc":
+		if strings.EqualFold(getFieldText(entry, "archiveprefix"), "arXiv") && w.eprint != "" {
+			w.howpublished = "arXiv:" + w.eprint
+		}
+	}
+
+	switch month := entry.Tags["month"].(type) {
+	case *bibtexAst.Ident:
+		w.month = monthNames[strings.ToLower(month.Name)]
+	case *bibtexAst.Text:
+		if len(month.Value) >= 3 {
+			w.month = monthNames[strings.ToLower(month.Value[:3])]
+		}
+	}
+
+	if w.shortTitle == "" {
+		w.shortTitle = shortenTitle(w.title)
+	}
+	return w
+}
+
+// shortenTitle shortens a title to its main title of at most four words, as
+// used in shortened notes.
+func shortenTitle(title string) string {
+	if main, _, ok := strings.Cut(title, ":"); ok {
+		title = main
+	}
+	words := strings.Fields(title)
+	if len(words) > 4 {
+		words = words[:4]
+	}
+	return strings.Join(words, " ")
+}
+
+func getNames(entry *bibtex.Entry, field string) []name {
+	authors, _ := entry.Tags[field].(bibtexAst.Authors)
+	names := make([]name, 0, len(authors))
+	for _, author := range authors {
+		names = append(names, name{
+			first:  cite.Text(author.First),
+			prefix: cite.Text(author.Prefix),
+			last:   cite.Text(author.Last),
+			suffix: cite.Text(author.Suffix),
+		})
+	}
+	return names
+}
+
+// full returns a name in the natural order, like "Ludwig van Beethoven".
+func (n name) full() string {
+	s := strings.Join(nonEmpty(n.first, n.prefix, n.last), " ")
+	if n.suffix != "" {
+		s += ", " + n.suffix
+	}
+	return s
+}
+
+// inverted returns a name with the last name first, like "Beethoven, Ludwig
+// van".
+func (n name) inverted() string {
+	s := n.last
+	if given := strings.Join(nonEmpty(n.first, n.prefix), " "); given != "" {
+		s += ", " + given
+	}
+	if n.suffix != "" {
+		s += ", " + n.suffix
+	}
+	return s
+}
+
+// defaultEtAl is the Chicago truncation: "Smith et al." for four or more
+// authors in citations and notes, and the first seven names followed by "et
+// al." for eleven or more authors in the reference list.
+var defaultEtAl = cite.EtAl{
+	Inline:    cite.Truncation{Min: 4, UseFirst: 1},
+	Reference: cite.Truncation{Min: 11, UseFirst: 7},
+}
+
+// formatBibliographyNames formats the names of a reference list entry, with
+// the first name inverted, like "Smith, John, and Jane Doe". Long lists are
+// truncated, like "Smith, John, Jane Doe, et al."
+func formatBibliographyNames(names []name, etAl cite.Truncation) string {
+	if len(names) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(names))
+	for i, n := range names {
+		if i == 0 {
+			parts = append(parts, n.inverted())
+		} else {
+			parts = append(parts, n.full())
+		}
+	}
+	if shown, truncated := etAl.Shown(len(parts)); truncated {
+		return strings.Join(parts[:shown], ", ") + ", et al."
+	}
+	if len(parts) == 2 {
+		return parts[0] + ", and " + parts[1]
+	}
+	return serial(parts)
+}
+
+// formatNoteNames formats the names of a full note, like "John Smith and Jane
+// Doe". Long lists are truncated, like "John Smith et al."
+func formatNoteNames(names []name, etAl cite.Truncation) string {
+	parts := make([]string, 0, len(names))
+	for _, n := range names {
+		parts = append(parts, n.full())
+	}
+	if shown, truncated := etAl.Shown(len(parts)); truncated {
+		return strings.Join(parts[:shown], ", ") + " et al."
+	}
+	return serial(parts)
+}
+
+// formatShortNames formats the last names of an inline citation or a
+// shortened note, like "Smith", "Smith and Doe" or "Smith et al." At least
+// shown names are shown before "et al." to disambiguate.
+func formatShortNames(names []name, shown int, etAl cite.Truncation) string {
+	parts := make([]string, 0, len(names))
+	for _, n := range names {
+		parts = append(parts, strings.Join(nonEmpty(n.prefix, n.last), " "))
+	}
+	if n, truncated := etAl.Shown(len(parts)); truncated && shown < len(parts) {
+		if shown < n {
+			shown = n
+		}
+		return strings.Join(parts[:shown], ", ") + " et al."
+	}
+	return serial(parts)
+}
+
+// formatEdited appends "ed." or "eds." to the names of the editors that lead a
+// work without authors, like "Jane Doe, ed."
+func formatEdited(names string, w work) string {
+	switch {
+	case !w.edited || names == "":
+		return names
+	case len(w.authors) == 1:
+		return names + ", ed."
+	default:
+		return names + ", eds."
+	}
+}
+
+// formatShortLead formats the short names of an inline citation, or the short
+// title of a work without authors, editors and organization.
+func formatShortLead(w work, shown int, etAl cite.Truncation) string {
+	if len(w.authors) > 0 {
+		return formatShortNames(w.authors, shown, etAl)
+	}
+	if w.kind == "book" {
+		return emph(w.shortTitle)
+	}
+	return quote(w.shortTitle)
+}
+
+// serial joins a list with commas and "and", with a serial comma before "and"
+// for three or more items.
+func serial(list []string) string {
+	switch len(list) {
+	case 0:
+		return ""
+	case 1:
+		return list[0]
+	case 2:
+		return list[0] + " and " + list[1]
+	default:
+		return strings.Join(list[:len(list)-1], ", ") + ", and " + list[len(list)-1]
+	}
+}
+
+// FormatInlineCitation formats the inline citation of a group of cited items
+// in the author-date system, like "(see Smith 2023, 45; Doe 2020)". A single
+// item cited in the ModeAuthorInText mode is formatted as a narrative
+// citation, like "Smith (2023, 45)".
+func FormatInlineCitation(items []cite.Item) string {
+	return formatInlineCitation(items, defaultEtAl.Inline)
+}
+
+func formatInlineCitation(items []cite.Item, etAl cite.Truncation) string {
+	if len(items) == 1 && items[0].Mode == cite.ModeAuthorInText {
+		item := items[0]
+		if item.Entry == nil {
+			return "?"
+		}
+		item.Locator = formatLocator(item.Locator)
+		w := getWork(item.Entry)
+		label := fmt.Sprintf("%s (%s)", formatShortLead(w, item.Names, etAl), item.Decorate(w.year+item.YearSuffix))
+		return formatKeySpan(item.Entry, label)
+	}
+
+	parts := make([]string, len(items))
+	for i, item := range items {
+		item.Locator = formatLocator(item.Locator)
+		switch {
+		case item.Entry == nil:
+			parts[i] = item.Decorate("?")
+		case item.Mode == cite.ModeSuppressAuthor:
+			parts[i] = item.Decorate(formatKeySpan(item.Entry, getWork(item.Entry).year+item.YearSuffix))
+		default:
+			w := getWork(item.Entry)
+			label := strings.Join(nonEmpty(formatShortLead(w, item.Names, etAl), w.year+item.YearSuffix), " ")
+			parts[i] = item.Decorate(formatKeySpan(item.Entry, label))
+		}
+	}
+	return "(" + strings.Join(parts, "; ") + ")"
+}
+
+// FormatNote formats the footnote of a group of cited items in the
+// notes-bibliography system. An item cited for the first time gets a full
+// note, like "John Smith, “Title,” Journal 4, no. 2 (2023): 45", and later
+// citations get a shortened note, like "Smith, “Title,” 45".
+func FormatNote(items []cite.Item) string {
+	return formatNote(items, defaultEtAl.Inline)
+}
+
+func formatNote(items []cite.Item, etAl cite.Truncation) string {
+	parts := make([]string, len(items))
+	for i, item := range items {
+		locator := formatLocator(item.Locator)
+		item.Locator = ""
+		switch {
+		case item.Entry == nil:
+			parts[i] = item.Decorate("?")
+		case item.First:
+			parts[i] = item.Decorate(formatKeySpan(item.Entry, formatFullNote(getWork(item.Entry), locator, etAl)))
+		default:
+			parts[i] = item.Decorate(formatKeySpan(item.Entry, formatShortNote(getWork(item.Entry), locator, etAl)))
+		}
+	}
+	return punctuate(capitalize(strings.Join(parts, "; ")) + ".")
+}
+
+// formatFullNote formats the first note of a work. The locator replaces the
+// page range of articles and chapters.
+func formatFullNote(w work, locator string, etAl cite.Truncation) string {
+	pages := w.pages
+	if locator != "" {
+		pages = locator
+	}
+	authors := formatEdited(formatNoteNames(w.authors, etAl), w)
+
+	switch w.kind {
+	case "article":
+		source := joinSpace(emph(w.container), w.volume)
+		if w.number != "" {
+			source = joinComma(source, "no. "+w.number)
+		}
+		if date := joinSpace(w.month, w.year); date != "" {
+			source = joinSpace(source, "("+date+")")
+		}
+		source = joinColon(source, pages)
+		return joinComma(authors, quote(w.title), source, accessed(w, "accessed"), link(w))
+	case "chapter":
+		in := "in " + emph(w.container)
+		if len(w.editors) > 0 {
+			in += ", ed. " + formatNoteNames(w.editors, etAl)
+		}
+		if w.container == "" {
+			in = ""
+		}
+		facts := parenthesize(joinComma(publication(w.address, w.publisher), w.year))
+		return joinComma(authors, quote(w.title), joinSpace(in, facts), pages, accessed(w, "accessed"), link(w))
+	case "book":
+		facts := parenthesize(joinComma(publication(w.address, w.publisher), w.year))
+		title := joinComma(emph(w.title), formatEdition(w.edition))
+		return joinComma(authors, joinSpace(title, facts), locator, accessed(w, "accessed"), link(w))
+	case "thesis", "report":
+		facts := parenthesize(joinComma(w.container, w.number, publication(w.address, w.publisher), w.year))
+		return joinComma(authors, joinSpace(quote(w.title), facts), locator, accessed(w, "accessed"), link(w))
+	default:
+		return joinComma(authors, quote(w.title), w.howpublished, joinSpace(w.month, w.year), locator, accessed(w, "accessed"), link(w))
+	}
+}
+
+// formatShortNote formats the shortened note of a work that was cited before,
+// with the last names of the authors and a short title.
+func formatShortNote(w work, locator string, etAl cite.Truncation) string {
+	title := quote(w.shortTitle)
+	if w.kind == "book" {
+		title = emph(w.shortTitle)
+	}
+	return joinComma(formatShortNames(w.authors, 0, etAl), title, locator)
+}
+
+// FormatCitation formats a full reference of the reference list of the
+// author-date system, with the year after the names.
+// Example: Mahfoud, Houari. 2020. “Graph Pattern Matching.” Cluster Computing 23 (3): 1529–1553.
+func FormatCitation(entry *bibtex.Entry) string {
+	return formatCitation(entry, defaultEtAl.Reference)
+}
+
+func formatCitation(entry *bibtex.Entry, etAl cite.Truncation) string {
+	w := getWork(entry)
+	lead := joinPeriod(formatEdited(formatBibliographyNames(w.authors, etAl), w), w.year)
+	if len(w.authors) == 0 {
+		// The title leads works without authors, editors and organization
+		title := quote(w.title)
+		if w.kind == "book" {
+			title = emph(w.title)
+		}
+		lead, w.title = joinPeriod(title, w.year), ""
+	}
+
+	var body string
+	switch w.kind {
+	case "article":
+		source := joinSpace(emph(w.container), w.volume)
+		if w.number != "" {
+			source = joinSpace(source, "("+w.number+")")
+		}
+		source = joinColon(source, w.pages)
+		body = joinPeriod(quote(w.title), source)
+	case "chapter":
+		body = joinPeriod(quote(w.title), inContainer(w), publication(w.address, w.publisher))
+	case "book":
+		body = joinPeriod(emph(w.title), formatEdition(w.edition), publication(w.address, w.publisher))
+	case "thesis", "report":
+		body = joinPeriod(quote(w.title), joinComma(w.container, w.number, w.publisher), w.address)
+	default:
+		body = joinPeriod(quote(w.title), w.howpublished)
+	}
+	return formatReference(joinPeriod(lead, body, accessed(w, "Accessed"), link(w)))
+}
+
+// FormatBibliographyEntry formats a full reference of the bibliography of the
+// notes-bibliography system, with the year in the publication facts.
+// Example: Mahfoud, Houari. “Graph Pattern Matching.” Cluster Computing 23, no. 3 (2020): 1529–1553.
+func FormatBibliographyEntry(entry *bibtex.Entry) string {
+	return formatBibliographyEntry(entry, defaultEtAl.Reference)
+}
+
+func formatBibliographyEntry(entry *bibtex.Entry, etAl cite.Truncation) string {
+	w := getWork(entry)
+	date := joinSpace(w.month, w.year)
+
+	var body string
+	switch w.kind {
+	case "article":
+		source := joinSpace(emph(w.container), w.volume)
+		if w.number != "" {
+			source = joinComma(source, "no. "+w.number)
+		}
+		if date != "" {
+			source = joinSpace(source, "("+date+")")
+		}
+		source = joinColon(source, w.pages)
+		body = joinPeriod(quote(w.title), source)
+	case "chapter":
+		body = joinPeriod(quote(w.title), inContainer(w), joinComma(publication(w.address, w.publisher), w.year))
+	case "book":
+		body = joinPeriod(emph(w.title), formatEdition(w.edition), joinComma(publication(w.address, w.publisher), w.year))
+	case "thesis", "report":
+		body = joinPeriod(quote(w.title), joinComma(w.container, w.number, w.publisher, w.year), w.address)
+	default:
+		body = joinPeriod(quote(w.title), joinComma(w.howpublished, date))
+	}
+	return formatReference(joinPeriod(formatEdited(formatBibliographyNames(w.authors, etAl), w), body, accessed(w, "Accessed"), link(w)))
+}
+
+// inContainer formats the book or proceedings a chapter appears in, like "In
+// Proceedings, edited by Jane Doe, 174–189".
+func inContainer(w work) string {
+	if w.container == "" {
+		return ""
+	}
+	in := "In " + emph(w.container)
+	if len(w.editors) > 0 {
+		in += ", edited by " + formatNoteNames(w.editors, defaultEtAl.Inline)
+	}
+	if w.pages != "" {
+		in += ", " + w.pages
+	}
+	return in
+}
+
+func formatReference(s string) string {
+	return `<span class="citation-full">` + punctuate(s+".") + "</span>"
+}
+
+// link formats the DOI of a work as a URL, or its URL if it has no DOI.
+func link(w work) string {
+	switch {
+	case w.doi != "":
+		return fmt.Sprintf(`<a href="https://doi.org/%s">https://doi.org/%s</a>`, w.doi, w.doi)
+	case w.url != "":
+		return fmt.Sprintf(`<a href="%s">%s</a>`, w.url, w.url)
+	default:
+		return ""
+	}
+}
+
+// accessed formats the date the URL of a work was accessed after a lead
+// word, like "Accessed March 15, 2021", for works linked by their URL.
+func accessed(w work, lead string) string {
+	if w.doi != "" || w.url == "" || w.accessed == "" {
+		return ""
+	}
+	return lead + " " + w.accessed
+}
+
+// publication formats the place of publication and the publisher, like
+// "Berlin: Springer".
+func publication(address, publisher string) string {
+	if address != "" && publisher != "" {
+		return address + ": " + publisher
+	}
+	return address + publisher
+}
+
+// formatEdition formats an edition other than the first, like "2nd ed."
+func formatEdition(edition string) string {
+	ordinals := map[string]string{
+		"1": "", "first": "", "second": "2nd", "third": "3rd",
+	}
+	if ordinal, ok := ordinals[strings.ToLower(edition)]; ok {
+		edition = ordinal
+	} else {
+		edition = cite.Ordinal(edition)
+	}
+	if edition == "" {
+		return ""
+	}
+	return edition + " ed."
+}
+
+// formatLocator formats a locator the Chicago way: page numbers without "p."
+// or "pp.", like "33–35".
+func formatLocator(locator string) string {
+	for _, term := range []string{"p. ", "pp. ", "page ", "pages "} {
+		if strings.HasPrefix(locator, term) {
+			return cite.PageRange(strings.TrimPrefix(locator, term))
+		}
+	}
+	return locator
+}
+
+func formatKeySpan(entry *bibtex.Entry, label string) string {
+	return fmt.Sprintf(`<span data-bibtex-key="%s" class="citation-key">%s</span>`, entry.Key, label)
+}
+
+func quote(title string) string {
+	if title == "" {
+		return ""
+	}
+	return "“" + title + "”"
+}
+
+func emph(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "<em>" + s + "</em>"
+}
+
+func parenthesize(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "(" + s + ")"
+}
+
+// punctuate applies the American placement of commas and periods inside
+// closing quotation marks, and removes the periods doubled by joining parts
+// that already end with punctuation. Ellipses are kept whole, and take no
+// period after them.
+func punctuate(s string) string {
+	s = strings.NewReplacer(
+		"”,", ",”", "”.", ".”",
+		"”</span>,", ",”</span>", "”</span>.", ".”</span>",
+		"...", "\x00",
+	).Replace(s)
+	s = strings.NewReplacer(
+		"\x00.", "\x00", "….", "…", "..", ".", "?.", "?", "!.", "!",
+		".</em>.", ".</em>", "?</em>.", "?</em>", "!</em>.", "!</em>",
+	).Replace(s)
+	return strings.ReplaceAll(s, "\x00", "...")
+}
+
+// capitalize capitalizes the first letter of a note, which may start with a
+// lowercase prefix like "see".
+func capitalize(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError || !unicode.IsLower(r) {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+func joinComma(parts ...string) string {
+	return strings.Join(nonEmpty(parts...), ", ")
+}
+
+func joinPeriod(parts ...string) string {
+	return strings.Join(nonEmpty(parts...), ". ")
+}
+
+func joinColon(parts ...string) string {
+	return strings.Join(nonEmpty(parts...), ": ")
+}
+
+func joinSpace(parts ...string) string {
+	return strings.Join(nonEmpty(parts...), " ")
+}
+
+func nonEmpty(parts ...string) []string {
+	list := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part != "" {
+			list = append(list, part)
+		}
+	}
+	return list
+}
+
+// getFieldText returns the text of a BibTeX field, or "" if it is not set.
+func getFieldText(entry *bibtex.Entry, field string) string {
+	return cite.Field(entry, field)
+}
+
+// getTitle returns a title field in title case.
+func getTitle(entry *bibtex.Entry, field string) string {
+	return cite.Title(entry, field, cite.TitleCase)
+}
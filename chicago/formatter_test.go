@@ -0,0 +1,83 @@
+package chicago
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jschaf/bibtex"
+	bibtexAst "github.com/jschaf/bibtex/ast"
+	"github.com/lmondada/goldmark-bibtex/cite"
+)
+
+func TestPunctuate(t *testing.T) {
+	tests := map[string]string{
+		"Title.. Press":                 "Title. Press",
+		"“Title”. Press":                "“Title.” Press",
+		"“Title”, Press":                "“Title,” Press",
+		"Why?. Press":                   "Why? Press",
+		"Stop!. Press":                  "Stop! Press",
+		"And so on.... Press":           "And so on... Press",
+		"And so on... Press":            "And so on... Press",
+		"And so on…. Press":             "And so on… Press",
+		"<em>Title?</em>. Press":        "<em>Title?</em> Press",
+		"<em>Title.</em>. Press":        "<em>Title.</em> Press",
+		"“Title”</span>. Press":         "“Title.”</span> Press",
+		"No punctuation to merge. Here": "No punctuation to merge. Here",
+	}
+	for s, want := range tests {
+		if got := punctuate(s); got != want {
+			t.Errorf("punctuate(%q) = %q; want %q", s, got, want)
+		}
+	}
+}
+
+func TestNotesNarrativeWithoutAuthor(t *testing.T) {
+	entry := &bibtex.Entry{
+		Type: "misc",
+		Key:  "Anonymous",
+		Tags: map[string]bibtexAst.Expr{
+			"title": &bibtexAst.Text{Value: "A Pamphlet"},
+			"year":  &bibtexAst.Text{Value: "1900"},
+		},
+	}
+	got := Notes{}.FormatCitation([]cite.Item{{Entry: entry, Mode: cite.ModeAuthorInText}})
+	if !strings.Contains(got, ">A Pamphlet<") {
+		t.Errorf("FormatCitation = %s; want the title as label", got)
+	}
+}
+
+func TestArticleWithoutJournal(t *testing.T) {
+	entry := &bibtex.Entry{
+		Type: "article",
+		Key:  "Ren2016",
+		Tags: map[string]bibtexAst.Expr{
+			"author": bibtexAst.Authors{cite.ParseName("Ren, Shaoqing")},
+			"title":  &bibtexAst.Text{Value: "Graph Search"},
+			"year":   &bibtexAst.Text{Value: "2016"},
+		},
+	}
+	tests := []struct {
+		name, got, want string
+	}{
+		{
+			"note",
+			Notes{}.FormatNote([]cite.Item{{Entry: entry, Key: entry.Key, First: true}}),
+			`<span data-bibtex-key="Ren2016" class="citation-key">Shaoqing Ren, “Graph Search,” (2016)</span>.`,
+		},
+		{
+			"bibliography",
+			Notes{}.FormatReference(cite.Reference{Entry: entry}),
+			`<span class="citation-full">Ren, Shaoqing. “Graph Search.” (2016).</span>`,
+		},
+		{
+			"author-date",
+			AuthorDate{}.FormatReference(cite.Reference{Entry: entry}),
+			`<span class="citation-full">Ren, Shaoqing. 2016. “Graph Search.”</span>`,
+		},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %q; want %q", tt.name, tt.got, tt.want)
+		}
+	}
+}
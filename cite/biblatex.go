@@ -0,0 +1,206 @@
+package cite
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jschaf/bibtex"
+	bibtexAst "github.com/jschaf/bibtex/ast"
+)
+
+// Date is a calendar date. Missing parts are 0.
+type Date struct {
+	Year, Month, Day int
+}
+
+// MonthNames are the English names of the months, January first.
+var MonthNames = [12]string{
+	"January", "February", "March", "April", "May", "June",
+	"July", "August", "September", "October", "November", "December",
+}
+
+// MonthKeys are the BibTeX abbreviations of the months, January first.
+var MonthKeys = [12]string{
+	"jan", "feb", "mar", "apr", "may", "jun",
+	"jul", "aug", "sep", "oct", "nov", "dec",
+}
+
+// String formats a date with the month name, like "March 15, 2021",
+// "March 2021" or "2021".
+func (d Date) String() string {
+	switch {
+	case d.Month < 1 || d.Month > 12:
+		return strconv.Itoa(d.Year)
+	case d.Day == 0:
+		return fmt.Sprintf("%s %d", MonthNames[d.Month-1], d.Year)
+	default:
+		return fmt.Sprintf("%s %d, %d", MonthNames[d.Month-1], d.Day, d.Year)
+	}
+}
+
+// ParseDate parses a BibLaTeX date, in the EDTF format of ISO 8601 like
+// "2021-03-15", "2021-03" or "2021". A range like "2020/2021" gives its
+// start, and the qualifiers "?", "~" and "%" of uncertain and approximate
+// dates are ignored.
+func ParseDate(s string) (Date, bool) {
+	start, _, _ := strings.Cut(strings.TrimSpace(s), "/")
+	start = strings.TrimRight(start, "?~%")
+	start, _, _ = strings.Cut(start, "T")
+	parts := strings.Split(start, "-")
+	if len(parts) > 3 || len(parts[0]) != 4 {
+		return Date{}, false
+	}
+	var d Date
+	for i, p := range parts {
+		n, err := strconv.Atoi(strings.TrimRight(p, "?~%"))
+		if err != nil {
+			return Date{}, false
+		}
+		switch i {
+		case 0:
+			d.Year = n
+		case 1:
+			// Months above 12 are seasons, like 21 for spring
+			if n >= 1 && n <= 12 {
+				d.Month = n
+			}
+		case 2:
+			d.Day = n
+		}
+	}
+	if d.Month == 0 {
+		d.Day = 0
+	}
+	return d, true
+}
+
+// URLDate returns the date the URL of an entry was accessed, from its
+// urldate field.
+func URLDate(entry *bibtex.Entry) (Date, bool) {
+	return ParseDate(Field(entry, "urldate"))
+}
+
+// biblatexTypes maps BibLaTeX entry types to the BibTeX types styles know.
+// Theses depend on their type field and are handled in Normalize.
+var biblatexTypes = map[string]string{
+	"online":        "misc",
+	"www":           "misc",
+	"electronic":    "misc",
+	"dataset":       "misc",
+	"software":      "misc",
+	"report":        "techreport",
+	"mvbook":        "book",
+	"bookinbook":    "inbook",
+	"collection":    "book",
+	"mvcollection":  "book",
+	"mvproceedings": "proceedings",
+}
+
+// biblatexFields maps BibLaTeX fields to the BibTeX fields with the same
+// meaning.
+var biblatexFields = map[string]string{
+	"journaltitle": "journal",
+	"location":     "address",
+	"eprintclass":  "primaryclass",
+}
+
+// thesisTypes are the BibTeX types of the known types of a thesis, in
+// lowercase: the keys of biblatex and the usual descriptions.
+var thesisTypes = map[string]string{
+	"phdthesis":             bibtex.EntryPhDThesis,
+	"phd":                   bibtex.EntryPhDThesis,
+	"phd thesis":            bibtex.EntryPhDThesis,
+	"ph.d. thesis":          bibtex.EntryPhDThesis,
+	"phd dissertation":      bibtex.EntryPhDThesis,
+	"ph.d. dissertation":    bibtex.EntryPhDThesis,
+	"doctoral thesis":       bibtex.EntryPhDThesis,
+	"doctoral dissertation": bibtex.EntryPhDThesis,
+	"mathesis":              bibtex.EntryMastersThesis,
+	"master's thesis":       bibtex.EntryMastersThesis,
+	"masters thesis":        bibtex.EntryMastersThesis,
+	"master thesis":         bibtex.EntryMastersThesis,
+	"ma thesis":             bibtex.EntryMastersThesis,
+	"m.a. thesis":           bibtex.EntryMastersThesis,
+	"ms thesis":             bibtex.EntryMastersThesis,
+	"m.s. thesis":           bibtex.EntryMastersThesis,
+	"msc thesis":            bibtex.EntryMastersThesis,
+	"m.sc. thesis":          bibtex.EntryMastersThesis,
+}
+
+// Normalize maps the BibLaTeX data model of an entry to the BibTeX fields
+// and entry types that styles read, so that BibLaTeX files render like
+// BibTeX ones:
+//
+//   - the date field gives the year, month and day fields
+//   - journaltitle, location and eprintclass give journal, address and
+//     primaryclass
+//   - eprinttype gives archiveprefix, like "arXiv"
+//   - @online and other web types become misc entries, and @report a
+//     techreport
+//   - @thesis becomes a phdthesis or mastersthesis if its type field is a
+//     known one, like "phdthesis" or "Master's thesis", and stays a thesis
+//     with its type otherwise, like "Bachelor's thesis"; its institution
+//     becomes its school
+//
+// Fields the entry already has are kept. The urldate field is read by
+// URLDate.
+func Normalize(entry *bibtex.Entry) {
+	setText := func(field, value string) {
+		if _, ok := entry.Tags[field]; !ok && value != "" {
+			entry.Tags[field] = &bibtexAst.Text{Value: value}
+		}
+	}
+
+	for from, to := range biblatexFields {
+		if value, ok := entry.Tags[from]; ok {
+			if _, set := entry.Tags[to]; !set {
+				entry.Tags[to] = value
+			}
+		}
+	}
+	if eprintType := Field(entry, "eprinttype"); strings.EqualFold(eprintType, "arxiv") {
+		setText("archiveprefix", "arXiv")
+	} else {
+		setText("archiveprefix", eprintType)
+	}
+
+	if _, ok := entry.Tags["year"]; !ok {
+		if d, ok := ParseDate(Field(entry, "date")); ok {
+			setText("year", strconv.Itoa(d.Year))
+			if _, ok := entry.Tags["month"]; !ok && d.Month > 0 {
+				entry.Tags["month"] = &bibtexAst.Ident{Name: MonthKeys[d.Month-1]}
+			}
+			if d.Day > 0 {
+				setText("day", strconv.Itoa(d.Day))
+			}
+		}
+	}
+
+	typ := strings.ToLower(entry.Type)
+	if t, ok := biblatexTypes[typ]; ok {
+		entry.Type = t
+	}
+	switch typ {
+	case "thesis":
+		kind := strings.ToLower(strings.ReplaceAll(Field(entry, "type"), "’", "'"))
+		if t, ok := thesisTypes[strings.TrimSpace(kind)]; ok {
+			entry.Type = t
+		} else {
+			entry.Type = "thesis"
+		}
+		// The keys of the standard types are not descriptions
+		if kind == "phdthesis" || kind == "mathesis" {
+			delete(entry.Tags, "type")
+		}
+		if value, ok := entry.Tags["institution"]; ok {
+			if _, set := entry.Tags["school"]; !set {
+				entry.Tags["school"] = value
+			}
+		}
+	case "report":
+		if strings.EqualFold(Field(entry, "type"), "techreport") {
+			delete(entry.Tags, "type")
+		}
+	}
+}
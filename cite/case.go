@@ -0,0 +1,300 @@
+package cite
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TextCase is a case transformation of titles, as in CSL.
+type TextCase int
+
+const (
+	// AsIs keeps the case of a title.
+	AsIs TextCase = iota
+	// Lowercase lowercases every letter.
+	Lowercase
+	// Uppercase uppercases every letter.
+	Uppercase
+	// CapitalizeFirst capitalizes the first word.
+	CapitalizeFirst
+	// CapitalizeAll capitalizes every word.
+	CapitalizeAll
+	// SentenceCase capitalizes the first word of the title and of its
+	// subtitle, and lowercases the other capitalized words, like "The RETE
+	// algorithm: A study". Words with other capitals, like "RETE" or
+	// "iPhone", are kept.
+	SentenceCase
+	// TitleCase capitalizes every word but the articles, conjunctions and
+	// short prepositions of StopWords, which are lowercased unless they
+	// start or end the title or its subtitle, like "The Art of Computer
+	// Programming: In Practice".
+	TitleCase
+)
+
+// NoCaseOpen and NoCaseClose enclose the text of a title that keeps its
+// case, like {RETE} in BibTeX or <span class="nocase">RETE</span> in
+// CSL-JSON. ChangeCase removes them.
+const (
+	NoCaseOpen  = `<span class="nocase">`
+	NoCaseClose = `</span>`
+)
+
+// StopWords are the words that TitleCase lowercases.
+var StopWords = map[string]bool{
+	"a": true, "an": true, "the": true,
+	"and": true, "but": true, "or": true, "nor": true, "for": true, "so": true, "yet": true,
+	"as": true, "at": true, "by": true, "from": true, "in": true, "into": true, "of": true,
+	"off": true, "on": true, "onto": true, "per": true, "than": true, "to": true, "up": true,
+	"via": true, "vs": true, "with": true,
+}
+
+// ChangeCase returns the title s in a case. HTML tags and entities, and the
+// text that keeps its case, see NoCaseOpen, are left as is. The parts of
+// hyphenated words change case like words, like "State-of-the-Art" in title
+// case.
+func ChangeCase(s string, c TextCase) string {
+	tokens := tokenizeTitle(s)
+	last := -1
+	for i, t := range tokens {
+		if t.kind == wordToken || t.kind == keptToken {
+			last = i
+		}
+	}
+	var sb strings.Builder
+	// start is whether the next word starts the title or its subtitle
+	start := true
+	for i, t := range tokens {
+		switch t.kind {
+		case wordToken:
+			// Words split by tags, entities or kept text, like R&amp;D,
+			// keep the case of their middle
+			glued := i >= 1 && tokens[i-1].kind == keptToken ||
+				i >= 2 && tokens[i-1].kind == markupToken &&
+					(tokens[i-2].kind == wordToken || tokens[i-2].kind == keptToken)
+			if glued {
+				sb.WriteString(t.text)
+			} else {
+				sb.WriteString(changeWordCase(t.text, c, start, i == last))
+			}
+			start = endsClause(t.text)
+		case keptToken:
+			sb.WriteString(t.text)
+			start = false
+		default:
+			sb.WriteString(t.text)
+		}
+	}
+	return sb.String()
+}
+
+// changeWordCase changes the case of a word, which starts the title or its
+// subtitle, or is the last word of the title.
+func changeWordCase(word string, c TextCase, start, last bool) string {
+	switch c {
+	case Lowercase:
+		return strings.ToLower(word)
+	case Uppercase:
+		return strings.ToUpper(word)
+	case CapitalizeFirst:
+		if start {
+			return capitalize(word)
+		}
+		return word
+	case SentenceCase:
+		return mapParts(word, func(part string, first bool) string {
+			if first && start {
+				return capitalize(part)
+			}
+			if isCapitalized(part) {
+				return strings.ToLower(part)
+			}
+			return part
+		})
+	case CapitalizeAll:
+		return capitalize(word)
+	case TitleCase:
+		return mapParts(word, func(part string, first bool) string {
+			if StopWords[strings.ToLower(trimPunct(part))] && !(first && (start || last)) && !hasInnerUpper(part) {
+				return strings.ToLower(part)
+			}
+			return capitalize(part)
+		})
+	default:
+		return word
+	}
+}
+
+// mapParts applies f to the parts of a hyphenated word, with whether the
+// part is the first.
+func mapParts(word string, f func(part string, first bool) string) string {
+	parts := strings.Split(word, "-")
+	for i, part := range parts {
+		parts[i] = f(part, i == 0)
+	}
+	return strings.Join(parts, "-")
+}
+
+// capitalize uppercases the first letter of a word, unless the word has
+// capitals after it, like "iPhone", or is a URL.
+func capitalize(word string) string {
+	if hasInnerUpper(word) || strings.Contains(word, "://") {
+		return word
+	}
+	for i, r := range word {
+		if unicode.IsLetter(r) {
+			return word[:i] + string(unicode.ToUpper(r)) + word[i+utf8.RuneLen(r):]
+		}
+		if unicode.IsDigit(r) {
+			break
+		}
+	}
+	return word
+}
+
+// isCapitalized reports whether the first letter of a word is its only
+// capital, and it has other letters, like "Matching" but not "RETE" or "A".
+func isCapitalized(word string) bool {
+	letters := 0
+	for _, r := range word {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		if letters == 0 && !unicode.IsUpper(r) || letters > 0 && unicode.IsUpper(r) {
+			return false
+		}
+		letters++
+	}
+	return letters > 1
+}
+
+// hasInnerUpper reports whether a word has capitals after its first
+// letter.
+func hasInnerUpper(word string) bool {
+	letters := 0
+	for _, r := range word {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		if letters > 0 && unicode.IsUpper(r) {
+			return true
+		}
+		letters++
+	}
+	return false
+}
+
+// trimPunct trims the punctuation around a word, like the quotes and comma
+// of “and,”.
+func trimPunct(word string) string {
+	return strings.TrimFunc(word, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// endsClause reports whether the word after a word starts a subtitle or a
+// new clause, like the word after "Graphs:" or an em dash.
+func endsClause(word string) bool {
+	word = strings.TrimRight(word, `"'”’)]`)
+	return word == "—" || word == "–" || strings.HasSuffix(word, ":") ||
+		strings.HasSuffix(word, "?") || strings.HasSuffix(word, "!")
+}
+
+type tokenKind int
+
+const (
+	wordToken tokenKind = iota
+	spaceToken
+	// markupToken is an HTML tag or entity
+	markupToken
+	// keptToken is text that keeps its case
+	keptToken
+)
+
+type titleToken struct {
+	kind tokenKind
+	text string
+}
+
+// tokenizeTitle splits a title into words, spaces, markup, and the text
+// that keeps its case, without its nocase spans.
+func tokenizeTitle(s string) []titleToken {
+	var tokens []titleToken
+	for s != "" {
+		r, size := utf8.DecodeRuneInString(s)
+		switch {
+		case strings.HasPrefix(s, NoCaseOpen):
+			var kept string
+			kept, s = noCaseText(s[len(NoCaseOpen):])
+			tokens = append(tokens, titleToken{keptToken, ChangeCase(kept, AsIs)})
+		case s[0] == '<':
+			end := strings.IndexByte(s, '>') + 1
+			if end == 0 {
+				end = len(s)
+			}
+			tokens = append(tokens, titleToken{markupToken, s[:end]})
+			s = s[end:]
+		case s[0] == '&' && entityLen(s) > 0:
+			n := entityLen(s)
+			tokens = append(tokens, titleToken{markupToken, s[:n]})
+			s = s[n:]
+		case unicode.IsSpace(r):
+			end := strings.IndexFunc(s, func(r rune) bool { return !unicode.IsSpace(r) })
+			if end < 0 {
+				end = len(s)
+			}
+			tokens = append(tokens, titleToken{spaceToken, s[:end]})
+			s = s[end:]
+		default:
+			end := size
+			for end < len(s) {
+				r, n := utf8.DecodeRuneInString(s[end:])
+				if unicode.IsSpace(r) || s[end] == '<' || s[end] == '&' && entityLen(s[end:]) > 0 {
+					break
+				}
+				end += n
+			}
+			tokens = append(tokens, titleToken{wordToken, s[:end]})
+			s = s[end:]
+		}
+	}
+	return tokens
+}
+
+// noCaseText splits the text of a nocase span, whose opening tag was
+// consumed, from the rest of s after its closing tag. Spans in the text,
+// like those of math, are kept.
+func noCaseText(s string) (text, rest string) {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "<span"):
+			depth++
+		case strings.HasPrefix(s[i:], NoCaseClose):
+			if depth == 0 {
+				return s[:i], s[i+len(NoCaseClose):]
+			}
+			depth--
+		}
+	}
+	return s, ""
+}
+
+// entityLen returns the length of the HTML entity at the start of s, like
+// &amp; or &#39;, or 0 if there is none.
+func entityLen(s string) int {
+	for i := 1; i < len(s) && i <= 32; i++ {
+		c := s[i]
+		switch {
+		case c == ';':
+			if i == 1 {
+				return 0
+			}
+			return i + 1
+		case c == '#' && i == 1, 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		default:
+			return 0
+		}
+	}
+	return 0
+}
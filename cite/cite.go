@@ -0,0 +1,116 @@
+// Package cite defines the citation data shared by the markdown extension and
+// the style packages.
+package cite
+
+import (
+	"html"
+	"strings"
+
+	"github.com/jschaf/bibtex"
+)
+
+// Mode is the way a work is cited in the text.
+type Mode int
+
+const (
+	// ModeNormal is a parenthetical citation, like "(Smith, 2023)".
+	ModeNormal Mode = iota
+	// ModeAuthorInText is a narrative citation, like "Smith (2023)".
+	ModeAuthorInText
+	// ModeSuppressAuthor omits the author, like "(2023)".
+	ModeSuppressAuthor
+)
+
+func (m Mode) String() string {
+	switch m {
+	case ModeAuthorInText:
+		return "AuthorInText"
+	case ModeSuppressAuthor:
+		return "SuppressAuthor"
+	default:
+		return "Normal"
+	}
+}
+
+// Item is a single cited work together with the way it is cited.
+type Item struct {
+	// Entry is the cited entry, or nil if the key is not in the bibliography.
+	Entry *bibtex.Entry
+	// Number is the position of the entry in the reference list, starting at
+	// 1, or 0 if the key is not in the bibliography.
+	Number  int
+	Key     string
+	Mode    Mode
+	Prefix  string
+	Locator string
+	Suffix  string
+	// First reports whether this is the first citation of the entry in the
+	// document. Note styles give first citations a full note.
+	First bool
+	// Disambiguation is the disambiguation of the reference of the entry.
+	Disambiguation
+}
+
+// Reference is an entry of the reference list.
+type Reference struct {
+	Entry *bibtex.Entry
+	// Number is the position of the entry in the reference list, starting at
+	// 1. Numeric styles cite the entry with this number.
+	Number int
+	// Disambiguation tells the work apart from other works with the same
+	// inline label, set for styles that disambiguate.
+	Disambiguation
+}
+
+// Decorate surrounds the label of an item with its escaped prefix, locator and
+// suffix, like "see Smith, 2023, p. 4, and passim".
+func (i Item) Decorate(label string) string {
+	if i.Prefix != "" {
+		label = html.EscapeString(i.Prefix) + " " + label
+	}
+	if i.Locator != "" {
+		label += ", " + html.EscapeString(i.Locator)
+	}
+	return label + html.EscapeString(i.Suffix)
+}
+
+// FormatList formats a reference list as an HTML list with the given tag, "ul"
+// or "ol", using format for each reference. Each item gets an id derived from
+// the entry key, like "ref-smith2023".
+func FormatList(tag string, refs []Reference, format func(ref Reference) string) string {
+	var sb strings.Builder
+	sb.WriteString("<" + tag + ">\n")
+	for _, ref := range refs {
+		sb.WriteString(`<li id="ref-` + html.EscapeString(ref.Entry.Key) + `">`)
+		sb.WriteString(format(ref))
+		sb.WriteString("</li>\n")
+	}
+	sb.WriteString("</" + tag + ">\n")
+	return sb.String()
+}
+
+// LessAuthorYear orders entries by the last name of their first author or
+// editor, or by the organization or title that leads them, then by year and
+// title. It is the usual reference list order of author-year
+// styles.
+func LessAuthorYear(a, b *bibtex.Entry) bool {
+	if x, y := firstAuthorLast(a), firstAuthorLast(b); x != y {
+		return x < y
+	}
+	if x, y := fieldText(a, "year"), fieldText(b, "year"); x != y {
+		return x < y
+	}
+	return strings.ToLower(fieldText(a, "title")) < strings.ToLower(fieldText(b, "title"))
+}
+
+func firstAuthorLast(entry *bibtex.Entry) string {
+	lead := LeadOf(entry)
+	if len(lead.Names) == 0 {
+		return strings.ToLower(lead.Organization + lead.Title)
+	}
+	return strings.ToLower(Text(lead.Names[0].Last))
+}
+
+func fieldText(entry *bibtex.Entry, field string) string {
+	return Field(entry, field)
+}
@@ -0,0 +1,223 @@
+package cite
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jschaf/bibtex"
+	bibtexAst "github.com/jschaf/bibtex/ast"
+)
+
+// Disambiguation is what a style adds to the inline label of a work to tell it
+// apart from other cited works with the same label, like "Smith 2020a" and
+// "Smith 2020b".
+type Disambiguation struct {
+	// YearSuffix is appended to the year, like "a" in "2020a".
+	YearSuffix string
+	// Initials adds the initials of the first author, like "J. Smith", because
+	// another cited first author has the same last name.
+	Initials bool
+	// Names is the number of names shown before "et al." when more names than
+	// usual are needed to tell works apart, or 0 otherwise.
+	Names int
+}
+
+// DisambiguationRules are the ways a style tells works apart. They are tried
+// in order: initials, then more names, then year suffixes.
+type DisambiguationRules struct {
+	// Truncation is the truncation of names in inline citations.
+	Truncation Truncation
+	// AddInitials adds the initials of first authors who share their last
+	// name with another cited first author.
+	AddInitials bool
+	// AddNames shows more names before "et al." until the labels differ.
+	AddNames bool
+	// AddYearSuffix appends "a", "b"… to the years of works that still have
+	// the same label, in the order of the reference list.
+	AddYearSuffix bool
+}
+
+// labelName is a name as shown in inline labels.
+type labelName struct {
+	last, initial string
+}
+
+// Disambiguate sets the disambiguation of the references, which are in the
+// order of the reference list, following the rules of a style.
+func Disambiguate(refs []Reference, rules DisambiguationRules) {
+	names := make([][]labelName, len(refs))
+	for i, ref := range refs {
+		names[i] = labelNames(ref.Entry)
+		refs[i].Disambiguation = Disambiguation{}
+	}
+	shown := func(i int) int {
+		n, _ := rules.Truncation.Shown(len(names[i]))
+		if d := refs[i].Names; d > n {
+			n = d
+		}
+		return n
+	}
+	label := func(i, shown int) string {
+		parts := make([]string, 0, shown+2)
+		for j, name := range names[i][:shown] {
+			if j == 0 && refs[i].Initials {
+				parts = append(parts, name.initial+" "+name.last)
+			} else {
+				parts = append(parts, name.last)
+			}
+		}
+		if shown < len(names[i]) {
+			parts = append(parts, "et al.")
+		}
+		return strings.Join(append(parts, fieldText(refs[i].Entry, "year")), "|")
+	}
+
+	if rules.AddInitials {
+		initials := make(map[string]map[string]bool)
+		for i := range refs {
+			if len(names[i]) == 0 {
+				continue
+			}
+			first := names[i][0]
+			if initials[first.last] == nil {
+				initials[first.last] = make(map[string]bool)
+			}
+			initials[first.last][first.initial] = true
+		}
+		for i := range refs {
+			if len(names[i]) > 0 && len(initials[names[i][0].last]) > 1 {
+				refs[i].Initials = true
+			}
+		}
+	}
+
+	if rules.AddNames {
+		for _, group := range ambiguous(len(refs), func(i int) string { return label(i, shown(i)) }) {
+			for _, i := range group {
+				n := shown(i)
+				for n < len(names[i]) && conflicts(group, i, func(j int) string { return label(j, n) }, names) {
+					n++
+				}
+				if n > shown(i) {
+					refs[i].Names = n
+				}
+			}
+		}
+	}
+
+	if rules.AddYearSuffix {
+		for _, group := range ambiguous(len(refs), func(i int) string { return label(i, shown(i)) }) {
+			for k, i := range group {
+				refs[i].YearSuffix = yearSuffix(k)
+			}
+		}
+	}
+}
+
+// WithYearSuffix returns a copy of an entry whose year has the year suffix
+// appended, like "2020a", for formatting a disambiguated reference. It returns
+// the entry itself if the suffix is empty.
+func WithYearSuffix(entry *bibtex.Entry, suffix string) *bibtex.Entry {
+	year, ok := entry.Tags["year"].(*bibtexAst.Text)
+	if suffix == "" || !ok {
+		return entry
+	}
+	copied := *entry
+	copied.Tags = make(map[string]bibtexAst.Expr, len(entry.Tags))
+	for field, value := range entry.Tags {
+		copied.Tags[field] = value
+	}
+	copied.Tags["year"] = &bibtexAst.Text{Value: year.Value + suffix}
+	return &copied
+}
+
+// ambiguous returns the groups of two or more indices with the same label, in
+// order.
+func ambiguous(n int, label func(i int) string) [][]int {
+	groups := make(map[string][]int)
+	var labels []string
+	for i := 0; i < n; i++ {
+		l := label(i)
+		if groups[l] == nil {
+			labels = append(labels, l)
+		}
+		groups[l] = append(groups[l], i)
+	}
+	var result [][]int
+	for _, l := range labels {
+		if len(groups[l]) > 1 {
+			result = append(result, groups[l])
+		}
+	}
+	return result
+}
+
+// conflicts reports whether another work of the group with different names
+// has the same label as work i.
+func conflicts(group []int, i int, label func(j int) string, names [][]labelName) bool {
+	for _, j := range group {
+		if j != i && label(j) == label(i) && !sameNames(names[i], names[j]) {
+			return true
+		}
+	}
+	return false
+}
+
+func sameNames(a, b []labelName) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// yearSuffix returns the year suffix of the k-th work with the same label:
+// "a" to "z", then "aa", "ab"…
+func yearSuffix(k int) string {
+	if k < 26 {
+		return string(rune('a' + k))
+	}
+	return yearSuffix(k/26-1) + yearSuffix(k%26)
+}
+
+func labelNames(entry *bibtex.Entry) []labelName {
+	authors := LeadOf(entry).Names
+	names := make([]labelName, 0, len(authors))
+	for _, author := range authors {
+		last, _ := author.Last.(*bibtexAst.Text)
+		if last == nil {
+			continue
+		}
+		name := labelName{last: last.Value}
+		if prefix, _ := author.Prefix.(*bibtexAst.Text); prefix != nil && prefix.Value != "" {
+			name.last = prefix.Value + " " + name.last
+		}
+		if first, _ := author.First.(*bibtexAst.Text); first != nil {
+			name.initial = Initials(first.Value)
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// Initials abbreviates given names to initials, like "J. P." for "John Peter"
+// and "T.-H." for "Thanh-Ha". Parts that do not start with a letter, like
+// "(Jr)", are kept.
+func Initials(given string) string {
+	words := strings.Fields(given)
+	for i, word := range words {
+		parts := strings.Split(word, "-")
+		for j, part := range parts {
+			if r, _ := utf8.DecodeRuneInString(part); unicode.IsLetter(r) {
+				parts[j] = string(r) + "."
+			}
+		}
+		words[i] = strings.Join(parts, "-")
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,53 @@
+package cite
+
+import "math"
+
+// Truncation shortens long lists of names: lists of at least Min names show
+// the first UseFirst names followed by "et al.", like "Smith et al." The zero
+// Truncation is unset, and styles use their default instead.
+type Truncation struct {
+	Min, UseFirst int
+	// UseLast shows the last name after an ellipsis instead of "et al.", like
+	// "Smith, Jones, … Lee" in APA reference lists.
+	UseLast bool
+}
+
+// NoTruncation never shortens lists of names.
+var NoTruncation = Truncation{Min: math.MaxInt}
+
+// Shown returns how many of n names are shown, and whether the list is
+// truncated.
+func (t Truncation) Shown(n int) (shown int, truncated bool) {
+	if t.Min > 0 && t.UseFirst > 0 && n >= t.Min && t.UseFirst < n {
+		return t.UseFirst, true
+	}
+	return n, false
+}
+
+// Or returns t, or def if t is unset.
+func (t Truncation) Or(def Truncation) Truncation {
+	if t == (Truncation{}) {
+		return def
+	}
+	return t
+}
+
+// EtAl configures the truncation of name lists of a style, separately for
+// inline citations and for the reference list. Unset fields keep the default
+// of the style, for example
+//
+//	apa.Style{EtAl: cite.EtAl{Reference: cite.Truncation{Min: 4, UseFirst: 1}}}
+//
+// lists "Smith et al." in APA references with four or more authors.
+type EtAl struct {
+	Inline    Truncation
+	Reference Truncation
+}
+
+// Or returns e with its unset fields taken from def.
+func (e EtAl) Or(def EtAl) EtAl {
+	return EtAl{
+		Inline:    e.Inline.Or(def.Inline),
+		Reference: e.Reference.Or(def.Reference),
+	}
+}
@@ -0,0 +1,179 @@
+package cite
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jschaf/bibtex"
+	bibtexAst "github.com/jschaf/bibtex/ast"
+)
+
+// NoDate is the year of works without a year, like "Smith (n.d.)".
+const NoDate = "n.d."
+
+// Text returns the text of a resolved field value, or "" if the value is
+// missing or is not text, a number or a month abbreviation. The nocase spans
+// of titles are removed, see Title.
+func Text(expr bibtexAst.Expr) string {
+	switch expr := expr.(type) {
+	case *bibtexAst.Text:
+		if strings.Contains(expr.Value, NoCaseOpen) {
+			return ChangeCase(expr.Value, AsIs)
+		}
+		return expr.Value
+	case *bibtexAst.Ident:
+		return expr.Name
+	case *bibtexAst.Number:
+		return expr.Value
+	default:
+		return ""
+	}
+}
+
+// Field returns the text of a field of an entry, see Text.
+func Field(entry *bibtex.Entry, field string) string {
+	return Text(entry.Tags[field])
+}
+
+// Title returns a title field of an entry in a case. The parts of the field
+// value that keep their case, like {RETE} in BibTeX, are in nocase spans, see
+// NoCaseOpen, which ChangeCase removes.
+func Title(entry *bibtex.Entry, field string, c TextCase) string {
+	if text, ok := entry.Tags[field].(*bibtexAst.Text); ok {
+		return ChangeCase(text.Value, c)
+	}
+	return ChangeCase(Field(entry, field), c)
+}
+
+// Names returns the names of a name field, like "author" or "editor", or nil
+// if the field is missing or was not resolved to names.
+func Names(entry *bibtex.Entry, field string) bibtexAst.Authors {
+	names, _ := entry.Tags[field].(bibtexAst.Authors)
+	return names
+}
+
+// Year returns the year of an entry, or NoDate if it has none.
+func Year(entry *bibtex.Entry) string {
+	if year := Field(entry, "year"); year != "" {
+		return year
+	}
+	return NoDate
+}
+
+// Lead is the element that leads the reference of a work and its inline
+// label. It is the first of the authors, the editors, the organization and
+// the title that the entry has.
+type Lead struct {
+	// Names are the authors, or the editors if Editors is set.
+	Names   bibtexAst.Authors
+	Editors bool
+	// Organization is set for works without authors and editors.
+	Organization string
+	// Title is set for works without authors, editors and organization. The
+	// title then leads the reference instead of following the names.
+	Title string
+}
+
+// LeadOf returns the lead of an entry.
+func LeadOf(entry *bibtex.Entry) Lead {
+	if names := Names(entry, "author"); len(names) > 0 {
+		return Lead{Names: names}
+	}
+	if names := Names(entry, "editor"); len(names) > 0 {
+		return Lead{Names: names, Editors: true}
+	}
+	if org := Field(entry, "organization"); org != "" {
+		return Lead{Organization: org}
+	}
+	return Lead{Title: Field(entry, "title")}
+}
+
+// Label returns the inline label of a lead without names: the organization,
+// or the title, or "?" for an entry with neither.
+func (l Lead) Label() string {
+	switch {
+	case l.Organization != "":
+		return l.Organization
+	case l.Title != "":
+		return l.Title
+	default:
+		return "?"
+	}
+}
+
+// Warning is a problem with an entry that styles work around, like a missing
+// year or a field of an unexpected shape. Problems with a whole bibliography,
+// like a file that cannot be read, have no key.
+type Warning struct {
+	Key     string
+	Field   string
+	Message string
+}
+
+func (w Warning) Error() string {
+	if w.Key == "" {
+		return w.Message
+	}
+	if w.Field == "" {
+		return fmt.Sprintf("entry %q: %s", w.Key, w.Message)
+	}
+	return fmt.Sprintf("entry %q: field %q: %s", w.Key, w.Field, w.Message)
+}
+
+// Check returns the problems of an entry that styles work around: fields
+// that are neither text nor names, author and editor fields that are not
+// names, and a missing lead or year.
+func Check(entry *bibtex.Entry) []Warning {
+	var warnings []Warning
+	for field, value := range entry.Tags {
+		switch value.(type) {
+		case bibtexAst.Authors:
+		case *bibtexAst.Text, *bibtexAst.Ident, *bibtexAst.Number:
+			if field == "author" || field == "editor" {
+				warnings = append(warnings, Warning{entry.Key, field, "not resolved to names, ignored"})
+			}
+		default:
+			warnings = append(warnings, Warning{entry.Key, field, fmt.Sprintf("unexpected value %T, ignored", value)})
+		}
+	}
+	// Tags have no order, sort for stable output
+	sort.Slice(warnings, func(i, j int) bool {
+		return warnings[i].Field < warnings[j].Field
+	})
+
+	lead := LeadOf(entry)
+	switch {
+	case lead.Names != nil || lead.Organization != "":
+	case lead.Title != "":
+		warnings = append(warnings, Warning{entry.Key, "author", "no author, editor or organization, the title is used instead"})
+	default:
+		warnings = append(warnings, Warning{entry.Key, "author", "no author, editor, organization or title"})
+	}
+	if Field(entry, "year") == "" {
+		warnings = append(warnings, Warning{entry.Key, "year", "no year, " + NoDate + " is used instead"})
+	}
+	return warnings
+}
+
+// Ordinal returns the English ordinal of a number, like "1st", "22nd" or
+// "113th", or n as is if it is not a number.
+func Ordinal(n string) string {
+	i, err := strconv.Atoi(n)
+	if err != nil || i < 0 {
+		return n
+	}
+	switch {
+	case i%100 >= 11 && i%100 <= 13:
+		return n + "th"
+	case i%10 == 1:
+		return n + "st"
+	case i%10 == 2:
+		return n + "nd"
+	case i%10 == 3:
+		return n + "rd"
+	default:
+		return n + "th"
+	}
+}
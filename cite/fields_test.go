@@ -0,0 +1,32 @@
+package cite
+
+import "testing"
+
+func TestOrdinal(t *testing.T) {
+	tests := map[string]string{
+		"1": "1st", "2": "2nd", "3": "3rd", "4": "4th", "11": "11th", "12": "12th",
+		"13": "13th", "21": "21st", "22": "22nd", "23": "23rd", "111": "111th",
+		"112": "112th", "101": "101st", "revised": "revised", "": "",
+	}
+	for n, want := range tests {
+		if got := Ordinal(n); got != want {
+			t.Errorf("Ordinal(%q) = %q; want %q", n, got, want)
+		}
+	}
+}
+
+func TestInitials(t *testing.T) {
+	tests := map[string]string{
+		"John Peter": "J. P.",
+		"Thanh-Ha":   "T.-H.",
+		"J. P.":      "J. P.",
+		"Émile":      "É.",
+		"(Jr)":       "(Jr)",
+		"":           "",
+	}
+	for given, want := range tests {
+		if got := Initials(given); got != want {
+			t.Errorf("Initials(%q) = %q; want %q", given, got, want)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package cite
+
+import (
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jschaf/bibtex"
+	bibtexAst "github.com/jschaf/bibtex/ast"
+)
+
+// ParseName parses a name written "Last, First" or "Last, Suffix, First", as
+// in RIS and EndNote files, or "First Last" without commas. A lowercase
+// prefix of the last name, like "van" in "van Gogh, Vincent", is its von
+// part.
+func ParseName(s string) *bibtexAst.Author {
+	parts := strings.Split(s, ",")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	var first, last, suffix string
+	switch len(parts) {
+	case 1:
+		if i := strings.LastIndexByte(parts[0], ' '); i >= 0 {
+			first, last = parts[0][:i], parts[0][i+1:]
+		} else {
+			last = parts[0]
+		}
+	case 2:
+		last, first = parts[0], parts[1]
+	default:
+		last, suffix, first = parts[0], parts[1], strings.Join(parts[2:], ", ")
+	}
+
+	var prefix string
+	words := strings.Fields(last)
+	for len(words) > 1 && unicode.IsLower([]rune(words[0])[0]) {
+		prefix = strings.TrimSpace(prefix + " " + words[0])
+		words = words[1:]
+	}
+	if prefix != "" {
+		last = strings.Join(words, " ")
+	}
+	return &bibtexAst.Author{
+		First:  &bibtexAst.Text{Value: strings.TrimSpace(first)},
+		Prefix: &bibtexAst.Text{Value: prefix},
+		Last:   &bibtexAst.Text{Value: strings.TrimSpace(last)},
+		Suffix: &bibtexAst.Text{Value: suffix},
+	}
+}
+
+// PageRange formats a page range with an en dash, like the decoded page
+// ranges of BibTeX files: "223–241" for "223-241" or "223--241".
+func PageRange(pages string) string {
+	return strings.NewReplacer("--", "–", "-", "–").Replace(pages)
+}
+
+// KeyGenerator makes keys for entries of formats without keys, from the last
+// name of the lead and the year, like "Smith2020". Accented Latin letters are
+// folded to ASCII, like "Muller2020" for Müller, so that keys can be cited.
+// Keys already given are suffixed with a letter, like "Smith2020a".
+type KeyGenerator map[string]bool
+
+// Key returns a new key for an entry.
+func (g KeyGenerator) Key(entry *bibtex.Entry) string {
+	var base string
+	lead := LeadOf(entry)
+	if len(lead.Names) > 0 {
+		base = Text(lead.Names[0].Last)
+	} else {
+		base = lead.Label()
+	}
+	// Field values are escaped for HTML, like "O&#39;Brien"
+	var sb strings.Builder
+	for _, r := range html.UnescapeString(base) {
+		switch {
+		case r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			sb.WriteRune(r)
+		case asciiFolds[r] != "":
+			sb.WriteString(asciiFolds[r])
+		}
+	}
+	base = sb.String()
+	if base == "" {
+		base = "ref"
+	}
+	base += Field(entry, "year")
+
+	key := base
+	for i := 0; g[key]; i++ {
+		key = base + yearSuffix(i)
+	}
+	g[key] = true
+	return key
+}
+
+// asciiFolds maps the letters of the Latin-1 Supplement and Latin
+// Extended-A blocks to ASCII letters, like ü to u and ß to ss.
+var asciiFolds = make(map[rune]string)
+
+func init() {
+	for ascii, letters := range map[string]string{
+		"a": "àáâãäåāăą", "A": "ÀÁÂÃÄÅĀĂĄ",
+		"c": "çćĉċč", "C": "ÇĆĈĊČ",
+		"d": "ďđð", "D": "ĎĐÐ",
+		"e": "èéêëēĕėęě", "E": "ÈÉÊËĒĔĖĘĚ",
+		"g": "ĝğġģ", "G": "ĜĞĠĢ",
+		"h": "ĥħ", "H": "ĤĦ",
+		"i": "ìíîïĩīĭįı", "I": "ÌÍÎÏĨĪĬĮİ",
+		"j": "ĵ", "J": "Ĵ",
+		"k": "ķ", "K": "Ķ",
+		"l": "ĺļľŀł", "L": "ĹĻĽĿŁ",
+		"n": "ñńņňŋ", "N": "ÑŃŅŇŊ",
+		"o": "òóôõöøōŏő", "O": "ÒÓÔÕÖØŌŎŐ",
+		"r": "ŕŗř", "R": "ŔŖŘ",
+		"s": "śŝşš", "S": "ŚŜŞŠ",
+		"t": "ţťŧ", "T": "ŢŤŦ",
+		"u": "ùúûüũūŭůűų", "U": "ÙÚÛÜŨŪŬŮŰŲ",
+		"w": "ŵ", "W": "Ŵ",
+		"y": "ýÿŷ", "Y": "ÝŶŸ",
+		"z": "źżž", "Z": "ŹŻŽ",
+		"ss": "ß", "ae": "æ", "AE": "Æ", "oe": "œ", "OE": "Œ", "th": "þ", "TH": "Þ",
+	} {
+		for _, r := range letters {
+			asciiFolds[r] = ascii
+		}
+	}
+}
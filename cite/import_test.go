@@ -0,0 +1,36 @@
+package cite
+
+import (
+	"testing"
+
+	"github.com/jschaf/bibtex"
+	bibtexAst "github.com/jschaf/bibtex/ast"
+)
+
+func TestKeyGenerator(t *testing.T) {
+	entry := func(last, year string) *bibtex.Entry {
+		tags := map[string]bibtexAst.Expr{"year": &bibtexAst.Text{Value: year}}
+		if last != "" {
+			tags["author"] = bibtexAst.Authors{ParseName(last + ", Hans")}
+		}
+		return &bibtex.Entry{Type: "article", Tags: tags}
+	}
+	tests := []struct {
+		last, year, want string
+	}{
+		{"Müller", "2020", "Muller2020"},
+		{"Müller", "2020", "Muller2020a"},
+		{"Ørsted", "1820", "Orsted1820"},
+		{"Straße", "1999", "Strasse1999"},
+		{"O'Brien-Łukasz", "2001", "OBrienLukasz2001"},
+		{"O&#39;Neil &amp; Sons", "2002", "ONeilSons2002"},
+		{"张", "2010", "ref2010"},
+		{"", "2011", "ref2011"},
+	}
+	keys := make(KeyGenerator)
+	for _, tt := range tests {
+		if got := keys.Key(entry(tt.last, tt.year)); got != tt.want {
+			t.Errorf("Key(%q, %s) = %s; want %s", tt.last, tt.year, got, tt.want)
+		}
+	}
+}
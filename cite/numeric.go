@@ -0,0 +1,78 @@
+package cite
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jschaf/bibtex"
+)
+
+// FormatNumeric formats the inline citation of a group of cited items in a
+// numeric style, like "[1–3, 7]". Runs of three or more consecutive numbers are
+// compressed to ranges; items with a prefix, locator or suffix are never
+// compressed, like in "[2, 5, see 1, p. 4]". A single item cited in the
+// ModeAuthorInText mode puts the names returned by author in front, like
+// "Smith et al. [1]".
+func FormatNumeric(items []Item, author func(entry *bibtex.Entry) string) string {
+	if len(items) == 1 && items[0].Mode == ModeAuthorInText && items[0].Entry != nil {
+		item := items[0]
+		item.Mode = ModeNormal
+		return author(item.Entry) + " " + FormatNumeric([]Item{item}, author)
+	}
+
+	var plain, decorated []Item
+	for _, item := range items {
+		if item.Prefix == "" && item.Locator == "" && item.Suffix == "" && item.Entry != nil {
+			plain = append(plain, item)
+		} else {
+			decorated = append(decorated, item)
+		}
+	}
+	sort.SliceStable(plain, func(i, j int) bool {
+		return plain[i].Number < plain[j].Number
+	})
+
+	var parts []string
+	for i := 0; i < len(plain); {
+		// Find the run of consecutive numbers starting at i
+		j := i
+		for j+1 < len(plain) && plain[j+1].Number <= plain[j].Number+1 {
+			j++
+		}
+		if plain[j].Number-plain[i].Number >= 2 {
+			keys := make([]string, 0, j-i+1)
+			for k, item := range plain[i : j+1] {
+				if k == 0 || item.Number != plain[i+k-1].Number {
+					keys = append(keys, item.Entry.Key)
+				}
+			}
+			label := fmt.Sprintf("%d–%d", plain[i].Number, plain[j].Number)
+			parts = append(parts, numberSpan(strings.Join(keys, " "), label))
+		} else {
+			for _, item := range plain[i : j+1] {
+				if len(parts) > 0 && parts[len(parts)-1] == numberSpan(item.Entry.Key, strconv.Itoa(item.Number)) {
+					// The same work cited twice in the group
+					continue
+				}
+				parts = append(parts, numberSpan(item.Entry.Key, strconv.Itoa(item.Number)))
+			}
+		}
+		i = j + 1
+	}
+
+	for _, item := range decorated {
+		if item.Entry == nil {
+			parts = append(parts, item.Decorate("?"))
+			continue
+		}
+		parts = append(parts, item.Decorate(numberSpan(item.Entry.Key, strconv.Itoa(item.Number))))
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+func numberSpan(keys, label string) string {
+	return fmt.Sprintf(`<span data-bibtex-key="%s" class="citation-key">%s</span>`, html.EscapeString(keys), label)
+}
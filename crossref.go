@@ -0,0 +1,158 @@
+package bibtex
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/jschaf/bibtex"
+	bibtexAst "github.com/jschaf/bibtex/ast"
+	"github.com/lmondada/goldmark-bibtex/cite"
+)
+
+// WithMinCrossrefs lists an entry in the reference list of a document when
+// at least n cited entries inherit from it with crossref, even if it is not
+// cited itself, like the -min-crossrefs option of BibTeX, whose default is 2.
+// Defaults to 0, which never lists parents that are not cited.
+func WithMinCrossrefs(n int) Option {
+	return func(e *Extender) {
+		e.minCrossrefs = n
+	}
+}
+
+// noInherit lists the fields that are never inherited, as in Biber.
+var noInherit = map[string]bool{
+	"crossref":       true,
+	"xdata":          true,
+	"xref":           true,
+	"ids":            true,
+	"entryset":       true,
+	"entrysubtype":   true,
+	"execute":        true,
+	"label":          true,
+	"options":        true,
+	"presort":        true,
+	"related":        true,
+	"relatedoptions": true,
+	"relatedstring":  true,
+	"relatedtype":    true,
+	"shorthand":      true,
+	"shorthandintro": true,
+	"sortkey":        true,
+}
+
+// containerTitles maps the types of parents to the types of their children
+// that take the title of the parent as their own container title, like the
+// booktitle of an inproceedings in a proceedings.
+var containerTitles = map[string]map[string]string{
+	"book": {
+		"inbook":       "booktitle",
+		"incollection": "booktitle",
+	},
+	"proceedings": {
+		"inproceedings": "booktitle",
+		"conference":    "booktitle",
+	},
+	"periodical": {
+		"article": "journal",
+	},
+}
+
+// inherit fills the fields an entry does not set from the @xdata entries it
+// lists in its xdata field, and then from the parent named by its crossref
+// field, which inherit from their own xdata entries and parents first. The
+// title of a book or proceedings becomes the booktitle of its chapters and
+// papers. The @xdata entries only hold shared fields, so they are moved out
+// of the bibliography. Keys are case-sensitive, as in Biber. Missing xdata
+// entries are ignored, and missing parents are reported when their children
+// are cited.
+func (e *Extender) inherit() {
+	if e.xdata == nil {
+		e.xdata = make(map[string]bibtex.Entry)
+	}
+	e.Bibliography = slices.DeleteFunc(e.Bibliography, func(entry bibtex.Entry) bool {
+		if !strings.EqualFold(entry.Type, "xdata") {
+			return false
+		}
+		e.xdata[entry.Key] = entry
+		delete(e.Origins, entry.Key)
+		return true
+	})
+
+	index := make(map[string]int, len(e.Bibliography))
+	for i, entry := range e.Bibliography {
+		index[entry.Key] = i
+	}
+	resolved := make([]bool, len(e.Bibliography))
+	var resolve func(i int)
+	resolve = func(i int) {
+		entry := &e.Bibliography[i]
+		if resolved[i] {
+			return
+		}
+		// Marked before inheriting, so that cycles end
+		resolved[i] = true
+
+		// Inherited fields go to a copy of the fields, which other
+		// bibliographies may share
+		tags := maps.Clone(entry.Tags)
+		for _, key := range xdataKeys(entry) {
+			inheritFields(tags, e.xdataFields(key, make(map[string]bool)), "")
+		}
+		if j, ok := index[cite.Field(entry, "crossref")]; ok && j != i {
+			resolve(j)
+			parent := e.Bibliography[j]
+			if field, ok := containerTitles[strings.ToLower(parent.Type)][strings.ToLower(entry.Type)]; ok {
+				if _, set := tags[field]; !set && parent.Tags["title"] != nil {
+					tags[field] = parent.Tags["title"]
+				}
+				inheritFields(tags, parent.Tags, "title")
+			} else {
+				inheritFields(tags, parent.Tags, "")
+			}
+		}
+		entry.Tags = tags
+	}
+	for i := range e.Bibliography {
+		resolve(i)
+	}
+}
+
+// xdataFields returns the fields of the @xdata entry with a key, with the
+// fields of the @xdata entries it lists itself, or nil if there is none.
+// Entries being visited are skipped, so that cycles end.
+func (e *Extender) xdataFields(key string, visiting map[string]bool) map[string]bibtexAst.Expr {
+	entry, ok := e.xdata[key]
+	if !ok || visiting[key] {
+		return nil
+	}
+	visiting[key] = true
+	defer delete(visiting, key)
+	fields := maps.Clone(entry.Tags)
+	for _, k := range xdataKeys(&entry) {
+		inheritFields(fields, e.xdataFields(k, visiting), "")
+	}
+	return fields
+}
+
+// xdataKeys returns the keys listed in the xdata field of an entry.
+func xdataKeys(entry *bibtex.Entry) []string {
+	var keys []string
+	for _, key := range strings.Split(cite.Field(entry, "xdata"), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// inheritFields copies the fields of a parent that fields does not set,
+// except the fields that are never inherited and skip.
+func inheritFields(fields, parent map[string]bibtexAst.Expr, skip string) {
+	for field, value := range parent {
+		if _, set := fields[field]; set || noInherit[field] || field == skip {
+			continue
+		}
+		fields[field] = value
+	}
+}
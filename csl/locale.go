@@ -0,0 +1,272 @@
+package csl
+
+import (
+	"strings"
+)
+
+// term is a localized term in its single and multiple forms.
+type term struct {
+	single, multiple string
+}
+
+// locale holds the terms of a locale by form ("long", "short", "symbol",
+// "verb", "verb-short") and name.
+type locale struct {
+	terms map[string]map[string]term
+}
+
+// enUS are the terms of the en-US locale, a subset of the CSL locales
+// repository.
+var enUS = map[string]map[string]term{
+	"long": {
+		"accessed":             {"accessed", "accessed"},
+		"and":                  {"and", "and"},
+		"and others":           {"and others", "and others"},
+		"anonymous":            {"anonymous", "anonymous"},
+		"at":                   {"at", "at"},
+		"available at":         {"available at", "available at"},
+		"by":                   {"by", "by"},
+		"circa":                {"circa", "circa"},
+		"cited":                {"cited", "cited"},
+		"edition":              {"edition", "editions"},
+		"et-al":                {"et al.", "et al."},
+		"forthcoming":          {"forthcoming", "forthcoming"},
+		"from":                 {"from", "from"},
+		"ibid":                 {"ibid.", "ibid."},
+		"in":                   {"in", "in"},
+		"in press":             {"in press", "in press"},
+		"internet":             {"internet", "internet"},
+		"letter":               {"letter", "letter"},
+		"no date":              {"no date", "no date"},
+		"online":               {"online", "online"},
+		"presented at":         {"presented at the", "presented at the"},
+		"reference":            {"reference", "references"},
+		"retrieved":            {"retrieved", "retrieved"},
+		"scale":                {"scale", "scale"},
+		"version":              {"version", "versions"},
+		"open-quote":           {"“", "“"},
+		"close-quote":          {"”", "”"},
+		"open-inner-quote":     {"‘", "‘"},
+		"close-inner-quote":    {"’", "’"},
+		"page-range-delimiter": {"–", "–"},
+		"ordinal":              {"th", "th"},
+		"ordinal-01":           {"st", "st"},
+		"ordinal-02":           {"nd", "nd"},
+		"ordinal-03":           {"rd", "rd"},
+		"ordinal-11":           {"th", "th"},
+		"ordinal-12":           {"th", "th"},
+		"ordinal-13":           {"th", "th"},
+		"book":                 {"book", "books"},
+		"chapter":              {"chapter", "chapters"},
+		"column":               {"column", "columns"},
+		"figure":               {"figure", "figures"},
+		"folio":                {"folio", "folios"},
+		"issue":                {"number", "numbers"},
+		"line":                 {"line", "lines"},
+		"note":                 {"note", "notes"},
+		"opus":                 {"opus", "opera"},
+		"page":                 {"page", "pages"},
+		"number-of-pages":      {"page", "pages"},
+		"paragraph":            {"paragraph", "paragraphs"},
+		"part":                 {"part", "parts"},
+		"section":              {"section", "sections"},
+		"sub verbo":            {"sub verbo", "sub verbis"},
+		"verse":                {"verse", "verses"},
+		"volume":               {"volume", "volumes"},
+		"director":             {"director", "directors"},
+		"editor":               {"editor", "editors"},
+		"editorial-director":   {"editor", "editors"},
+		"illustrator":          {"illustrator", "illustrators"},
+		"translator":           {"translator", "translators"},
+		"editortranslator":     {"editor & translator", "editors & translators"},
+		"month-01":             {"January", "January"},
+		"month-02":             {"February", "February"},
+		"month-03":             {"March", "March"},
+		"month-04":             {"April", "April"},
+		"month-05":             {"May", "May"},
+		"month-06":             {"June", "June"},
+		"month-07":             {"July", "July"},
+		"month-08":             {"August", "August"},
+		"month-09":             {"September", "September"},
+		"month-10":             {"October", "October"},
+		"month-11":             {"November", "November"},
+		"month-12":             {"December", "December"},
+	},
+	"short": {
+		"edition":            {"ed.", "eds."},
+		"et-al":              {"et al.", "et al."},
+		"no date":            {"n.d.", "n.d."},
+		"book":               {"bk.", "bks."},
+		"chapter":            {"chap.", "chaps."},
+		"column":             {"col.", "cols."},
+		"figure":             {"fig.", "figs."},
+		"folio":              {"fol.", "fols."},
+		"issue":              {"no.", "nos."},
+		"line":               {"l.", "ll."},
+		"note":               {"n.", "nn."},
+		"opus":               {"op.", "opp."},
+		"page":               {"p.", "pp."},
+		"number-of-pages":    {"p.", "pp."},
+		"paragraph":          {"para.", "paras."},
+		"part":               {"pt.", "pts."},
+		"section":            {"sec.", "secs."},
+		"sub verbo":          {"s.v.", "s.vv."},
+		"verse":              {"v.", "vv."},
+		"volume":             {"vol.", "vols."},
+		"director":           {"dir.", "dirs."},
+		"editor":             {"ed.", "eds."},
+		"editorial-director": {"ed.", "eds."},
+		"illustrator":        {"ill.", "ills."},
+		"translator":         {"tran.", "trans."},
+		"editortranslator":   {"ed. & tran.", "eds. & trans."},
+		"month-01":           {"Jan.", "Jan."},
+		"month-02":           {"Feb.", "Feb."},
+		"month-03":           {"Mar.", "Mar."},
+		"month-04":           {"Apr.", "Apr."},
+		"month-05":           {"May", "May"},
+		"month-06":           {"Jun.", "Jun."},
+		"month-07":           {"Jul.", "Jul."},
+		"month-08":           {"Aug.", "Aug."},
+		"month-09":           {"Sep.", "Sep."},
+		"month-10":           {"Oct.", "Oct."},
+		"month-11":           {"Nov.", "Nov."},
+		"month-12":           {"Dec.", "Dec."},
+	},
+	"symbol": {
+		"and":       {"&", "&"},
+		"section":   {"§", "§§"},
+		"paragraph": {"¶", "¶¶"},
+	},
+	"verb": {
+		"editor":     {"edited by", "edited by"},
+		"translator": {"translated by", "translated by"},
+		"director":   {"directed by", "directed by"},
+	},
+	"verb-short": {
+		"editor":     {"ed. by", "ed. by"},
+		"translator": {"trans. by", "trans. by"},
+	},
+}
+
+func newLocale() *locale {
+	l := &locale{terms: make(map[string]map[string]term, len(enUS))}
+	for form, terms := range enUS {
+		l.terms[form] = make(map[string]term, len(terms))
+		for name, t := range terms {
+			l.terms[form][name] = t
+		}
+	}
+	return l
+}
+
+// override replaces terms with the ones defined by a locale element of a
+// style.
+func (l *locale) override(e *element) {
+	terms := e.child("terms")
+	if terms == nil {
+		return
+	}
+	for _, t := range terms.Children {
+		if t.XMLName.Local != "term" {
+			continue
+		}
+		form := t.attr("form")
+		if form == "" {
+			form = "long"
+		}
+		var value term
+		if single := t.child("single"); single != nil {
+			value = term{single.Text, t.child("multiple").textOr(single.Text)}
+		} else {
+			value = term{t.Text, t.Text}
+		}
+		if l.terms[form] == nil {
+			l.terms[form] = make(map[string]term)
+		}
+		l.terms[form][t.attr("name")] = value
+	}
+}
+
+// term returns a term, falling back from the "verb-short" to the "verb" form
+// and from the "symbol" and "short" forms to the "long" form as specified by
+// CSL.
+func (l *locale) term(name, form string, plural bool) string {
+	if form == "" {
+		form = "long"
+	}
+	for {
+		if t, ok := l.terms[form][name]; ok {
+			if plural {
+				return t.multiple
+			}
+			return t.single
+		}
+		switch form {
+		case "verb-short":
+			form = "verb"
+		case "symbol":
+			form = "short"
+		case "short", "verb":
+			form = "long"
+		default:
+			return ""
+		}
+	}
+}
+
+func (e *element) textOr(fallback string) string {
+	if e == nil {
+		return fallback
+	}
+	return e.Text
+}
+
+// ordinal returns the ordinal suffix of a number, like "st" for 1 and "th" for
+// 11.
+func (l *locale) ordinal(n int) string {
+	if n%100 >= 11 && n%100 <= 13 {
+		return l.term("ordinal-"+twoDigits(n%100), "long", false)
+	}
+	switch n % 10 {
+	case 1, 2, 3:
+		return l.term("ordinal-0"+string(rune('0'+n%10)), "long", false)
+	default:
+		return l.term("ordinal", "long", false)
+	}
+}
+
+func twoDigits(n int) string {
+	return string([]rune{rune('0' + n/10), rune('0' + n%10)})
+}
+
+// locatorLabels maps the locator terms recognized by the citation parser to
+// CSL locator types.
+var locatorLabels = map[string]string{
+	"p.": "page", "pp.": "page", "page": "page", "pages": "page",
+	"ch.": "chapter", "chap.": "chapter", "chapter": "chapter",
+	"sec.": "section", "section": "section", "§": "section", "§§": "section",
+	"vol.": "volume", "vols.": "volume", "volume": "volume",
+	"fig.": "figure", "figs.": "figure", "figure": "figure",
+	"para.": "paragraph", "n.": "note", "nn.": "note",
+	"l.": "line", "ll.": "line", "line": "line", "lines": "line",
+	"col.": "column", "bk.": "book", "pt.": "part", "no.": "issue",
+	"v.": "verse", "art.": "article-locator",
+}
+
+// splitLocator splits a locator like "pp. 33-35" into its CSL locator type and
+// value. Locators without a known term are page locators.
+func splitLocator(locator string) (label, value string) {
+	term, rest, ok := strings.Cut(locator, " ")
+	if !ok {
+		for _, symbol := range []string{"§§", "§"} {
+			if strings.HasPrefix(locator, symbol) {
+				return "section", locator[len(symbol):]
+			}
+		}
+		return "page", locator
+	}
+	if label, ok := locatorLabels[term]; ok {
+		return label, strings.TrimSpace(rest)
+	}
+	return "page", locator
+}
@@ -0,0 +1,784 @@
+package csl
+
+import (
+	"encoding/xml"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jschaf/bibtex"
+	"github.com/lmondada/goldmark-bibtex/cite"
+)
+
+// context is the state of rendering one cited item or reference.
+type context struct {
+	style *Style
+	vars  variables
+	// item is the cited item, or nil when rendering a reference.
+	item *cite.Item
+	// number is the number of the reference when rendering a reference.
+	number int
+	// disambiguation is the disambiguation of the cited item or reference.
+	// The year suffix is added to the first rendered year unless the style
+	// renders the year-suffix variable.
+	disambiguation   cite.Disambiguation
+	yearSuffixIssued bool
+	// inherited are the inheritable name options of the style and of the
+	// citation or bibliography element.
+	inherited map[string]string
+	// suppressAuthor suppresses the first names element, whose output is kept
+	// in author for narrative citations.
+	suppressAuthor bool
+	author         string
+	// substituted are the variables rendered in place of missing names, which
+	// are not rendered again.
+	substituted map[string]bool
+	// calledVars and emptyVars count the variables rendered so far, to
+	// suppress groups whose variables are all empty.
+	calledVars, emptyVars int
+}
+
+// inheritableNameOptions are the name options that may be set on the style,
+// citation and bibliography elements.
+var inheritableNameOptions = []string{
+	"and", "delimiter-precedes-et-al", "delimiter-precedes-last", "et-al-min",
+	"et-al-use-first", "et-al-use-last", "initialize", "initialize-with",
+	"name-as-sort-order", "sort-separator", "name-form", "name-delimiter",
+	"names-delimiter",
+}
+
+func (s *Style) newContext(parent *element, entry *bibtex.Entry, item *cite.Item) *context {
+	ctx := &context{
+		style:     s,
+		vars:      newVariables(entry),
+		item:      item,
+		inherited: make(map[string]string),
+	}
+	if item != nil {
+		ctx.disambiguation = item.Disambiguation
+	}
+	for _, e := range []*element{s.root, parent} {
+		if e == nil {
+			continue
+		}
+		for _, option := range inheritableNameOptions {
+			if value := e.attr(option); value != "" {
+				ctx.inherited[option] = value
+			}
+		}
+	}
+	return ctx
+}
+
+// renderChildren renders the children of an element and joins the non-empty
+// results with the delimiter.
+func (ctx *context) renderChildren(e *element, delimiter string) string {
+	parts := make([]string, 0, len(e.Children))
+	for _, c := range e.Children {
+		if rendered := ctx.render(c); rendered != "" {
+			parts = append(parts, rendered)
+		}
+	}
+	return join(parts, delimiter)
+}
+
+// join joins parts with a delimiter like strings.Join, but without doubling
+// periods, like in "Smith, J.. 2023".
+func join(parts []string, delimiter string) string {
+	var s string
+	for i, part := range parts {
+		if i > 0 {
+			s = concat(s, delimiter)
+		}
+		s = concat(s, part)
+	}
+	return s
+}
+
+// concat concatenates a and b, dropping the leading period of b if the text of
+// a already ends with one.
+func concat(a, b string) string {
+	if strings.HasPrefix(b, ".") && strings.HasSuffix(tagPattern.ReplaceAllString(a, ""), ".") {
+		return a + b[1:]
+	}
+	return a + b
+}
+
+func (ctx *context) render(e *element) string {
+	switch e.XMLName.Local {
+	case "text":
+		return ctx.renderText(e)
+	case "number":
+		return ctx.renderNumber(e)
+	case "label":
+		return ctx.renderLabel(e)
+	case "date":
+		return ctx.renderDate(e)
+	case "names":
+		return ctx.renderNames(e)
+	case "group":
+		return ctx.renderGroup(e)
+	case "choose":
+		return ctx.renderChoose(e)
+	default:
+		return ""
+	}
+}
+
+// decorate applies the formatting, quotes and affixes of an element to its
+// rendered content.
+func decorate(e *element, s string) string {
+	if s == "" {
+		return ""
+	}
+	s = textCase(e.attr("text-case"), s)
+	if e.attr("strip-periods") == "true" {
+		s = strings.ReplaceAll(s, ".", "")
+	}
+	if e.attr("quotes") == "true" {
+		s = "“" + s + "”"
+	}
+	return applyAffixes(e, formatting(e, s))
+}
+
+// formatting applies the font and display attributes of an element as HTML.
+func formatting(e *element, s string) string {
+	if s == "" {
+		return ""
+	}
+	switch e.attr("font-style") {
+	case "italic", "oblique":
+		s = "<i>" + s + "</i>"
+	}
+	switch e.attr("font-weight") {
+	case "bold":
+		s = "<b>" + s + "</b>"
+	case "light":
+		s = `<span style="font-weight:lighter">` + s + "</span>"
+	}
+	if e.attr("font-variant") == "small-caps" {
+		s = `<span style="font-variant:small-caps">` + s + "</span>"
+	}
+	if e.attr("text-decoration") == "underline" {
+		s = `<span style="text-decoration:underline">` + s + "</span>"
+	}
+	switch e.attr("vertical-align") {
+	case "sup":
+		s = "<sup>" + s + "</sup>"
+	case "sub":
+		s = "<sub>" + s + "</sub>"
+	}
+	return s
+}
+
+func applyAffixes(e *element, s string) string {
+	if s == "" {
+		return ""
+	}
+	return concat(html.EscapeString(e.attr("prefix"))+s, html.EscapeString(e.attr("suffix")))
+}
+
+// variable returns the value of a standard variable and counts the call for
+// group suppression.
+func (ctx *context) variable(name, form string) string {
+	var value string
+	switch name {
+	case "locator":
+		if ctx.item != nil {
+			_, value = splitLocator(ctx.item.Locator)
+		}
+	case "year-suffix":
+		value = ctx.disambiguation.YearSuffix
+	case "citation-number":
+		number := ctx.number
+		if ctx.item != nil {
+			number = ctx.item.Number
+		}
+		if number > 0 {
+			value = strconv.Itoa(number)
+		}
+	default:
+		if !ctx.substituted[name] {
+			value = ctx.vars.text(name, form)
+		}
+	}
+	ctx.calledVars++
+	if value == "" {
+		ctx.emptyVars++
+	}
+	return value
+}
+
+func (ctx *context) renderText(e *element) string {
+	var s string
+	switch {
+	case e.attr("variable") != "":
+		s = ctx.variable(e.attr("variable"), e.attr("form"))
+		if e.attr("variable") == "page" || e.attr("variable") == "locator" {
+			s = strings.ReplaceAll(s, "-", "–")
+		}
+	case e.attr("macro") != "":
+		macro := ctx.style.macros[e.attr("macro")]
+		if macro == nil {
+			return ""
+		}
+		s = ctx.renderChildren(macro, "")
+	case e.attr("term") != "":
+		s = html.EscapeString(ctx.style.locale.term(e.attr("term"), e.attr("form"), e.attr("plural") == "true"))
+	case e.attr("value") != "":
+		s = html.EscapeString(e.attr("value"))
+	}
+	return decorate(e, s)
+}
+
+func (ctx *context) renderNumber(e *element) string {
+	s := ctx.variable(e.attr("variable"), "")
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return decorate(e, strings.ReplaceAll(s, "-", "–"))
+	}
+	switch e.attr("form") {
+	case "ordinal", "long-ordinal":
+		s += ctx.style.locale.ordinal(n)
+	case "roman":
+		s = strings.ToLower(roman(n))
+	}
+	return decorate(e, s)
+}
+
+func roman(n int) string {
+	values := []int{1000, 900, 500, 400, 100, 90, 50, 40, 10, 9, 5, 4, 1}
+	symbols := []string{"M", "CM", "D", "CD", "C", "XC", "L", "XL", "X", "IX", "V", "IV", "I"}
+	var sb strings.Builder
+	for i, v := range values {
+		for n >= v {
+			sb.WriteString(symbols[i])
+			n -= v
+		}
+	}
+	return sb.String()
+}
+
+// renderLabel renders the term of a label, which is only rendered if its
+// variable is set.
+func (ctx *context) renderLabel(e *element) string {
+	variable := e.attr("variable")
+	var name, value string
+	switch variable {
+	case "locator":
+		if ctx.item == nil || ctx.item.Locator == "" {
+			return ""
+		}
+		name, value = splitLocator(ctx.item.Locator)
+	default:
+		value = ctx.vars.text(variable, "")
+		name = variable
+	}
+	if value == "" {
+		return ""
+	}
+
+	plural := false
+	switch e.attr("plural") {
+	case "always":
+		plural = true
+	case "never":
+	default:
+		plural = strings.ContainsAny(value, "-–,&")
+	}
+	form := e.attr("form")
+	if form == "" {
+		form = "long"
+	}
+	return decorate(e, html.EscapeString(ctx.style.locale.term(name, form, plural)))
+}
+
+func (ctx *context) renderDate(e *element) string {
+	d, ok := ctx.vars.dates[e.attr("variable")]
+	ctx.calledVars++
+	if !ok || d.year == 0 {
+		ctx.emptyVars++
+		return ""
+	}
+
+	parts := e.Children
+	if form := e.attr("form"); form != "" {
+		// Localized date formats of en-US
+		parts = localizedDateParts(form, e.attr("date-parts"))
+	}
+
+	var sb strings.Builder
+	for _, part := range parts {
+		if part.XMLName.Local != "date-part" {
+			continue
+		}
+		var s string
+		switch part.attr("name") {
+		case "year":
+			s = strconv.Itoa(d.year)
+			if part.attr("form") == "short" {
+				s = twoDigits(d.year % 100)
+			}
+			if !ctx.style.hasYearSuffix && !ctx.yearSuffixIssued {
+				s += ctx.disambiguation.YearSuffix
+				ctx.yearSuffixIssued = true
+			}
+		case "month":
+			s = ctx.formatMonth(d.month, part.attr("form"))
+		case "day":
+			if d.day == 0 {
+				continue
+			}
+			s = strconv.Itoa(d.day)
+			switch part.attr("form") {
+			case "numeric-leading-zeros":
+				s = twoDigits(d.day)
+			case "ordinal":
+				s += ctx.style.locale.ordinal(d.day)
+			}
+		}
+		sb.WriteString(decorate(part, s))
+	}
+	return decorate(e, sb.String())
+}
+
+func (ctx *context) formatMonth(month int, form string) string {
+	if month < 1 || month > 12 {
+		return ""
+	}
+	switch form {
+	case "numeric":
+		return strconv.Itoa(month)
+	case "numeric-leading-zeros":
+		return twoDigits(month)
+	case "short":
+		return ctx.style.locale.term("month-"+twoDigits(month), "short", false)
+	default:
+		return ctx.style.locale.term("month-"+twoDigits(month), "long", false)
+	}
+}
+
+// localizedDateParts returns the date parts of the en-US localized date
+// formats, limited to the requested parts.
+func localizedDateParts(form, dateParts string) []*element {
+	part := func(name, partForm, suffix string) *element {
+		return newElement("date-part", attr("name", name), attr("form", partForm), attr("suffix", suffix))
+	}
+	if dateParts == "" {
+		dateParts = "year-month-day"
+	}
+	var parts []*element
+	if form == "numeric" {
+		if strings.Contains(dateParts, "month") {
+			parts = append(parts, part("month", "numeric", "/"))
+		}
+		if dateParts == "year-month-day" {
+			parts = append(parts, part("day", "numeric", "/"))
+		}
+		return append(parts, part("year", "long", ""))
+	}
+	if strings.Contains(dateParts, "month") {
+		parts = append(parts, part("month", "long", " "))
+	}
+	if dateParts == "year-month-day" {
+		parts = append(parts, part("day", "numeric", ", "))
+	}
+	return append(parts, part("year", "long", ""))
+}
+
+// renderGroup renders the children of a group, or nothing if the group calls
+// variables that are all empty.
+func (ctx *context) renderGroup(e *element) string {
+	called, empty := ctx.calledVars, ctx.emptyVars
+	s := ctx.renderChildren(e, e.attr("delimiter"))
+	if called, empty := ctx.calledVars-called, ctx.emptyVars-empty; called > 0 && called == empty {
+		return ""
+	}
+	return decorate(e, s)
+}
+
+func (ctx *context) renderChoose(e *element) string {
+	for _, branch := range e.Children {
+		switch branch.XMLName.Local {
+		case "if", "else-if":
+			if ctx.test(branch) {
+				return ctx.renderChildren(branch, "")
+			}
+		case "else":
+			return ctx.renderChildren(branch, "")
+		}
+	}
+	return ""
+}
+
+// test evaluates the conditions of an if or else-if element.
+func (ctx *context) test(e *element) bool {
+	var results []bool
+	for _, a := range e.Attrs {
+		for _, value := range strings.Fields(a.Value) {
+			switch a.Name.Local {
+			case "type":
+				results = append(results, ctx.vars.typ == value)
+			case "variable":
+				results = append(results, ctx.hasVariable(value))
+			case "is-numeric":
+				_, err := strconv.Atoi(strings.TrimSpace(ctx.vars.text(value, "")))
+				results = append(results, err == nil)
+			case "locator":
+				label := ""
+				if ctx.item != nil && ctx.item.Locator != "" {
+					label, _ = splitLocator(ctx.item.Locator)
+				}
+				results = append(results, label == value || (value == "sub-verbo" && label == "sub verbo"))
+			case "position":
+				// Every cite is treated as the first cite of its item
+				results = append(results, value == "first")
+			case "is-uncertain-date", "disambiguate":
+				results = append(results, false)
+			}
+		}
+	}
+
+	switch e.attr("match") {
+	case "any":
+		for _, r := range results {
+			if r {
+				return true
+			}
+		}
+		return false
+	case "none":
+		for _, r := range results {
+			if r {
+				return false
+			}
+		}
+		return true
+	default:
+		for _, r := range results {
+			if !r {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func (ctx *context) hasVariable(variable string) bool {
+	if variable == "locator" {
+		return ctx.item != nil && ctx.item.Locator != ""
+	}
+	return ctx.vars.has(variable)
+}
+
+// renderNames renders the names of the first variable that has names, or the
+// substitute if none has.
+func (ctx *context) renderNames(e *element) string {
+	var parts []string
+	for _, variable := range strings.Fields(e.attr("variable")) {
+		names := ctx.vars.names[variable]
+		ctx.calledVars++
+		if len(names) == 0 || ctx.substituted[variable] {
+			ctx.emptyVars++
+			continue
+		}
+		s := ctx.formatNames(e, names)
+		if label := e.child("label"); label != nil {
+			plural := len(names) > 1
+			term := ctx.style.locale.term(variable, label.attr("form"), plural)
+			s = concat(s, decorate(label, html.EscapeString(term)))
+		}
+		parts = append(parts, s)
+	}
+
+	delimiter := e.attr("delimiter")
+	if delimiter == "" {
+		delimiter = ctx.inherited["names-delimiter"]
+	}
+	s := join(parts, delimiter)
+	if s == "" {
+		if substitute := e.child("substitute"); substitute != nil {
+			// The substitute is suppressed as a whole, not its own names
+			suppressAuthor := ctx.suppressAuthor
+			ctx.suppressAuthor = false
+			for _, c := range substitute.Children {
+				if s = ctx.render(inheritNames(e, c)); s != "" {
+					ctx.substitute(c)
+					break
+				}
+			}
+			ctx.suppressAuthor = suppressAuthor
+			return ctx.suppress(s)
+		}
+		return ""
+	}
+	return ctx.suppress(decorate(e, s))
+}
+
+// inheritNames returns a names element of a substitute that has no name,
+// et-al or label element with those of the names element it substitutes.
+func inheritNames(names, c *element) *element {
+	if c.XMLName.Local != "names" || len(c.Children) > 0 {
+		return c
+	}
+	inherited := *c
+	for _, child := range names.Children {
+		if child.XMLName.Local != "substitute" {
+			inherited.Children = append(inherited.Children, child)
+		}
+	}
+	return &inherited
+}
+
+// substitute records the variables rendered by a substitute, which are not
+// rendered again for the same item.
+func (ctx *context) substitute(e *element) {
+	if ctx.substituted == nil {
+		ctx.substituted = make(map[string]bool)
+	}
+	for _, variable := range strings.Fields(e.attr("variable")) {
+		ctx.substituted[variable] = true
+	}
+	for _, c := range e.Children {
+		ctx.substitute(c)
+	}
+}
+
+// suppress hides the first rendered names of an author-suppressed or narrative
+// citation, keeping them for the narrative citation.
+func (ctx *context) suppress(s string) string {
+	if !ctx.suppressAuthor || s == "" {
+		return s
+	}
+	ctx.suppressAuthor = false
+	ctx.author = s
+	return ""
+}
+
+// nameOption returns a name option of the name element, falling back to the
+// inherited options.
+func (ctx *context) nameOption(nameElem *element, option string) string {
+	if nameElem != nil {
+		if value := nameElem.attr(option); value != "" {
+			return value
+		}
+	}
+	switch option {
+	case "form":
+		return ctx.inherited["name-form"]
+	case "delimiter":
+		if value, ok := ctx.inherited["name-delimiter"]; ok {
+			return value
+		}
+		return ", "
+	}
+	return ctx.inherited[option]
+}
+
+func (ctx *context) formatNames(e *element, names []name) string {
+	nameElem := e.child("name")
+	form := ctx.nameOption(nameElem, "form")
+	if form == "count" {
+		return strconv.Itoa(len(names))
+	}
+
+	shown := len(names)
+	etAl := false
+	etAlMin, _ := strconv.Atoi(ctx.nameOption(nameElem, "et-al-min"))
+	etAlUseFirst, _ := strconv.Atoi(ctx.nameOption(nameElem, "et-al-use-first"))
+	if etAlMin > 0 && etAlUseFirst > 0 && len(names) >= etAlMin {
+		shown = etAlUseFirst
+		etAl = true
+	}
+	if ctx.item != nil && ctx.disambiguation.Names > shown {
+		shown = min(ctx.disambiguation.Names, len(names))
+		etAl = shown < len(names)
+	}
+
+	formatted := make([]string, shown)
+	inverted := make([]bool, shown)
+	for i, n := range names[:shown] {
+		sortOrder := ctx.nameOption(nameElem, "name-as-sort-order")
+		inverted[i] = sortOrder == "all" || (sortOrder == "first" && i == 0)
+		formatted[i] = ctx.formatName(nameElem, n, form, inverted[i])
+		if i == 0 && form == "short" && ctx.item != nil && ctx.disambiguation.Initials && n.given != "" {
+			formatted[i] = initials(n.given, ". ") + " " + formatted[i]
+		}
+	}
+
+	delimiter := ctx.nameOption(nameElem, "delimiter")
+	and := ""
+	switch ctx.nameOption(nameElem, "and") {
+	case "text":
+		and = html.EscapeString(ctx.style.locale.term("and", "long", false)) + " "
+	case "symbol":
+		and = html.EscapeString(ctx.style.locale.term("and", "symbol", false)) + " "
+	}
+
+	var sb strings.Builder
+	for i, s := range formatted {
+		if i > 0 {
+			last := i == len(formatted)-1 && !etAl
+			switch {
+			case last && and != "":
+				if delimiterPrecedes(ctx.nameOption(nameElem, "delimiter-precedes-last"), len(formatted) > 2, inverted[i-1]) {
+					sb.WriteString(delimiter)
+				} else {
+					sb.WriteString(" ")
+				}
+				sb.WriteString(and)
+			default:
+				sb.WriteString(delimiter)
+			}
+		}
+		sb.WriteString(s)
+	}
+
+	if etAl {
+		term := "et-al"
+		etAlElem := e.child("et-al")
+		if etAlElem != nil && etAlElem.attr("term") != "" {
+			term = etAlElem.attr("term")
+		}
+		if delimiterPrecedes(ctx.nameOption(nameElem, "delimiter-precedes-et-al"), shown > 1, inverted[shown-1]) {
+			sb.WriteString(delimiter)
+		} else {
+			sb.WriteString(" ")
+		}
+		etAlText := html.EscapeString(ctx.style.locale.term(term, "long", false))
+		if etAlElem != nil {
+			etAlText = decorate(etAlElem, etAlText)
+		}
+		sb.WriteString(etAlText)
+	}
+	return sb.String()
+}
+
+// delimiterPrecedes evaluates a delimiter-precedes-last or
+// delimiter-precedes-et-al option. The "contextual" default puts the delimiter
+// if contextual is set: for three or more names before "and", and for two or
+// more names before "et al."
+func delimiterPrecedes(option string, contextual, previousInverted bool) bool {
+	switch option {
+	case "always":
+		return true
+	case "never":
+		return false
+	case "after-inverted-name":
+		return previousInverted
+	default:
+		return contextual
+	}
+}
+
+func (ctx *context) formatName(nameElem *element, n name, form string, inverted bool) string {
+	family := strings.TrimSpace(n.particle + " " + n.family)
+	if form == "short" {
+		return ctx.nameParts(nameElem, "family", family)
+	}
+
+	given := n.given
+	if initializeWith := ctx.nameOption(nameElem, "initialize-with"); initializeWith != "" && ctx.nameOption(nameElem, "initialize") != "false" {
+		given = initials(given, initializeWith)
+	}
+	given = ctx.nameParts(nameElem, "given", given)
+	family = ctx.nameParts(nameElem, "family", family)
+
+	if given == "" {
+		return family
+	}
+	if inverted {
+		separator := ctx.nameOption(nameElem, "sort-separator")
+		if separator == "" {
+			separator = ", "
+		}
+		s := family + separator + given
+		if n.suffix != "" {
+			s += separator + n.suffix
+		}
+		return s
+	}
+	s := given + " " + family
+	if n.suffix != "" {
+		s += ", " + n.suffix
+	}
+	return s
+}
+
+// nameParts applies the formatting of a name-part element to a name part.
+func (ctx *context) nameParts(nameElem *element, part, s string) string {
+	if nameElem == nil || s == "" {
+		return s
+	}
+	for _, c := range nameElem.Children {
+		if c.XMLName.Local == "name-part" && c.attr("name") == part {
+			return decorate(c, s)
+		}
+	}
+	return s
+}
+
+// initials reduces given names to their initials, like "John Peter" to
+// "J. P." for initializeWith ". ". Hyphenated names keep the hyphen.
+func initials(given, initializeWith string) string {
+	var parts []string
+	for _, word := range strings.Fields(given) {
+		var sub []string
+		for _, piece := range strings.Split(word, "-") {
+			r, _ := utf8.DecodeRuneInString(piece)
+			if r == utf8.RuneError {
+				continue
+			}
+			if !unicode.IsUpper(r) {
+				// Lowercase parts are kept as-is, like "de"
+				sub = append(sub, piece)
+				continue
+			}
+			sub = append(sub, string(r)+strings.TrimRight(initializeWith, " "))
+		}
+		parts = append(parts, strings.Join(sub, "-"))
+	}
+	if strings.HasSuffix(initializeWith, " ") {
+		return strings.Join(parts, " ")
+	}
+	return strings.Join(parts, "")
+}
+
+var tagPattern = regexp.MustCompile(`<[^>]*>`)
+
+func stripTags(s string) string {
+	return html.UnescapeString(tagPattern.ReplaceAllString(s, ""))
+}
+
+// textCase applies a CSL text-case to s, see cite.ChangeCase. Text without
+// a text-case keeps its nocase spans, which the text-case of the elements
+// around it honours.
+func textCase(textCase, s string) string {
+	c, ok := textCases[textCase]
+	if !ok {
+		return s
+	}
+	return cite.ChangeCase(s, c)
+}
+
+var textCases = map[string]cite.TextCase{
+	"lowercase":        cite.Lowercase,
+	"uppercase":        cite.Uppercase,
+	"capitalize-first": cite.CapitalizeFirst,
+	"capitalize-all":   cite.CapitalizeAll,
+	"sentence":         cite.SentenceCase,
+	"title":            cite.TitleCase,
+}
+
+func newElement(local string, attrs ...xml.Attr) *element {
+	e := &element{Attrs: attrs}
+	e.XMLName.Local = local
+	return e
+}
+
+func attr(name, value string) xml.Attr {
+	return xml.Attr{Name: xml.Name{Local: name}, Value: value}
+}
@@ -0,0 +1,359 @@
+// Package csl renders citations and references with a Citation Style Language
+// (CSL 1.0.x) style file, like the ones from https://github.com/citation-style-language/styles.
+//
+// A Style implements the Style interface of the goldmark-bibtex extension:
+//
+//	style, err := csl.Load("ieee.csl")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	bibExtender, err := bibtex.New("references.bib", bibtex.WithStyle(style))
+//
+// Only the subset of CSL needed by common styles is supported: rendering
+// elements, macros, conditions, name and date formatting, sorting and the
+// built-in en-US locale, which styles may override with a locale element.
+package csl
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jschaf/bibtex"
+	"github.com/lmondada/goldmark-bibtex/cite"
+)
+
+// element is a generic CSL XML element. Attributes are looked up by name when
+// the style is rendered.
+type element struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Children []*element `xml:",any"`
+	Text     string     `xml:",chardata"`
+}
+
+// attr returns the value of an attribute, or "" if it is not set.
+func (e *element) attr(name string) string {
+	for _, a := range e.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// child returns the first child element with the given name, or nil.
+func (e *element) child(name string) *element {
+	if e == nil {
+		return nil
+	}
+	for _, c := range e.Children {
+		if c.XMLName.Local == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// childrenOrNil returns the children of e, or nil if e is nil.
+func (e *element) childrenOrNil() []*element {
+	if e == nil {
+		return nil
+	}
+	return e.Children
+}
+
+// Style is a parsed CSL style.
+type Style struct {
+	root         *element
+	macros       map[string]*element
+	citation     *element
+	bibliography *element
+	locale       *locale
+	// hasYearSuffix reports whether the style renders the year-suffix
+	// variable, which is otherwise appended to the first rendered year.
+	hasYearSuffix bool
+}
+
+// Load loads a CSL style from a file.
+func Load(path string) (*Style, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// LoadFS loads a CSL style from a file in fsys, like an embed.FS.
+func LoadFS(fsys fs.FS, name string) (*Style, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse parses a CSL style.
+func Parse(r io.Reader) (*Style, error) {
+	var root element
+	if err := xml.NewDecoder(r).Decode(&root); err != nil {
+		return nil, fmt.Errorf("parse CSL style: %w", err)
+	}
+	if root.XMLName.Local != "style" {
+		return nil, fmt.Errorf("parse CSL style: root element is %q, want \"style\"", root.XMLName.Local)
+	}
+
+	s := &Style{
+		root:   &root,
+		macros: make(map[string]*element),
+		locale: newLocale(),
+	}
+	for _, c := range root.Children {
+		switch c.XMLName.Local {
+		case "macro":
+			s.macros[c.attr("name")] = c
+		case "citation":
+			s.citation = c
+		case "bibliography":
+			s.bibliography = c
+		case "locale":
+			if lang := c.attr("lang"); lang == "" || strings.HasPrefix(lang, "en") {
+				s.locale.override(c)
+			}
+		}
+	}
+	if s.citation.child("layout") == nil {
+		return nil, fmt.Errorf("parse CSL style: missing citation layout")
+	}
+	s.hasYearSuffix = root.uses("year-suffix")
+	return s, nil
+}
+
+// uses reports whether e or one of its descendants renders a variable.
+func (e *element) uses(variable string) bool {
+	if e.attr("variable") == variable {
+		return true
+	}
+	for _, c := range e.Children {
+		if c.uses(variable) {
+			return true
+		}
+	}
+	return false
+}
+
+// Title returns the title of the style.
+func (s *Style) Title() string {
+	return strings.TrimSpace(s.root.child("info").child("title").Text)
+}
+
+// IsNumeric reports whether the style uses numeric citations, like "[1]".
+func (s *Style) IsNumeric() bool {
+	for _, c := range s.root.child("info").Children {
+		if c.XMLName.Local == "category" && c.attr("citation-format") == "numeric" {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatCitation formats the inline citation of a group of cited items with
+// the citation layout of the style. A narrative citation puts the author names
+// in front of the citation, like "Smith (2023)" or "Smith et al. [1]".
+//
+// Items are sorted by number if the citation is sorted by citation-number, and
+// runs of three or more consecutive numbers are compressed to ranges, like
+// "[1–3]", if the citation has collapse="citation-number".
+func (s *Style) FormatCitation(items []cite.Item) string {
+	layout := s.citation.child("layout")
+
+	if len(items) == 1 && items[0].Mode == cite.ModeAuthorInText && items[0].Entry != nil {
+		item := items[0]
+		ctx := s.newContext(s.citation, item.Entry, &item)
+		ctx.suppressAuthor = true
+		rest := ctx.renderChildren(layout, layout.attr("delimiter"))
+		rest = applyAffixes(layout, formatting(layout, rest))
+		if ctx.author == "" {
+			// Numeric styles do not render names in citations
+			ctx.author = ctx.render(narrativeNames)
+		}
+		label := rest
+		if ctx.author != "" {
+			label = ctx.author + " " + rest
+		}
+		return formatKeySpan(item.Entry.Key, cite.ChangeCase(label, cite.AsIs))
+	}
+
+	if s.sortsByNumber() {
+		items = append([]cite.Item(nil), items...)
+		sort.SliceStable(items, func(i, j int) bool {
+			return items[i].Number < items[j].Number
+		})
+	}
+	collapse := s.citation.attr("collapse") == "citation-number"
+
+	parts := make([]string, 0, len(items))
+	for i := 0; i < len(items); i++ {
+		item := items[i]
+		if item.Entry == nil {
+			parts = append(parts, affixItem(item, "?"))
+			continue
+		}
+		if j := numberRunEnd(items, i); collapse && items[j].Number-item.Number >= 2 {
+			keys := make([]string, 0, j-i+1)
+			for _, item := range items[i : j+1] {
+				keys = append(keys, item.Entry.Key)
+			}
+			label := fmt.Sprintf("%d–%d", item.Number, items[j].Number)
+			parts = append(parts, formatKeySpan(strings.Join(keys, " "), label))
+			i = j
+			continue
+		}
+		ctx := s.newContext(s.citation, item.Entry, &item)
+		ctx.suppressAuthor = item.Mode == cite.ModeSuppressAuthor
+		rendered := ctx.renderChildren(layout, "")
+		parts = append(parts, affixItem(item, formatKeySpan(item.Entry.Key, rendered)))
+	}
+	return cite.ChangeCase(applyAffixes(layout, formatting(layout, join(parts, layout.attr("delimiter")))), cite.AsIs)
+}
+
+// narrativeNames renders the author names of a narrative citation for styles
+// whose citations do not contain names.
+var narrativeNames = &element{
+	XMLName: xml.Name{Local: "names"},
+	Attrs:   []xml.Attr{attr("variable", "author")},
+	Children: []*element{
+		newElement("name", attr("form", "short"), attr("and", "text"), attr("et-al-min", "3"), attr("et-al-use-first", "1")),
+	},
+}
+
+// sortsByNumber reports whether the cites of a citation are sorted by
+// citation-number.
+func (s *Style) sortsByNumber() bool {
+	for _, key := range s.citation.child("sort").childrenOrNil() {
+		if key.attr("variable") == "citation-number" {
+			return true
+		}
+	}
+	return false
+}
+
+// numberRunEnd returns the index of the last item of the run of consecutively
+// numbered items starting at i. Items with a prefix, locator or suffix are not
+// part of runs.
+func numberRunEnd(items []cite.Item, i int) int {
+	plain := func(item cite.Item) bool {
+		return item.Entry != nil && item.Prefix == "" && item.Locator == "" && item.Suffix == ""
+	}
+	if !plain(items[i]) {
+		return i
+	}
+	j := i
+	for j+1 < len(items) && plain(items[j+1]) && items[j+1].Number == items[j].Number+1 {
+		j++
+	}
+	return j
+}
+
+// FormatReference formats the full reference of an entry with the
+// bibliography layout of the style.
+func (s *Style) FormatReference(ref cite.Reference) string {
+	if s.bibliography == nil {
+		return ""
+	}
+	layout := s.bibliography.child("layout")
+	ctx := s.newContext(s.bibliography, ref.Entry, nil)
+	ctx.number = ref.Number
+	ctx.disambiguation = ref.Disambiguation
+	rendered := ctx.renderChildren(layout, layout.attr("delimiter"))
+	rendered = cite.ChangeCase(applyAffixes(layout, formatting(layout, rendered)), cite.AsIs)
+	return `<span class="citation-full">` + rendered + `</span>`
+}
+
+// Disambiguate tells apart works with the same citation with the
+// disambiguation methods enabled on the citation element of the style:
+// disambiguate-add-givenname, disambiguate-add-names and
+// disambiguate-add-year-suffix.
+func (s *Style) Disambiguate(refs []cite.Reference) {
+	option := func(name string) string {
+		if value := s.citation.attr(name); value != "" {
+			return value
+		}
+		return s.root.attr(name)
+	}
+	etAlMin, _ := strconv.Atoi(option("et-al-min"))
+	etAlUseFirst, _ := strconv.Atoi(option("et-al-use-first"))
+	cite.Disambiguate(refs, cite.DisambiguationRules{
+		Truncation:    cite.Truncation{Min: etAlMin, UseFirst: etAlUseFirst},
+		AddInitials:   s.citation.attr("disambiguate-add-givenname") == "true",
+		AddNames:      s.citation.attr("disambiguate-add-names") == "true",
+		AddYearSuffix: s.citation.attr("disambiguate-add-year-suffix") == "true",
+	})
+}
+
+// Less orders the reference list with the sort keys of the bibliography. Without
+// sort keys, entries keep the order in which they are first cited.
+func (s *Style) Less(a, b *bibtex.Entry) bool {
+	sortElem := s.bibliography.child("sort")
+	if sortElem == nil {
+		return false
+	}
+	for _, key := range sortElem.Children {
+		x, y := s.sortKey(key, a), s.sortKey(key, b)
+		if x == y {
+			continue
+		}
+		// Empty values sort last, regardless of the direction
+		if x == "" || y == "" {
+			return y == ""
+		}
+		if key.attr("sort") == "descending" {
+			return x > y
+		}
+		return x < y
+	}
+	return false
+}
+
+// FormatBibliography formats the reference list, as an ordered list for
+// numeric styles and as an unordered list otherwise.
+func (s *Style) FormatBibliography(refs []cite.Reference) string {
+	tag := "ul"
+	if s.IsNumeric() {
+		tag = "ol"
+	}
+	return cite.FormatList(tag, refs, s.FormatReference)
+}
+
+func (s *Style) sortKey(key *element, entry *bibtex.Entry) string {
+	ctx := s.newContext(s.bibliography, entry, nil)
+	if name := key.attr("macro"); name != "" {
+		macro := s.macros[name]
+		if macro == nil {
+			return ""
+		}
+		return strings.ToLower(stripTags(ctx.renderChildren(macro, "")))
+	}
+	return ctx.vars.sortValue(key.attr("variable"))
+}
+
+// formatKeySpan wraps the label of the cited entries with the given
+// space-separated keys.
+func formatKeySpan(keys, label string) string {
+	return fmt.Sprintf(`<span data-bibtex-key="%s" class="citation-key">%s</span>`, html.EscapeString(keys), label)
+}
+
+// affixItem surrounds a rendered cite with the prefix and suffix of the cited
+// item. The locator is rendered by the style itself.
+func affixItem(item cite.Item, rendered string) string {
+	item.Locator = ""
+	return item.Decorate(rendered)
+}
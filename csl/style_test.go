@@ -0,0 +1,235 @@
+package csl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jschaf/bibtex"
+	bibtexAst "github.com/jschaf/bibtex/ast"
+	"github.com/lmondada/goldmark-bibtex/cite"
+)
+
+// parseStyle parses a style with the citation and bibliography elements and
+// macros of body.
+func parseStyle(t *testing.T, body string) *Style {
+	t.Helper()
+	s, err := Parse(strings.NewReader(`<style xmlns="http://purl.org/net/xbiblio/csl" class="in-text" version="1.0">` +
+		`<info><title>Test</title></info>` + body + `</style>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// newEntry returns an entry with text fields, and authors written
+// "Last, First", or "von Last, First".
+func newEntry(typ, key string, fields map[string]string, authors ...string) *bibtex.Entry {
+	tags := make(map[string]bibtexAst.Expr)
+	for field, value := range fields {
+		tags[field] = &bibtexAst.Text{Value: value}
+	}
+	if len(authors) > 0 {
+		names := make(bibtexAst.Authors, len(authors))
+		for i, author := range authors {
+			last, first, _ := strings.Cut(author, ", ")
+			var prefix string
+			if i := strings.LastIndex(last, " "); i >= 0 {
+				prefix, last = last[:i], last[i+1:]
+			}
+			names[i] = &bibtexAst.Author{
+				First:  &bibtexAst.Text{Value: first},
+				Prefix: &bibtexAst.Text{Value: prefix},
+				Last:   &bibtexAst.Text{Value: last},
+				Suffix: &bibtexAst.Text{},
+			}
+		}
+		tags["author"] = names
+	}
+	return &bibtex.Entry{Type: typ, Key: key, Tags: tags}
+}
+
+// citationText returns the text of the citation of an item, without the
+// span of its key.
+func citationText(s *Style, item cite.Item) string {
+	got := s.FormatCitation([]cite.Item{item})
+	prefix := `<span data-bibtex-key="` + item.Key + `" class="citation-key">`
+	return strings.Replace(strings.Replace(got, prefix, "", 1), "</span>", "", 1)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name, src, want string
+	}{
+		{"not XML", "author-date", "parse CSL style: EOF"},
+		{"truncated", `<style><citation><layout>`, "parse CSL style: XML syntax error"},
+		{"other root", `<locale xml:lang="en"/>`, `parse CSL style: root element is "locale", want "style"`},
+		{"no citation", `<style><bibliography><layout/></bibliography></style>`, "parse CSL style: missing citation layout"},
+		{"no layout", `<style><citation/></style>`, "parse CSL style: missing citation layout"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(strings.NewReader(tt.src))
+		if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("%s: Parse error = %v; want %s", tt.name, err, tt.want)
+		}
+	}
+
+	s := parseStyle(t, `<citation><layout><text value="x"/></layout></citation>`)
+	if got := s.FormatReference(cite.Reference{Entry: newEntry("book", "a", nil)}); got != "" {
+		t.Errorf("FormatReference without a bibliography = %q; want \"\"", got)
+	}
+	if s.Title() != "Test" || s.IsNumeric() {
+		t.Errorf("Title, IsNumeric = %q, %v; want Test, false", s.Title(), s.IsNumeric())
+	}
+}
+
+func TestConditions(t *testing.T) {
+	article := newEntry("article", "a", map[string]string{"title": "T", "volume": "12", "number": "4a"}, "Smith, John")
+	tests := []struct {
+		condition string
+		locator   string
+		want      bool
+	}{
+		{`type="article-journal"`, "", true},
+		{`type="book"`, "", false},
+		{`type="book article-journal" match="any"`, "", true},
+		{`type="book article-journal"`, "", false},
+		{`variable="title"`, "", true},
+		{`variable="publisher"`, "", false},
+		{`variable="author title"`, "", true},
+		{`variable="publisher DOI" match="none"`, "", true},
+		{`variable="title DOI" match="none"`, "", false},
+		{`is-numeric="volume"`, "", true},
+		{`is-numeric="issue"`, "", false},
+		{`variable="locator"`, "", false},
+		{`variable="locator"`, "p. 4", true},
+		{`locator="page"`, "p. 4", true},
+		{`locator="chapter"`, "p. 4", false},
+		{`position="first"`, "", true},
+		{`position="subsequent"`, "", false},
+		{`type="article-journal" variable="title" is-numeric="volume"`, "", true},
+		{`disambiguate="true"`, "", false},
+	}
+	for _, tt := range tests {
+		s := parseStyle(t, `<citation><layout><choose><if `+tt.condition+`><text value="yes"/></if>`+
+			`<else><text value="no"/></else></choose></layout></citation>`)
+		want := "no"
+		if tt.want {
+			want = "yes"
+		}
+		item := cite.Item{Entry: article, Key: "a", Number: 1, Locator: tt.locator}
+		if got := citationText(s, item); got != want {
+			t.Errorf("if %s (locator %q) = %q; want %q", tt.condition, tt.locator, got, want)
+		}
+	}
+
+	// The first matching branch is rendered
+	s := parseStyle(t, `<citation><layout><choose><if type="book"><text value="book"/></if>`+
+		`<else-if type="article-journal"><text value="article"/></else-if>`+
+		`<else-if variable="title"><text value="titled"/></else-if></choose></layout></citation>`)
+	if got := citationText(s, cite.Item{Entry: article, Key: "a"}); got != "article" {
+		t.Errorf("else-if = %q; want article", got)
+	}
+	if got := citationText(s, cite.Item{Entry: newEntry("misc", "m", nil), Key: "m"}); got != "" {
+		t.Errorf("choose without a matching branch = %q; want \"\"", got)
+	}
+}
+
+func TestMacros(t *testing.T) {
+	s := parseStyle(t, `<macro name="author"><names variable="author"><name and="text" initialize-with=". "/>`+
+		`<substitute><names variable="editor"/><text variable="title" font-style="italic"/></substitute></names></macro>`+
+		`<macro name="year"><date variable="issued"><date-part name="year"/></date></macro>`+
+		`<macro name="nested"><group delimiter=", "><text macro="author"/><text macro="year"/></group></macro>`+
+		`<citation><layout><text macro="nested"/><text macro="missing"/></layout></citation>`+
+		`<bibliography><layout suffix="."><group delimiter=". "><text macro="author"/>`+
+		`<group prefix="(" suffix=")"><text variable="publisher"/><text variable="publisher-place"/></group>`+
+		`<text macro="year"/></group></layout></bibliography>`)
+
+	tests := []struct {
+		entry     *bibtex.Entry
+		citation  string
+		reference string
+	}{
+		{
+			newEntry("book", "a", map[string]string{"title": "Graphs", "year": "2020"}, "Smith, John", "Doe, Jane Ann"),
+			"J. Smith and J. A. Doe, 2020",
+			`<span class="citation-full">J. Smith and J. A. Doe. 2020.</span>`,
+		},
+		{
+			// Missing names are substituted by the title, and the group of
+			// missing variables is dropped with its affixes
+			newEntry("book", "b", map[string]string{"title": "Graphs", "year": "2021"}),
+			"<i>Graphs</i>, 2021",
+			`<span class="citation-full"><i>Graphs</i>. 2021.</span>`,
+		},
+		{
+			// Groups whose variables are all missing are dropped
+			newEntry("book", "c", map[string]string{"publisher": "Springer"}),
+			"",
+			`<span class="citation-full">(Springer).</span>`,
+		},
+	}
+	for _, tt := range tests {
+		if got := citationText(s, cite.Item{Entry: tt.entry, Key: tt.entry.Key}); got != tt.citation {
+			t.Errorf("%s: citation = %q; want %q", tt.entry.Key, got, tt.citation)
+		}
+		if got := s.FormatReference(cite.Reference{Entry: tt.entry}); got != tt.reference {
+			t.Errorf("%s: reference = %q; want %q", tt.entry.Key, got, tt.reference)
+		}
+	}
+}
+
+func TestLess(t *testing.T) {
+	smith2020 := newEntry("book", "smith2020", map[string]string{"title": "B", "year": "2020"}, "Smith, John")
+	smith2019 := newEntry("book", "smith2019", map[string]string{"title": "C", "year": "2019"}, "Smith, John")
+	doe := newEntry("book", "doe", map[string]string{"title": "A", "year": "2021"}, "Doe, Jane")
+	untitled := newEntry("book", "untitled", map[string]string{"year": "2018"}, "Adams, Ann")
+
+	tests := []struct {
+		sort string
+		a, b *bibtex.Entry
+		want bool
+	}{
+		{`<key variable="author"/>`, doe, smith2020, true},
+		{`<key variable="author"/>`, smith2020, doe, false},
+		{`<key variable="author"/><key variable="issued"/>`, smith2019, smith2020, true},
+		{`<key variable="author"/><key variable="issued" sort="descending"/>`, smith2020, smith2019, true},
+		{`<key variable="title"/>`, doe, smith2020, true},
+		// Empty values sort last, in both directions
+		{`<key variable="title"/>`, untitled, doe, false},
+		{`<key variable="title" sort="descending"/>`, doe, untitled, true},
+		{`<key macro="author"/>`, doe, smith2020, true},
+		{`<key macro="missing"/>`, doe, smith2020, false},
+		{``, doe, smith2020, false},
+	}
+	for _, tt := range tests {
+		s := parseStyle(t, `<macro name="author"><names variable="author"><name/></names></macro>`+
+			`<citation><layout><text variable="title"/></layout></citation>`+
+			`<bibliography><sort>`+tt.sort+`</sort><layout><text variable="title"/></layout></bibliography>`)
+		if got := s.Less(tt.a, tt.b); got != tt.want {
+			t.Errorf("%s: Less(%s, %s) = %v; want %v", tt.sort, tt.a.Key, tt.b.Key, got, tt.want)
+		}
+	}
+}
+
+func TestNames(t *testing.T) {
+	entry := newEntry("article", "a", map[string]string{"title": "T"},
+		"Smith, John", "Doe, Jane", "Roe, Richard", "van Gogh, Vincent")
+	tests := []struct {
+		name, want string
+	}{
+		{`<name/>`, "John Smith, Jane Doe, Richard Roe, Vincent van Gogh"},
+		{`<name form="short" and="symbol"/>`, "Smith, Doe, Roe, &amp; van Gogh"},
+		{`<name form="short" and="text" delimiter-precedes-last="never"/>`, "Smith, Doe, Roe and van Gogh"},
+		{`<name form="count"/>`, "4"},
+		{`<name name-as-sort-order="first" initialize-with="."/>`, "Smith, J., J. Doe, R. Roe, V. van Gogh"},
+		{`<name form="short" et-al-min="3" et-al-use-first="1"/>`, "Smith et al."},
+		{`<name form="short" et-al-min="3" et-al-use-first="2"/>`, "Smith, Doe, et al."},
+		{`<name><name-part name="family" text-case="uppercase"/></name>`, "John SMITH, Jane DOE, Richard ROE, Vincent VAN GOGH"},
+	}
+	for _, tt := range tests {
+		s := parseStyle(t, `<citation><layout><names variable="author">`+tt.name+`</names></layout></citation>`)
+		if got := citationText(s, cite.Item{Entry: entry, Key: "a"}); got != tt.want {
+			t.Errorf("%s: names = %q; want %q", tt.name, got, tt.want)
+		}
+	}
+}
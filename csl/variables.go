@@ -0,0 +1,229 @@
+package csl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jschaf/bibtex"
+	bibtexAst "github.com/jschaf/bibtex/ast"
+	"github.com/lmondada/goldmark-bibtex/cite"
+)
+
+// name is a personal name in the CSL name model.
+type name struct {
+	given, particle, family, suffix string
+}
+
+// date is a date in the CSL date model. Missing parts are 0.
+type date struct {
+	year, month, day int
+}
+
+// variables are the CSL variables of a bibtex.Entry.
+type variables struct {
+	typ      string
+	standard map[string]string
+	names    map[string][]name
+	dates    map[string]date
+}
+
+// entryTypes maps BibTeX entry types to CSL item types.
+var entryTypes = map[string]string{
+	"article":       "article-journal",
+	"book":          "book",
+	"booklet":       "pamphlet",
+	"conference":    "paper-conference",
+	"inbook":        "chapter",
+	"incollection":  "chapter",
+	"inproceedings": "paper-conference",
+	"manual":        "report",
+	"mastersthesis": "thesis",
+	"misc":          "document",
+	"phdthesis":     "thesis",
+	"proceedings":   "book",
+	"techreport":    "report",
+	"thesis":        "thesis",
+	"unpublished":   "manuscript",
+}
+
+// fieldVariables maps BibTeX fields to CSL standard variables. Fields that
+// depend on the entry type are handled in newVariables.
+var fieldVariables = map[string]string{
+	"title":     "title",
+	"series":    "collection-title",
+	"publisher": "publisher",
+	"address":   "publisher-place",
+	"pages":     "page",
+	"volume":    "volume",
+	"number":    "issue",
+	"doi":       "DOI",
+	"url":       "URL",
+	"isbn":      "ISBN",
+	"issn":      "ISSN",
+	"edition":   "edition",
+	"note":      "note",
+	"abstract":  "abstract",
+	"chapter":   "chapter-number",
+	"keywords":  "keyword",
+}
+
+var monthNumbers = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+// newVariables maps the fields of a BibTeX entry to CSL variables.
+func newVariables(entry *bibtex.Entry) variables {
+	v := variables{
+		typ:      entryTypes[strings.ToLower(entry.Type)],
+		standard: make(map[string]string),
+		names:    make(map[string][]name),
+		dates:    make(map[string]date),
+	}
+	if v.typ == "" {
+		v.typ = "document"
+	}
+
+	for field, value := range entry.Tags {
+		switch value := value.(type) {
+		case bibtexAst.Authors:
+			names := make([]name, 0, len(value))
+			for _, author := range value {
+				names = append(names, name{
+					given:    exprText(author.First),
+					particle: exprText(author.Prefix),
+					family:   exprText(author.Last),
+					suffix:   exprText(author.Suffix),
+				})
+			}
+			v.names[field] = names
+		default:
+			if variable, ok := fieldVariables[field]; ok {
+				v.standard[variable] = exprText(value)
+			}
+		}
+	}
+
+	v.standard["citation-key"] = entry.Key
+	if page, ok := v.standard["page"]; ok {
+		v.standard["page"] = strings.ReplaceAll(page, "--", "–")
+		v.standard["page-first"], _, _ = strings.Cut(v.standard["page"], "–")
+	}
+
+	switch v.typ {
+	case "article-journal":
+		v.setField(entry, "container-title", "journal")
+	case "paper-conference", "chapter":
+		v.setField(entry, "container-title", "booktitle")
+	case "thesis":
+		v.setField(entry, "publisher", "school")
+		switch strings.ToLower(entry.Type) {
+		case "phdthesis":
+			v.standard["genre"] = "PhD thesis"
+		case "mastersthesis":
+			v.standard["genre"] = "Master's thesis"
+		}
+	case "report":
+		v.setField(entry, "publisher", "institution")
+		v.setField(entry, "number", "number")
+		delete(v.standard, "issue")
+	case "document":
+		v.setField(entry, "container-title", "howpublished")
+		if strings.EqualFold(exprText(entry.Tags["archiveprefix"]), "arXiv") {
+			v.typ = "article"
+			v.standard["container-title"] = "arXiv"
+			if eprint := exprText(entry.Tags["eprint"]); eprint != "" {
+				v.standard["number"] = "arXiv:" + eprint
+				if v.standard["URL"] == "" {
+					v.standard["URL"] = "https://arxiv.org/abs/" + eprint
+				}
+			}
+		}
+	}
+	v.setField(entry, "genre", "type")
+
+	if year, err := strconv.Atoi(exprText(entry.Tags["year"])); err == nil {
+		issued := date{year: year}
+		switch month := entry.Tags["month"].(type) {
+		case *bibtexAst.Ident:
+			issued.month = monthNumbers[strings.ToLower(month.Name)]
+		case *bibtexAst.Text:
+			if m, err := strconv.Atoi(month.Value); err == nil {
+				issued.month = m
+			} else if len(month.Value) >= 3 {
+				issued.month = monthNumbers[strings.ToLower(month.Value[:3])]
+			}
+		}
+		if issued.month != 0 {
+			issued.day, _ = strconv.Atoi(exprText(entry.Tags["day"]))
+		}
+		v.dates["issued"] = issued
+	}
+	if accessed, ok := cite.URLDate(entry); ok {
+		v.dates["accessed"] = date{year: accessed.Year, month: accessed.Month, day: accessed.Day}
+	}
+	return v
+}
+
+func (v variables) setField(entry *bibtex.Entry, variable, field string) {
+	if value := exprText(entry.Tags[field]); value != "" {
+		v.standard[variable] = value
+	}
+}
+
+// text returns the value of a standard variable, or of the short form of a
+// variable with a fallback to the long form.
+func (v variables) text(variable, form string) string {
+	if form == "short" {
+		if value := v.standard[variable+"-short"]; value != "" {
+			return value
+		}
+	}
+	return v.standard[variable]
+}
+
+// has reports whether a variable of any kind is set.
+func (v variables) has(variable string) bool {
+	if variable == "type" {
+		return true
+	}
+	if v.standard[variable] != "" {
+		return true
+	}
+	if len(v.names[variable]) > 0 {
+		return true
+	}
+	_, ok := v.dates[variable]
+	return ok
+}
+
+// sortValue returns the value of a variable used for sorting.
+func (v variables) sortValue(variable string) string {
+	if names, ok := v.names[variable]; ok {
+		parts := make([]string, len(names))
+		for i, n := range names {
+			parts[i] = strings.TrimSpace(n.family + " " + n.given)
+		}
+		return strings.ToLower(strings.Join(parts, ", "))
+	}
+	if d, ok := v.dates[variable]; ok {
+		return fmt.Sprintf("%04d%02d%02d", d.year, d.month, d.day)
+	}
+	return strings.ToLower(v.standard[variable])
+}
+
+// exprText returns the text of a resolved field value, or "" for other
+// expressions.
+func exprText(expr bibtexAst.Expr) string {
+	switch expr := expr.(type) {
+	case *bibtexAst.Text:
+		return expr.Value
+	case *bibtexAst.Ident:
+		return expr.Name
+	case *bibtexAst.Number:
+		return expr.Value
+	default:
+		return ""
+	}
+}
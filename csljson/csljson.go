@@ -0,0 +1,355 @@
+// Package csljson loads bibliographies in the CSL-JSON format exported by
+// Zotero and Pandoc, converting each item to the BibTeX entry the styles
+// format:
+//
+//	entries, err := csljson.Load("references.json")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	bibExtender := bibtex.NewFromEntries(entries)
+//
+// Item types are mapped to the closest BibTeX entry type, names to authors
+// and editors, the issued date to the year and month, and the standard
+// variables to the matching fields, like container-title to the journal of
+// an article or the booktitle of a chapter.
+package csljson
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jschaf/bibtex"
+	bibtexAst "github.com/jschaf/bibtex/ast"
+	"github.com/lmondada/goldmark-bibtex/cite"
+)
+
+// Error is an error in a CSL-JSON document.
+type Error struct {
+	// Line is the line of the error, starting at 1.
+	Line int
+	// Key is the id of the item, or "" for errors outside items.
+	Key string
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Key == "" {
+		return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+	}
+	return fmt.Sprintf("line %d: item %q: %v", e.Line, e.Key, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Load loads the entries of a CSL-JSON file.
+func Load(path string) ([]bibtex.Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(bytes.NewReader(data))
+}
+
+// LoadFS loads the entries of a CSL-JSON file in fsys, like an embed.FS.
+func LoadFS(fsys fs.FS, name string) ([]bibtex.Entry, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(bytes.NewReader(data))
+}
+
+// Parse parses a CSL-JSON array of items. Errors are returned as *Error.
+func Parse(r io.Reader) ([]bibtex.Entry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	lineAt := func(offset int64) int {
+		return bytes.Count(data[:offset], []byte("\n")) + 1
+	}
+	syntaxError := func(err error) error {
+		var syntax *json.SyntaxError
+		if errors.As(err, &syntax) {
+			return &Error{Line: lineAt(syntax.Offset), Err: err}
+		}
+		return &Error{Line: lineAt(int64(len(data))), Err: err}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil {
+		return nil, syntaxError(err)
+	} else if tok != json.Delim('[') {
+		return nil, &Error{Line: lineAt(dec.InputOffset()), Err: errors.New("expected an array of items")}
+	}
+	var entries []bibtex.Entry
+	for dec.More() {
+		// The offset of the item is after the comma that separates it from
+		// the previous one
+		offset := dec.InputOffset()
+		offset += int64(len(data[offset:]) - len(bytes.TrimLeft(data[offset:], ", \t\r\n")))
+		var item map[string]interface{}
+		if err := dec.Decode(&item); err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				return nil, &Error{Line: lineAt(offset), Err: errors.New("expected an item object")}
+			}
+			return nil, syntaxError(err)
+		}
+		entry, err := convert(item)
+		if err != nil {
+			return nil, &Error{Line: lineAt(offset), Key: entry.Key, Err: err}
+		}
+		entries = append(entries, entry)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, syntaxError(err)
+	}
+	return entries, nil
+}
+
+// entryTypes maps CSL item types to BibTeX entry types. Other types are
+// misc entries.
+var entryTypes = map[string]string{
+	"article-journal":   "article",
+	"article-magazine":  "article",
+	"article-newspaper": "article",
+	"book":              "book",
+	"chapter":           "incollection",
+	"manuscript":        "unpublished",
+	"pamphlet":          "booklet",
+	"paper-conference":  "inproceedings",
+	"report":            "techreport",
+	"thesis":            "phdthesis",
+}
+
+// variableFields maps CSL standard variables to BibTeX fields. Variables
+// that depend on the item type are handled in convert.
+var variableFields = map[string]string{
+	"title":            "title",
+	"collection-title": "series",
+	"publisher-place":  "address",
+	"volume":           "volume",
+	"issue":            "number",
+	"DOI":              "doi",
+	"URL":              "url",
+	"ISBN":             "isbn",
+	"ISSN":             "issn",
+	"edition":          "edition",
+	"note":             "note",
+	"abstract":         "abstract",
+	"chapter-number":   "chapter",
+	"keyword":          "keywords",
+	"genre":            "type",
+}
+
+// convert converts a CSL-JSON item to a BibTeX entry. The entry has the key
+// of the item even if there is an error.
+func convert(item map[string]interface{}) (bibtex.Entry, error) {
+	entry := bibtex.Entry{
+		Key:  text(item["id"]),
+		Tags: make(map[string]bibtexAst.Expr),
+	}
+	if entry.Key == "" {
+		return entry, errors.New("missing id")
+	}
+	typ := text(item["type"])
+	entry.Type = entryTypes[typ]
+	if entry.Type == "" {
+		entry.Type = "misc"
+	}
+
+	setText := func(field, value string) {
+		if value != "" {
+			entry.Tags[field] = &bibtexAst.Text{Value: richText(value)}
+		}
+	}
+	for variable, field := range variableFields {
+		setText(field, text(item[variable]))
+	}
+	setText("pages", cite.PageRange(text(item["page"])))
+
+	container := text(item["container-title"])
+	publisher := text(item["publisher"])
+	switch entry.Type {
+	case "article":
+		setText("journal", container)
+		setText("publisher", publisher)
+	case "incollection", "inproceedings":
+		setText("booktitle", container)
+		setText("publisher", publisher)
+	case "phdthesis":
+		if strings.Contains(strings.ToLower(text(item["genre"])), "master") {
+			entry.Type = "mastersthesis"
+		}
+		delete(entry.Tags, "type")
+		setText("school", publisher)
+	case "techreport":
+		setText("institution", publisher)
+		setText("number", text(item["number"]))
+	case "misc":
+		setText("howpublished", container)
+		setText("publisher", publisher)
+	default:
+		setText("publisher", publisher)
+	}
+
+	for _, field := range []string{"author", "editor"} {
+		value, ok := item[field]
+		if !ok {
+			continue
+		}
+		names, err := convertNames(value)
+		if err != nil {
+			return entry, fmt.Errorf("%s: %w", field, err)
+		}
+		entry.Tags[field] = names
+	}
+
+	if issued, ok := item["issued"]; ok {
+		year, month, err := convertDate(issued)
+		if err != nil {
+			return entry, fmt.Errorf("issued: %w", err)
+		}
+		setText("year", year)
+		if month >= 1 && month <= 12 {
+			entry.Tags["month"] = &bibtexAst.Ident{Name: cite.MonthKeys[month-1]}
+		}
+	}
+	return entry, nil
+}
+
+// convertNames converts a CSL name list to BibTeX authors.
+func convertNames(value interface{}) (bibtexAst.Authors, error) {
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, errors.New("expected a list of names")
+	}
+	authors := make(bibtexAst.Authors, 0, len(list))
+	for _, v := range list {
+		name, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("expected a name object")
+		}
+		family := text(name["family"])
+		if family == "" {
+			family = text(name["literal"])
+		}
+		prefix := strings.TrimSpace(text(name["dropping-particle"]) + " " + text(name["non-dropping-particle"]))
+		authors = append(authors, &bibtexAst.Author{
+			First:  &bibtexAst.Text{Value: html.EscapeString(text(name["given"]))},
+			Prefix: &bibtexAst.Text{Value: html.EscapeString(prefix)},
+			Last:   &bibtexAst.Text{Value: html.EscapeString(family)},
+			Suffix: &bibtexAst.Text{Value: html.EscapeString(text(name["suffix"]))},
+		})
+	}
+	return authors, nil
+}
+
+// convertDate returns the year and month of a CSL date, from its first date
+// parts or the leading year of its raw or literal form. The month is 0 if
+// the date has none.
+func convertDate(value interface{}) (year string, month int, err error) {
+	date, ok := value.(map[string]interface{})
+	if !ok {
+		return "", 0, errors.New("expected a date object")
+	}
+	if parts, ok := date["date-parts"].([]interface{}); ok && len(parts) > 0 {
+		first, ok := parts[0].([]interface{})
+		if !ok {
+			return "", 0, errors.New("expected a list of date parts")
+		}
+		if len(first) > 0 {
+			year = text(first[0])
+		}
+		if len(first) > 1 {
+			month, _ = strconv.Atoi(text(first[1]))
+		}
+		return year, month, nil
+	}
+	for _, form := range []string{"raw", "literal"} {
+		s := text(date[form])
+		if len(s) >= 4 {
+			if _, err := strconv.Atoi(s[:4]); err == nil {
+				return s[:4], 0, nil
+			}
+		}
+	}
+	return "", 0, nil
+}
+
+// richTags are the HTML elements of the rich text of CSL-JSON values, with
+// their closing tags. Spans of text that keeps its case are those of
+// cite.ChangeCase.
+var richTags = map[string]string{
+	"<i>":                   "</i>",
+	"<b>":                   "</b>",
+	"<sup>":                 "</sup>",
+	"<sub>":                 "</sub>",
+	`<span class="nocase">`: cite.NoCaseClose,
+}
+
+// richText escapes a value for HTML, keeping the elements of richTags, like
+// <i>...</i>. Other tags are escaped, like text, and elements left open are
+// closed.
+func richText(s string) string {
+	var sb strings.Builder
+	var open []string
+	for s != "" {
+		i := strings.IndexByte(s, '<')
+		if i < 0 {
+			sb.WriteString(html.EscapeString(s))
+			break
+		}
+		sb.WriteString(html.EscapeString(s[:i]))
+		s = s[i:]
+		// A "<" without a ">" before the next "<" is text, like in "x < y"
+		tag := s[:1]
+		if j := strings.IndexAny(s[1:], "<>"); j >= 0 && s[j+1] == '>' {
+			tag = s[:j+2]
+		}
+		s = s[len(tag):]
+		switch closing, ok := richTags[tag]; {
+		case ok && tag == `<span class="nocase">`:
+			sb.WriteString(cite.NoCaseOpen)
+			open = append(open, closing)
+		case ok:
+			sb.WriteString(tag)
+			open = append(open, closing)
+		case len(open) > 0 && tag == open[len(open)-1]:
+			sb.WriteString(tag)
+			open = open[:len(open)-1]
+		default:
+			sb.WriteString(html.EscapeString(tag))
+		}
+	}
+	for i := len(open) - 1; i >= 0; i-- {
+		sb.WriteString(open[i])
+	}
+	return sb.String()
+}
+
+// text returns a string or number value as a string, or "" for other
+// values.
+func text(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case json.Number:
+		return v.String()
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,138 @@
+package csljson
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	bibtexAst "github.com/jschaf/bibtex/ast"
+	"github.com/lmondada/goldmark-bibtex/cite"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name, src string
+		typ       string
+		fields    map[string]string
+	}{
+		{
+			"article",
+			`{"id": "a", "type": "article-journal", "title": "Graph pattern matching", "container-title": "Cluster Computing",
+				"volume": 23, "issue": "3", "page": "1529-1553", "ISSN": "1573-7543", "issued": {"date-parts": [[2020, 3, 15]]}}`,
+			"article",
+			map[string]string{"title": "Graph pattern matching", "journal": "Cluster Computing", "volume": "23",
+				"number": "3", "pages": "1529–1553", "issn": "1573-7543", "year": "2020", "month": "mar"},
+		},
+		{
+			"chapter",
+			`{"id": "a", "type": "chapter", "title": "Chapter", "container-title": "The Book", "collection-title": "LNCS",
+				"issued": {"date-parts": [["2019"]]}}`,
+			"incollection",
+			map[string]string{"booktitle": "The Book", "series": "LNCS", "year": "2019", "month": ""},
+		},
+		{
+			"master's thesis",
+			`{"id": "a", "type": "thesis", "genre": "Master's thesis", "publisher": "MIT", "issued": {"raw": "2018-05"}}`,
+			"mastersthesis",
+			map[string]string{"school": "MIT", "publisher": "", "type": "", "year": "2018"},
+		},
+		{
+			"thesis",
+			`{"id": "a", "type": "thesis", "genre": "PhD dissertation", "publisher": "MIT", "issued": {"literal": "Spring 2018"}}`,
+			"phdthesis",
+			map[string]string{"school": "MIT", "type": "", "year": ""},
+		},
+		{
+			"report",
+			`{"id": "a", "type": "report", "genre": "Technical note", "publisher": "CERN", "number": 42, "issued": {"date-parts": [[2022, 13]]}}`,
+			"techreport",
+			map[string]string{"institution": "CERN", "type": "Technical note", "number": "42", "year": "2022", "month": ""},
+		},
+		{
+			// Values are escaped for HTML, except the rich text elements
+			"rich text",
+			`{"id": "a", "type": "book", "title": "x < y & <b>bold</b> <script>alert(1)</script> <span class=\"nocase\">RETE</span> <i>open",
+				"publisher": "Smith & Sons"}`,
+			"book",
+			map[string]string{
+				"title": "x &lt; y &amp; <b>bold</b> &lt;script&gt;alert(1)&lt;/script&gt; " +
+					cite.NoCaseOpen + "RETE" + cite.NoCaseClose + " <i>open</i>",
+				"publisher": "Smith &amp; Sons",
+			},
+		},
+		{
+			"unknown type",
+			`{"id": "a", "type": "webpage", "title": " Web page ", "container-title": "Example", "URL": "https://example.com", "issued": {}}`,
+			"misc",
+			map[string]string{"title": "Web page", "howpublished": "Example", "url": "https://example.com", "year": ""},
+		},
+	}
+	for _, tt := range tests {
+		entries, err := Parse(strings.NewReader("[" + tt.src + "]"))
+		if err != nil {
+			t.Errorf("%s: Parse error = %v", tt.name, err)
+			continue
+		}
+		if len(entries) != 1 {
+			t.Errorf("%s: got %d entries; want 1", tt.name, len(entries))
+			continue
+		}
+		entry := entries[0]
+		if entry.Type != tt.typ || entry.Key != "a" {
+			t.Errorf("%s: entry = @%s{%s}; want @%s{a}", tt.name, entry.Type, entry.Key, tt.typ)
+		}
+		for field, want := range tt.fields {
+			// Titles keep their nocase spans in the field values
+			got := cite.Field(&entry, field)
+			if text, ok := entry.Tags[field].(*bibtexAst.Text); ok {
+				got = text.Value
+			}
+			if got != want {
+				t.Errorf("%s: %s = %q; want %q", tt.name, field, got, want)
+			}
+		}
+	}
+}
+
+func TestParseNames(t *testing.T) {
+	entries, err := Parse(strings.NewReader(`[{"id": "a", "author": [
+		{"family": "Gogh", "given": "Vincent", "non-dropping-particle": "van"},
+		{"family": "Smith", "given": "John", "suffix": "Jr."},
+		{"literal": "The Graph & <b>Tree</b> Consortium"}],
+		"editor": []}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	authors := cite.Names(&entries[0], "author")
+	if len(authors) != 3 || cite.Text(authors[0].Prefix) != "van" || cite.Text(authors[0].Last) != "Gogh" ||
+		cite.Text(authors[1].Suffix) != "Jr." || cite.Text(authors[2].Last) != "The Graph &amp; &lt;b&gt;Tree&lt;/b&gt; Consortium" {
+		t.Errorf("authors = %v", authors)
+	}
+	if editors := cite.Names(&entries[0], "editor"); len(editors) != 0 {
+		t.Errorf("editors = %v; want none", editors)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name, src, want string
+	}{
+		{"empty", "", "line 1: EOF"},
+		{"not an array", `{"id": "a"}`, "line 1: expected an array of items"},
+		{"not an item", "[\n{\"id\": \"a\"},\n\"b\"\n]", "line 3: expected an item object"},
+		{"syntax", "[\n{\"id\": \"a\",\n\"title\" \"T\"}\n]", "line 3: invalid character"},
+		{"unterminated", "[\n{\"id\": \"a\"}\n", "line 3: unexpected end of JSON input"},
+		{"missing id", "[\n{\"id\": \"a\"},\n{\"title\": \"T\"}\n]", "line 3: missing id"},
+		{"names", `[{"id": "a", "author": "Smith, John"}]`, `line 1: item "a": author: expected a list of names`},
+		{"name", `[{"id": "a", "editor": ["Smith, John"]}]`, `line 1: item "a": editor: expected a name object`},
+		{"date", `[{"id": "a", "issued": "2020"}]`, `line 1: item "a": issued: expected a date object`},
+		{"date parts", `[{"id": "a", "issued": {"date-parts": [2020]}}]`, `line 1: item "a": issued: expected a list of date parts`},
+	}
+	for _, tt := range tests {
+		_, err := Parse(strings.NewReader(tt.src))
+		var jsonErr *Error
+		if !errors.As(err, &jsonErr) || !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("%s: Parse error = %v; want %s", tt.name, err, tt.want)
+		}
+	}
+}
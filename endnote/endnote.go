@@ -0,0 +1,297 @@
+// Package endnote loads bibliographies in the EndNote XML format, converting
+// each record to the BibTeX entry the styles format:
+//
+//	entries, err := endnote.Load("references.xml")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	bibExtender := bibtex.NewFromEntries(entries)
+//
+// Reference types are mapped to the closest BibTeX entry type, like Journal
+// Article to article, and elements to the matching fields, like the
+// secondary title to the journal of an article or the booktitle of a book
+// section. Records without a label get a key made of the last name of the
+// first author and the year, like "Smith2020".
+package endnote
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jschaf/bibtex"
+	bibtexAst "github.com/jschaf/bibtex/ast"
+	"github.com/lmondada/goldmark-bibtex/cite"
+)
+
+// Error is an error in an EndNote XML document.
+type Error struct {
+	// Line is the line of the error, starting at 1.
+	Line int
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Load loads the entries of an EndNote XML file.
+func Load(path string) ([]bibtex.Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(bytes.NewReader(data))
+}
+
+// LoadFS loads the entries of an EndNote XML file in fsys, like an embed.FS.
+func LoadFS(fsys fs.FS, name string) ([]bibtex.Entry, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(bytes.NewReader(data))
+}
+
+// text is the text of an element, which EndNote often wraps in style
+// elements.
+type text string
+
+func (t *text) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var sb strings.Builder
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch tok := tok.(type) {
+		case xml.CharData:
+			sb.Write(tok)
+		case xml.EndElement:
+			if tok.Name == start.Name {
+				*t = text(strings.TrimSpace(sb.String()))
+				return nil
+			}
+		}
+	}
+}
+
+// record is an EndNote XML record, with the elements that have a BibTeX
+// equivalent.
+type record struct {
+	RefType struct {
+		Name string `xml:"name,attr"`
+		Code int    `xml:",chardata"`
+	} `xml:"ref-type"`
+	Label        text `xml:"label"`
+	Contributors struct {
+		Authors          []text `xml:"authors>author"`
+		SecondaryAuthors []text `xml:"secondary-authors>author"`
+	} `xml:"contributors"`
+	Titles struct {
+		Title          text `xml:"title"`
+		SecondaryTitle text `xml:"secondary-title"`
+		TertiaryTitle  text `xml:"tertiary-title"`
+	} `xml:"titles"`
+	Periodical  text   `xml:"periodical>full-title"`
+	Pages       text   `xml:"pages"`
+	Volume      text   `xml:"volume"`
+	Number      text   `xml:"number"`
+	Edition     text   `xml:"edition"`
+	Year        text   `xml:"dates>year"`
+	Date        text   `xml:"dates>pub-dates>date"`
+	Publisher   text   `xml:"publisher"`
+	PubLocation text   `xml:"pub-location"`
+	ISBN        text   `xml:"isbn"`
+	DOI         text   `xml:"electronic-resource-num"`
+	URL         text   `xml:"urls>related-urls>url"`
+	Abstract    text   `xml:"abstract"`
+	Notes       text   `xml:"notes"`
+	WorkType    text   `xml:"work-type"`
+	Keywords    []text `xml:"keywords>keyword"`
+	Language    text   `xml:"language"`
+}
+
+// Parse parses the records of an EndNote XML document. Errors are returned
+// as *Error.
+func Parse(r io.Reader) ([]bibtex.Entry, error) {
+	dec := xml.NewDecoder(r)
+	keys := make(cite.KeyGenerator)
+	var entries []bibtex.Entry
+	for {
+		line, _ := dec.InputPos()
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, &Error{Line: line, Err: err}
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "record" {
+			continue
+		}
+		var rec record
+		if err := dec.DecodeElement(&rec, &start); err != nil {
+			line, _ := dec.InputPos()
+			return nil, &Error{Line: line, Err: err}
+		}
+		entry := convert(&rec)
+		if entry.Key == "" {
+			entry.Key = keys.Key(&entry)
+		} else {
+			keys[entry.Key] = true
+		}
+		entries = append(entries, entry)
+	}
+}
+
+// entryTypes maps EndNote reference type names to BibTeX entry types. Other
+// types are misc entries.
+var entryTypes = map[string]string{
+	"Journal Article":        "article",
+	"Magazine Article":       "article",
+	"Newspaper Article":      "article",
+	"Book":                   "book",
+	"Edited Book":            "book",
+	"Book Section":           "incollection",
+	"Conference Paper":       "inproceedings",
+	"Conference Proceedings": "inproceedings",
+	"Thesis":                 "phdthesis",
+	"Report":                 "techreport",
+	"Unpublished Work":       "unpublished",
+	"Pamphlet":               "booklet",
+}
+
+// typeCodes maps the EndNote reference type codes to their names, for
+// records without a name attribute.
+var typeCodes = map[int]string{
+	5:  "Book Section",
+	6:  "Book",
+	10: "Conference Proceedings",
+	17: "Journal Article",
+	19: "Magazine Article",
+	23: "Newspaper Article",
+	27: "Report",
+	28: "Edited Book",
+	32: "Thesis",
+	34: "Unpublished Work",
+	47: "Conference Paper",
+}
+
+// convert converts a record to a BibTeX entry.
+func convert(rec *record) bibtex.Entry {
+	typeName := rec.RefType.Name
+	if typeName == "" {
+		typeName = typeCodes[rec.RefType.Code]
+	}
+	entry := bibtex.Entry{
+		Type: entryTypes[typeName],
+		Key:  string(rec.Label),
+		Tags: make(map[string]bibtexAst.Expr),
+	}
+	if entry.Type == "" {
+		entry.Type = "misc"
+	}
+	// Values are plain text, escaped for HTML like the decoded fields of
+	// BibTeX files
+	setText := func(field string, value text) {
+		if value != "" {
+			entry.Tags[field] = &bibtexAst.Text{Value: html.EscapeString(string(value))}
+		}
+	}
+
+	if names := parseNames(rec.Contributors.Authors); len(names) > 0 {
+		entry.Tags["author"] = names
+	}
+	if names := parseNames(rec.Contributors.SecondaryAuthors); len(names) > 0 {
+		entry.Tags["editor"] = names
+	}
+	setText("title", rec.Titles.Title)
+	setText("series", rec.Titles.TertiaryTitle)
+	setText("pages", text(cite.PageRange(string(rec.Pages))))
+	setText("volume", rec.Volume)
+	setText("number", rec.Number)
+	setText("edition", rec.Edition)
+	setText("address", rec.PubLocation)
+	setText("doi", rec.DOI)
+	setText("url", rec.URL)
+	setText("abstract", rec.Abstract)
+	setText("note", rec.Notes)
+	setText("language", rec.Language)
+	var keywords []string
+	for _, k := range rec.Keywords {
+		keywords = append(keywords, string(k))
+	}
+	setText("keywords", text(strings.Join(keywords, ", ")))
+
+	container := rec.Titles.SecondaryTitle
+	if container == "" {
+		container = rec.Periodical
+	}
+	switch entry.Type {
+	case "article":
+		setText("journal", container)
+		setText("publisher", rec.Publisher)
+		setText("issn", rec.ISBN)
+	case "incollection", "inproceedings":
+		setText("booktitle", container)
+		setText("publisher", rec.Publisher)
+		setText("isbn", rec.ISBN)
+	case "phdthesis":
+		if strings.Contains(strings.ToLower(string(rec.WorkType)), "master") {
+			entry.Type = "mastersthesis"
+		}
+		setText("school", rec.Publisher)
+	case "techreport":
+		setText("institution", rec.Publisher)
+		setText("type", rec.WorkType)
+	case "misc":
+		setText("howpublished", container)
+		setText("publisher", rec.Publisher)
+	default:
+		setText("publisher", rec.Publisher)
+		setText("isbn", rec.ISBN)
+	}
+
+	if len(rec.Year) >= 4 {
+		setText("year", rec.Year[:4])
+	}
+	// Publication dates are free text, like "March 15" or "03/15"
+	date := strings.ToLower(string(rec.Date))
+	var month int
+	for i, name := range cite.MonthKeys {
+		if strings.HasPrefix(date, name) {
+			month = i + 1
+		}
+	}
+	if m, err := strconv.Atoi(strings.SplitN(date, "/", 2)[0]); err == nil {
+		month = m
+	}
+	if month >= 1 && month <= 12 {
+		entry.Tags["month"] = &bibtexAst.Ident{Name: cite.MonthKeys[month-1]}
+	}
+	return entry
+}
+
+// parseNames parses names written "Last, First".
+func parseNames(names []text) bibtexAst.Authors {
+	var authors bibtexAst.Authors
+	for _, name := range names {
+		if name != "" {
+			authors = append(authors, cite.ParseName(html.EscapeString(string(name))))
+		}
+	}
+	return authors
+}
@@ -0,0 +1,153 @@
+package endnote
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/lmondada/goldmark-bibtex/cite"
+)
+
+// xmlRecords wraps records in an EndNote XML document.
+func xmlRecords(records ...string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?><xml><records><record>` +
+		strings.Join(records, "</record><record>") + `</record></records></xml>`
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name, src string
+		typ, key  string
+		fields    map[string]string
+	}{
+		{
+			"article",
+			`<ref-type name="Journal Article">17</ref-type><label>Mahfoud2020</label>` +
+				`<contributors><authors><author><style face="normal">Mahfoud, Houari</style></author></authors></contributors>` +
+				`<titles><title><style face="normal">Graph</style><style face="italic"> pattern matching</style></title></titles>` +
+				`<periodical><full-title>Cluster Computing</full-title></periodical>` +
+				`<pages>1529-1553</pages><volume>23</volume><number>3</number>` +
+				`<dates><year>2020</year><pub-dates><date>March 15</date></pub-dates></dates><isbn>1573-7543</isbn>`,
+			"article", "Mahfoud2020",
+			map[string]string{"title": "Graph pattern matching", "journal": "Cluster Computing", "pages": "1529–1553",
+				"volume": "23", "number": "3", "year": "2020", "month": "mar", "issn": "1573-7543", "isbn": ""},
+		},
+		{
+			// Types without a name attribute come from their code
+			"book section",
+			`<ref-type>5</ref-type><contributors><authors><author>Doe, Jane</author></authors>` +
+				`<secondary-authors><author>Roe, Richard</author></secondary-authors></contributors>` +
+				`<titles><title>Chapter</title><secondary-title>The Book</secondary-title><tertiary-title>LNCS</tertiary-title></titles>` +
+				`<dates><year>2019 (online)</year><pub-dates><date>11/2019</date></pub-dates></dates>` +
+				`<keywords><keyword>graphs</keyword><keyword>rewriting</keyword></keywords>`,
+			"incollection", "Doe2019",
+			map[string]string{"title": "Chapter", "booktitle": "The Book", "series": "LNCS", "year": "2019",
+				"month": "nov", "keywords": "graphs, rewriting"},
+		},
+		{
+			"master's thesis",
+			`<ref-type name="Thesis">32</ref-type><contributors><authors><author>Smith, John</author></authors></contributors>` +
+				`<publisher>MIT</publisher><work-type>Master's thesis</work-type><dates><year>2018</year><pub-dates><date>Spring</date></pub-dates></dates>`,
+			"mastersthesis", "Smith2018",
+			map[string]string{"school": "MIT", "publisher": "", "type": "", "month": ""},
+		},
+		{
+			"thesis",
+			`<ref-type name="Thesis">32</ref-type><contributors><authors><author>Smith, John</author></authors></contributors>` +
+				`<publisher>MIT</publisher><work-type>Ph.D. dissertation</work-type><dates><year>2018</year></dates>`,
+			"phdthesis", "Smith2018",
+			map[string]string{"school": "MIT", "type": ""},
+		},
+		{
+			"report",
+			`<ref-type name="Report">27</ref-type><contributors><authors><author>Doe, Jane</author></authors></contributors>` +
+				`<publisher>CERN</publisher><work-type>Technical note</work-type><dates><year>20</year></dates>`,
+			"techreport", "Doe",
+			map[string]string{"institution": "CERN", "type": "Technical note", "year": ""},
+		},
+		{
+			// Values are escaped for HTML, after the XML decoder unescapes them
+			"escaped",
+			`<ref-type name="Book">6</ref-type><contributors><authors><author>O'Brien &amp; &lt;b&gt;Sons&lt;/b&gt;, Pat</author></authors></contributors>` +
+				`<titles><title>Bounds for n &lt; m &amp; <style face="normal">&lt;script&gt;alert(1)&lt;/script&gt;</style></title></titles>` +
+				`<dates><year>2020</year></dates>`,
+			"book", "OBrienbSonsb2020",
+			map[string]string{"title": "Bounds for n &lt; m &amp; &lt;script&gt;alert(1)&lt;/script&gt;"},
+		},
+		{
+			"unknown type",
+			`<ref-type name="Web Page">12</ref-type><titles><title>Web page</title><secondary-title>Example</secondary-title></titles>` +
+				`<urls><related-urls><url>https://example.com</url></related-urls></urls><dates><year>2021</year></dates>`,
+			"misc", "Webpage2021",
+			map[string]string{"howpublished": "Example", "url": "https://example.com"},
+		},
+	}
+	for _, tt := range tests {
+		entries, err := Parse(strings.NewReader(xmlRecords(tt.src)))
+		if err != nil {
+			t.Errorf("%s: Parse error = %v", tt.name, err)
+			continue
+		}
+		if len(entries) != 1 {
+			t.Errorf("%s: got %d entries; want 1", tt.name, len(entries))
+			continue
+		}
+		entry := entries[0]
+		if entry.Type != tt.typ || entry.Key != tt.key {
+			t.Errorf("%s: entry = @%s{%s}; want @%s{%s}", tt.name, entry.Type, entry.Key, tt.typ, tt.key)
+		}
+		for field, want := range tt.fields {
+			if got := cite.Field(&entry, field); got != want {
+				t.Errorf("%s: %s = %q; want %q", tt.name, field, got, want)
+			}
+		}
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	author := `<ref-type name="Journal Article">17</ref-type><contributors><authors><author>Doe, Jane</author>` +
+		`<author></author><author>van Gogh, Vincent</author></authors></contributors><dates><year>2020</year></dates>`
+	entries, err := Parse(strings.NewReader(xmlRecords(author, author, author+`<label>Doe2020b</label>`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Keys are generated from the first author, and do not reuse the labels
+	// of the document
+	var keys []string
+	for _, entry := range entries {
+		keys = append(keys, entry.Key)
+	}
+	if got, want := strings.Join(keys, " "), "Doe2020 Doe2020a Doe2020b"; got != want {
+		t.Errorf("keys = %s; want %s", got, want)
+	}
+	// Empty authors are dropped
+	authors := cite.Names(&entries[0], "author")
+	if len(authors) != 2 || cite.Text(authors[1].Prefix) != "van" || cite.Text(authors[1].Last) != "Gogh" {
+		t.Errorf("authors = %v", authors)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name, src, want string
+	}{
+		{"not XML", "Author, A. (2020)", ""},
+		{"unclosed record", "<xml>\n<records>\n<record><titles><title>T</title></titles>\n", "line 4: XML syntax error"},
+		{"mismatched element", "<xml>\n<record>\n<title>T</titles>\n</record>\n</xml>", "line 3: XML syntax error"},
+		{"bad type code", "<xml><record><ref-type>book</ref-type></record></xml>", `line 1: strconv.ParseInt: parsing "book"`},
+	}
+	for _, tt := range tests {
+		entries, err := Parse(strings.NewReader(tt.src))
+		if tt.want == "" {
+			// Text outside elements has no records
+			if err != nil || len(entries) != 0 {
+				t.Errorf("%s: Parse = %v, %v; want no entries", tt.name, entries, err)
+			}
+			continue
+		}
+		var endnoteErr *Error
+		if !errors.As(err, &endnoteErr) || !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("%s: Parse error = %v; want %s", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package bibtex
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BibError is an error in a bibliography file, located at an entry or a field
+// so that tools can report it like a compiler diagnostic, for example
+//
+//	refs.bib:12: entry "Smith2020": field "author": unbalanced braces
+type BibError struct {
+	// File is the name of the bibliography file, or "" if it was not read
+	// from a file.
+	File string
+	// Line is the line of the error, starting at 1, or 0 if it is unknown.
+	Line int
+	// Key is the key of the entry, or "" for syntax errors outside entries.
+	Key string
+	// Field is the name of the field, or "" for errors of the whole entry.
+	Field string
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *BibError) Error() string {
+	var parts []string
+	switch {
+	case e.File != "" && e.Line > 0:
+		parts = append(parts, fmt.Sprintf("%s:%d", e.File, e.Line))
+	case e.File != "":
+		parts = append(parts, e.File)
+	case e.Line > 0:
+		parts = append(parts, fmt.Sprintf("line %d", e.Line))
+	}
+	if e.Key != "" {
+		parts = append(parts, fmt.Sprintf("entry %q", e.Key))
+	}
+	if e.Field != "" {
+		parts = append(parts, fmt.Sprintf("field %q", e.Field))
+	}
+	parts = append(parts, e.Err.Error())
+	return strings.Join(parts, ": ")
+}
+
+func (e *BibError) Unwrap() error {
+	return e.Err
+}
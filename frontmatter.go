@@ -0,0 +1,97 @@
+package bibtex
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/lmondada/goldmark-bibtex/cite"
+	"github.com/lmondada/goldmark-bibtex/csl"
+	meta "github.com/yuin/goldmark-meta"
+	"github.com/yuin/goldmark/parser"
+)
+
+// WithFrontMatter reads the bibliography settings of each document from its
+// front matter, parsed by the goldmark-meta extension:
+//
+//   - bibliography: a file or a list of files whose entries are added to the
+//     bibliography of the extender for this document, before the entries of
+//     its bibtex code blocks
+//   - nocite: keys listed in the reference list without being cited, like
+//     "@smith2020, @doe2019", or "@*" for all entries
+//   - csl: a CSL style file replacing the style of the extender
+//   - link-citations: whether citations link to their reference, see
+//     WithLinkCitations
+//
+// Paths are relative to root, like os.DirFS("content"). Files that cannot be
+// read are reported to the warning handler and ignored.
+func WithFrontMatter(root fs.FS) Option {
+	return func(e *Extender) {
+		e.root = root
+	}
+}
+
+// documentOptions are the options of the rendering of a document, which its
+// front matter may override.
+type documentOptions struct {
+	style Style
+	link  bool
+}
+
+// frontMatter returns the bibliography sources, the uncited keys and the
+// options of the document being transformed, from its front matter.
+func (t *bibliographyTransformer) frontMatter(pc parser.Context) ([]Source, []string, *documentOptions) {
+	var sources []Source
+	opts := &documentOptions{style: t.style, link: t.link}
+	m := meta.Get(pc)
+
+	for _, name := range stringList(m["bibliography"]) {
+		name = path.Clean(name)
+		data, err := fs.ReadFile(t.extender.root, name)
+		if err != nil {
+			t.report(cite.Warning{Message: err.Error()})
+			continue
+		}
+		sources = append(sources, Source{Name: name, Data: data})
+	}
+	if name, ok := m["csl"].(string); ok {
+		style, err := csl.LoadFS(t.extender.root, path.Clean(name))
+		if err != nil {
+			t.report(cite.Warning{Message: err.Error()})
+		} else {
+			opts.style = style
+		}
+	}
+	if link, ok := m["link-citations"].(bool); ok {
+		opts.link = link
+	}
+
+	var nocite []string
+	for _, s := range stringList(m["nocite"]) {
+		for _, key := range strings.FieldsFunc(s, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t' || r == '\n'
+		}) {
+			nocite = append(nocite, strings.TrimPrefix(key, "@"))
+		}
+	}
+	return sources, nocite, opts
+}
+
+// stringList returns a front matter value that is a string or a list of
+// strings as a list.
+func stringList(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var list []string
+		for _, x := range v {
+			if s, ok := x.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	default:
+		return nil
+	}
+}
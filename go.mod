@@ -4,4 +4,9 @@ go 1.23.3
 
 require github.com/yuin/goldmark v1.7.8
 
-require github.com/jschaf/bibtex v0.0.0-20250112212027-d97aa8eba549
+require (
+	github.com/jschaf/bibtex v0.0.0-20250112212027-d97aa8eba549
+	github.com/yuin/goldmark-meta v1.1.0
+)
+
+require gopkg.in/yaml.v2 v2.4.0 // indirect
@@ -0,0 +1,457 @@
+// Package ieee formats citations and references in the IEEE reference style,
+// with numeric citations like "[1]" and references like
+//
+//	H. Mahfoud, “Graph pattern matching,” Cluster Computing, vol. 23, no. 3, pp. 1529–1553, 2020.
+package ieee
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jschaf/bibtex"
+	bibtexAst "github.com/jschaf/bibtex/ast"
+	"github.com/lmondada/goldmark-bibtex/cite"
+)
+
+// defaultEtAl is the IEEE truncation: "Smith et al." for three or more
+// authors in narrative citations, and for seven or more authors in the
+// reference list.
+var defaultEtAl = cite.EtAl{
+	Inline:    cite.Truncation{Min: 3, UseFirst: 1},
+	Reference: cite.Truncation{Min: 7, UseFirst: 1},
+}
+
+// FormatAuthor formats an author with initials first, like "J. P. Campora".
+func FormatAuthor(author *bibtexAst.Author) string {
+	name := cite.Initials(cite.Text(author.First))
+	if prefix := cite.Text(author.Prefix); prefix != "" {
+		name += " " + prefix
+	}
+	name += fmt.Sprintf(` <span class="last-name">%s</span>`, cite.Text(author.Last))
+	if suffix := cite.Text(author.Suffix); suffix != "" {
+		name += ", " + suffix
+	}
+	return strings.TrimSpace(name)
+}
+
+// FormatAuthors formats a list of authors according to IEEE style: up to six
+// authors are separated with commas and "and" before the last one, more than
+// six are abbreviated to the first author followed by "et al."
+func FormatAuthors(authors bibtexAst.Authors) string {
+	return formatAuthors(authors, defaultEtAl.Reference)
+}
+
+func formatAuthors(authors bibtexAst.Authors, etAl cite.Truncation) string {
+	var s string
+	shown, truncated := etAl.Shown(len(authors))
+	switch {
+	case truncated:
+		names := make([]string, shown)
+		for i, author := range authors[:shown] {
+			names[i] = FormatAuthor(author)
+		}
+		s = strings.Join(names, ", ") + " <i>et al.</i>"
+	case len(authors) == 2:
+		s = FormatAuthor(authors[0]) + " and " + FormatAuthor(authors[1])
+	default:
+		for i, author := range authors {
+			if i > 0 {
+				if i == len(authors)-1 {
+					s += ", and "
+				} else {
+					s += ", "
+				}
+			}
+			s += FormatAuthor(author)
+		}
+	}
+	return fmt.Sprintf(`<span class="authors">%s</span>`, s)
+}
+
+// FormatInlineCitation formats the inline citation of a group of cited items
+// with numeric brackets, like "[1, 3]" or "[1–3]", see cite.FormatNumeric. A
+// single item cited in the ModeAuthorInText mode puts the author names in
+// front, like "Smith and Jones [2]".
+func FormatInlineCitation(items []cite.Item) string {
+	return formatInlineCitation(items, defaultEtAl.Inline)
+}
+
+func formatInlineCitation(items []cite.Item, etAl cite.Truncation) string {
+	return cite.FormatNumeric(items, func(entry *bibtex.Entry) string {
+		return formatAuthorLabel(entry, etAl)
+	})
+}
+
+// formatAuthorLabel formats the author last names of a narrative citation,
+// like "Smith", "Smith and Jones" or "Smith et al.", or the editors,
+// organization or title of works without authors.
+func formatAuthorLabel(entry *bibtex.Entry, etAl cite.Truncation) string {
+	lead := cite.LeadOf(entry)
+	authors := lead.Names
+	if len(authors) == 0 {
+		return lead.Label()
+	}
+	shown, truncated := etAl.Shown(len(authors))
+	names := make([]string, shown)
+	for i, author := range authors[:shown] {
+		names[i] = cite.Text(author.Last)
+	}
+	switch {
+	case truncated:
+		return strings.Join(names, ", ") + " <i>et al.</i>"
+	case shown == 1:
+		return names[0]
+	case shown == 2:
+		return names[0] + " and " + names[1]
+	default:
+		return strings.Join(names[:shown-1], ", ") + ", and " + names[shown-1]
+	}
+}
+
+// FormatCitation formats a full reference in IEEE style.
+func FormatCitation(entry *bibtex.Entry) string {
+	return formatCitation(entry, defaultEtAl.Reference)
+}
+
+func formatCitation(entry *bibtex.Entry, etAl cite.Truncation) string {
+	ref := getRef(entry, etAl)
+
+	switch strings.ToLower(entry.Type) {
+	case "article":
+		return formatArticle(ref)
+	case "inproceedings", "conference":
+		return formatProceedings(ref)
+	case "book":
+		return formatBook(ref)
+	case "techreport":
+		return formatTechreport(ref)
+	case "phdthesis":
+		return formatThesis(ref, "Ph.D. dissertation")
+	case "mastersthesis":
+		return formatThesis(ref, "M.S. thesis")
+	case "thesis":
+		return formatThesis(ref, cite.Field(entry, "type"))
+	default:
+		if strings.EqualFold(getFieldText(entry, "archiveprefix"), "arXiv") {
+			return formatArxiv(ref)
+		}
+		return formatMisc(ref)
+	}
+}
+
+// ref holds the formatted fields of an entry. Fields that are not set are "".
+type ref struct {
+	authors      string
+	title        string
+	journal      string
+	booktitle    string
+	volume       string
+	number       string
+	pages        string
+	date         string
+	publisher    string
+	address      string
+	institution  string
+	school       string
+	edition      string
+	howpublished string
+	eprint       string
+	primaryClass string
+	doi          string
+	url          string
+	accessed     string
+}
+
+func getRef(entry *bibtex.Entry, etAl cite.Truncation) ref {
+	r := ref{
+		title:        getTitle(entry, "title"),
+		journal:      getTitle(entry, "journal"),
+		booktitle:    getTitle(entry, "booktitle"),
+		volume:       getFieldText(entry, "volume"),
+		number:       getFieldText(entry, "number"),
+		pages:        formatPages(getFieldText(entry, "pages")),
+		date:         formatDate(entry),
+		publisher:    getFieldText(entry, "publisher"),
+		address:      getFieldText(entry, "address"),
+		institution:  getFieldText(entry, "institution"),
+		school:       getFieldText(entry, "school"),
+		edition:      getFieldText(entry, "edition"),
+		howpublished: getFieldText(entry, "howpublished"),
+		eprint:       getFieldText(entry, "eprint"),
+		primaryClass: getFieldText(entry, "primaryclass"),
+		doi:          getFieldText(entry, "doi"),
+		url:          getFieldText(entry, "url"),
+		accessed:     formatAccessed(entry),
+	}
+	// Works without authors are led by their editors or organization, or
+	// else by their title
+	switch lead := cite.LeadOf(entry); {
+	case len(lead.Names) == 1 && lead.Editors:
+		r.authors = formatAuthors(lead.Names, etAl) + ", Ed."
+	case lead.Editors:
+		r.authors = formatAuthors(lead.Names, etAl) + ", Eds."
+	case len(lead.Names) > 0:
+		r.authors = formatAuthors(lead.Names, etAl)
+	default:
+		r.authors = lead.Organization
+	}
+	return r
+}
+
+// formatArticle formats a journal article in IEEE style
+// Example: J. R. Ullmann, “An algorithm for subgraph isomorphism,” J. ACM, vol. 23, no. 1, pp. 31–42, Jan. 1976, doi: 10.1145/321921.321925.
+func formatArticle(r ref) string {
+	parts := []string{r.authors, quoteTitle(r.title), emph(r.journal)}
+	if r.volume != "" {
+		parts = append(parts, "vol. "+r.volume)
+	}
+	if r.number != "" {
+		parts = append(parts, "no. "+r.number)
+	}
+	parts = append(parts, r.pages, r.date)
+	return finish(parts, r)
+}
+
+// formatProceedings formats a conference paper in IEEE style
+// Example: H. Bunke, T. Glauser, and T.-H. Tran, “An efficient implementation of graph grammars,” in Graph-Grammars and Their Application to Computer Science, Bremen, Germany: Springer, 1990, pp. 174–189.
+func formatProceedings(r ref) string {
+	parts := []string{r.authors, quoteTitle(r.title)}
+	if r.booktitle != "" {
+		parts = append(parts, "in "+emph(r.booktitle))
+	}
+	parts = append(parts, publication(r.address, r.publisher), r.date, r.pages)
+	return finish(parts, r)
+}
+
+// formatBook formats a book in IEEE style
+// Example: D. Kosiur, Understanding Policy-Based Networking, 2nd ed. New York, NY: Wiley, 2001.
+func formatBook(r ref) string {
+	title := emph(r.title)
+	if r.edition != "" {
+		title += ", " + formatEdition(r.edition) + " ed."
+	}
+	citation := join([]string{r.authors, title})
+	if pub := publication(r.address, r.publisher); pub != "" {
+		if !strings.HasSuffix(citation, ".") {
+			citation += "."
+		}
+		citation += " " + join([]string{pub, r.date})
+	} else if r.date != "" {
+		citation += ", " + r.date
+	}
+	return finish([]string{citation}, r)
+}
+
+// formatTechreport formats a technical report in IEEE style
+// Example: A. Author, “Title of report,” Abbrev. Name of Co., City of Co., Abbrev. State, Tech. Rep. 1234, Jan. 2020.
+func formatTechreport(r ref) string {
+	report := "Tech. Rep."
+	if r.number != "" {
+		report += " " + r.number
+	}
+	parts := []string{r.authors, quoteTitle(r.title), r.institution, r.address, report, r.date}
+	return finish(parts, r)
+}
+
+// formatThesis formats a thesis of a kind, like "Ph.D. dissertation", in
+// IEEE style
+// Example: J. O. Williams, “Narrow-band analyzer,” Ph.D. dissertation, Dept. Elect. Eng., Harvard Univ., Cambridge, MA, 1993.
+func formatThesis(r ref, kind string) string {
+	parts := []string{r.authors, quoteTitle(r.title), kind, r.school, r.address, r.date}
+	return finish(parts, r)
+}
+
+// formatArxiv formats an arXiv preprint in IEEE style
+// Example: A. Javadi-Abhari et al., “Quantum computing with Qiskit,” 2024, arXiv:2405.08810 [quant-ph].
+func formatArxiv(r ref) string {
+	parts := []string{r.authors, quoteTitle(r.title), r.date}
+	if r.eprint != "" {
+		eprint := fmt.Sprintf(`<a href="https://arxiv.org/abs/%s">arXiv:%s</a>`, r.eprint, r.eprint)
+		if r.primaryClass != "" {
+			eprint += fmt.Sprintf(" [%s]", r.primaryClass)
+		}
+		parts = append(parts, eprint)
+	}
+	return finish(parts, r)
+}
+
+// formatMisc formats other entries in IEEE style
+// Example: A. Author, “Title,” howpublished, Jan. 2020. [Online]. Available: https://example.com
+func formatMisc(r ref) string {
+	parts := []string{r.authors, quoteTitle(r.title), r.howpublished, r.date}
+	return finish(parts, r)
+}
+
+// finish joins the parts of a reference with commas, adds the DOI or else the
+// URL, and wraps the result in a span.
+func finish(parts []string, r ref) string {
+	if r.doi != "" {
+		parts = append(parts, fmt.Sprintf(`doi: <a href="https://doi.org/%s">%s</a>`, r.doi, r.doi))
+	}
+	citation := join(parts)
+	if strings.HasSuffix(citation, ",”") {
+		citation = strings.TrimSuffix(citation, ",”") + ".”"
+	} else if !strings.HasSuffix(citation, ".") {
+		citation += "."
+	}
+	if r.doi == "" && r.url != "" {
+		if r.accessed != "" {
+			citation += " Accessed: " + r.accessed + "."
+		}
+		citation += fmt.Sprintf(` [Online]. Available: <a href="%s">%s</a>`, r.url, r.url)
+	}
+	return `<span class="citation-full">` + citation + "</span>"
+}
+
+// join joins the non-empty parts of a reference with commas. A quoted title
+// carries its comma inside the closing quotation mark.
+func join(parts []string) string {
+	var s string
+	for _, part := range parts {
+		if part == "" {
+			continue
+		}
+		switch {
+		case s == "":
+		case strings.HasSuffix(s, ",”"):
+			s += " "
+		default:
+			s += ", "
+		}
+		s += part
+	}
+	return s
+}
+
+// quoteTitle puts a title in quotation marks, with a trailing comma inside the
+// closing mark as IEEE requires.
+func quoteTitle(title string) string {
+	if title == "" {
+		return ""
+	}
+	return "“" + title + ",”"
+}
+
+func emph(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "<em>" + s + "</em>"
+}
+
+// publication formats the place of publication and the publisher, like
+// "New York, NY: Wiley".
+func publication(address, publisher string) string {
+	switch {
+	case address != "" && publisher != "":
+		return address + ": " + publisher
+	default:
+		return address + publisher
+	}
+}
+
+// formatPages formats a page range with an en dash and the "p." or "pp."
+// prefix, like "pp. 31–42".
+func formatPages(pages string) string {
+	if pages == "" {
+		return ""
+	}
+	pages = cite.PageRange(pages)
+	if strings.Contains(pages, "–") || strings.Contains(pages, ",") {
+		return "pp. " + pages
+	}
+	return "p. " + pages
+}
+
+// formatEdition formats an edition as an ordinal, like "2nd" for "2" or
+// "second".
+func formatEdition(edition string) string {
+	ordinals := map[string]string{
+		"first": "1st", "second": "2nd", "third": "3rd", "fourth": "4th", "fifth": "5th",
+	}
+	if ordinal, ok := ordinals[strings.ToLower(edition)]; ok {
+		return ordinal
+	}
+	return cite.Ordinal(edition)
+}
+
+// months are the IEEE abbreviations of month names.
+var months = map[string]string{
+	"jan": "Jan.", "feb": "Feb.", "mar": "Mar.", "apr": "Apr.",
+	"may": "May", "jun": "Jun.", "jul": "Jul.", "aug": "Aug.",
+	"sep": "Sep.", "oct": "Oct.", "nov": "Nov.", "dec": "Dec.",
+}
+
+// formatDate formats the year of an entry, preceded by the abbreviated month
+// if it is set, like "Jan. 1976".
+func formatDate(entry *bibtex.Entry) string {
+	year := getFieldText(entry, "year")
+	var month string
+	switch m := entry.Tags["month"].(type) {
+	case *bibtexAst.Ident:
+		month = months[strings.ToLower(m.Name)]
+	case *bibtexAst.Text:
+		if len(m.Value) >= 3 {
+			month = months[strings.ToLower(m.Value[:3])]
+		}
+		if month == "" {
+			month = m.Value
+		}
+	}
+	return strings.TrimSpace(month + " " + year)
+}
+
+// formatAccessed formats the date the URL of an entry was accessed, like
+// "Mar. 15, 2021", or returns "" if it is not set.
+func formatAccessed(entry *bibtex.Entry) string {
+	date, ok := cite.URLDate(entry)
+	switch {
+	case !ok:
+		return ""
+	case date.Month == 0:
+		return strconv.Itoa(date.Year)
+	case date.Day == 0:
+		return fmt.Sprintf("%s %d", months[cite.MonthKeys[date.Month-1]], date.Year)
+	default:
+		return fmt.Sprintf("%s %d, %d", months[cite.MonthKeys[date.Month-1]], date.Day, date.Year)
+	}
+}
+
+// getFieldText returns the text of a BibTeX field, or "" if it is not set.
+func getFieldText(entry *bibtex.Entry, field string) string {
+	return cite.Field(entry, field)
+}
+
+// getTitle returns a title field as written, since IEEE titles keep the
+// capitalization of the work.
+func getTitle(entry *bibtex.Entry, field string) string {
+	return cite.Title(entry, field, cite.AsIs)
+}
+
+// Style is the IEEE reference style. It implements the Style interface of the
+// goldmark-bibtex extension.
+type Style struct {
+	// EtAl overrides the truncation of author lists, see defaultEtAl.
+	EtAl cite.EtAl
+}
+
+// FormatCitation formats an inline citation, see FormatInlineCitation.
+func (s Style) FormatCitation(items []cite.Item) string {
+	return formatInlineCitation(items, s.EtAl.Or(defaultEtAl).Inline)
+}
+
+// FormatReference formats a full reference, see FormatCitation.
+func (s Style) FormatReference(ref cite.Reference) string {
+	return formatCitation(ref.Entry, s.EtAl.Or(defaultEtAl).Reference)
+}
+
+// Less keeps the references in the order in which they are first cited.
+func (Style) Less(a, b *bibtex.Entry) bool {
+	return false
+}
+
+// FormatBibliography formats the reference list as an ordered list.
+func (s Style) FormatBibliography(refs []cite.Reference) string {
+	return cite.FormatList("ol", refs, s.FormatReference)
+}
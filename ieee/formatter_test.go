@@ -0,0 +1,15 @@
+package ieee
+
+import "testing"
+
+func TestFormatEdition(t *testing.T) {
+	tests := map[string]string{
+		"2": "2nd", "second": "2nd", "11": "11th", "21": "21st", "22": "22nd",
+		"23": "23rd", "Revised": "Revised",
+	}
+	for edition, want := range tests {
+		if got := formatEdition(edition); got != want {
+			t.Errorf("formatEdition(%q) = %q; want %q", edition, got, want)
+		}
+	}
+}
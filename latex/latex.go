@@ -0,0 +1,517 @@
+// Package latex decodes the LaTeX markup of BibTeX field values, so that
+// references show the text the markup stands for:
+//
+//   - accents like \'e, \"{o} and \v{c} become accented letters, like é, ö
+//     and č, and symbol macros like \ss and \o their characters, ß and ø
+//   - dashes and quotes like -- and “...” become typographic characters,
+//     like – and “...”
+//   - formatting macros like \emph{...}, \textit{...} and \textbf{...}, and
+//     declarations like {\em ...}, become HTML elements, like <em>...</em>
+//   - brace groups of titles, like {RETE}, keep their case, see
+//     cite.ChangeCase
+//   - math like $\alpha^2$ becomes text, like α<sup>2</sup>, or the markup
+//     of MathJax, KaTeX or MathML, see MathMode
+//
+// The text of fields other than names is escaped for HTML, like &amp; for
+// \& or &lt; for <, so that it is not read as markup. Unknown macros are kept
+// as written, like \foo{bar}, and reported.
+package latex
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jschaf/bibtex/ast"
+	"github.com/lmondada/goldmark-bibtex/cite"
+)
+
+// nameFields are the fields of names, whose structure the name parser reads
+// and where formatting macros keep only their text.
+var nameFields = map[string]bool{
+	"author": true,
+	"editor": true,
+}
+
+// titleFields are the fields of titles, whose brace groups keep their case
+// when styles change the case of titles, like {RETE}, as do their math.
+var titleFields = map[string]bool{
+	"title":           true,
+	"subtitle":        true,
+	"titleaddon":      true,
+	"shorttitle":      true,
+	"booktitle":       true,
+	"booksubtitle":    true,
+	"maintitle":       true,
+	"mainsubtitle":    true,
+	"journal":         true,
+	"journaltitle":    true,
+	"journalsubtitle": true,
+	"issuetitle":      true,
+	"eventtitle":      true,
+	"series":          true,
+}
+
+// verbatimFields are the fields whose value is not LaTeX text, like URLs,
+// which are left as is.
+var verbatimFields = map[string]bool{
+	"url":    true,
+	"urlraw": true,
+	"doi":    true,
+	"eprint": true,
+	"file":   true,
+	"pdf":    true,
+	"verba":  true,
+	"verbb":  true,
+	"verbc":  true,
+}
+
+// MathMode is the form of the math of field values, like $O(n^2)$.
+type MathMode int
+
+const (
+	// MathText writes math as text, like O(n<sup>2</sup>).
+	MathText MathMode = iota
+	// MathJax writes the TeX of math in \(...\) delimiters, which MathJax
+	// and the KaTeX auto-render extension typeset.
+	MathJax
+	// MathKaTeX writes the TeX of math in a <span class="math inline">
+	// element, like pandoc for KaTeX.
+	MathKaTeX
+	// MathML writes math as a MathML <math> element, which browsers render
+	// without scripts.
+	MathML
+)
+
+// Resolver is a bibtex.Resolver that decodes the LaTeX markup of the fields
+// of entries. It must run before the resolvers that parse names and render
+// text, since it keeps the braces, commas and spaces that names are split
+// on.
+type Resolver struct {
+	// Warn is called with each unknown macro, or nil to ignore them.
+	Warn func(cite.Warning)
+	// Math is the form of math, except in names, where it is always text.
+	// Defaults to MathText.
+	Math MathMode
+}
+
+// Resolve implements bibtex.Resolver.
+func (r Resolver) Resolve(root ast.Node) error {
+	return ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		decl, ok := n.(*ast.BibDecl)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+		for _, tag := range decl.Tags {
+			txt, ok := tag.Value.(*ast.ParsedText)
+			if !ok || verbatimFields[tag.Name] {
+				continue
+			}
+			d := &decoder{
+				html:    !nameFields[tag.Name],
+				protect: titleFields[tag.Name],
+				mode:    r.Math,
+				warn: func(macro string) {
+					if r.Warn != nil {
+						r.Warn(cite.Warning{
+							Key:     decl.Key.Name,
+							Field:   tag.Name,
+							Message: fmt.Sprintf(`unknown LaTeX macro \%s`, macro),
+						})
+					}
+				},
+			}
+			txt.Values = d.values(txt.Values)
+		}
+		return ast.WalkSkipChildren, nil
+	})
+}
+
+// decoder decodes the values of a field.
+type decoder struct {
+	// html is whether formatting becomes HTML elements, or is dropped
+	html bool
+	// protect is whether brace groups and math keep their case, see
+	// cite.NoCaseOpen
+	protect bool
+	// mode is the form of math, if html is set
+	mode MathMode
+	warn func(macro string)
+}
+
+// ligatures replaces the TeX ligatures of dashes and quotes.
+var ligatures = strings.NewReplacer(
+	"---", "—",
+	"--", "–",
+	"``", "“",
+	"''", "”",
+	"`", "‘",
+	"!`", "¡",
+	"?`", "¿",
+)
+
+// values decodes a list of values. Macros take their argument from the
+// values that follow them.
+func (d *decoder) values(values []ast.Expr) []ast.Expr {
+	out := make([]ast.Expr, 0, len(values))
+	for i := 0; i < len(values); i++ {
+		switch v := values[i].(type) {
+		case *ast.Text:
+			out = append(out, &ast.Text{ValuePos: v.ValuePos, Value: d.escape(ligatures.Replace(v.Value))})
+		case *ast.TextEscaped:
+			out = append(out, &ast.Text{ValuePos: v.ValuePos, Value: d.escape(v.Value)})
+		case *ast.TextAccent:
+			out = append(out, &ast.Text{ValuePos: v.ValuePos, Value: d.escape(accent(string(v.Accent), v.Text.Value))})
+		case *ast.TextMath:
+			out = append(out, d.noCase([]ast.Expr{&ast.Text{ValuePos: v.ValuePos, Value: d.formula(v.Value)}})...)
+		case *ast.ParsedText:
+			decoded := d.values(v.Values)
+			// Groups that do not start with a macro, like {RETE} but not
+			// {\em ...} or {\"o}, keep their case, as in BibTeX
+			if _, special := at(v.Values, 0).(*ast.TextMacro); d.protect && !special && !isAccent(at(v.Values, 0)) {
+				out = append(out, d.noCase(decoded)...)
+				continue
+			}
+			// A group of a single text, like {\ss} or {-}, is part of the
+			// word around it, except a protected {and} in names
+			if t, ok := at(decoded, 0).(*ast.Text); ok && len(decoded) == 1 && t.Value != "and" {
+				out = append(out, t)
+				continue
+			}
+			out = append(out, &ast.ParsedText{
+				Opener: v.Opener,
+				Depth:  v.Depth,
+				Delim:  v.Delim,
+				Values: decoded,
+				Closer: v.Closer,
+			})
+		case *ast.TextMacro:
+			var decoded []ast.Expr
+			decoded, i = d.macro(v, values, i)
+			out = append(out, decoded...)
+		default:
+			out = append(out, v)
+		}
+	}
+	return merge(out)
+}
+
+// escape escapes the text s for HTML, if the decoder writes HTML, so that
+// text like "x < y" or an escaped \& is not read as markup.
+func (d *decoder) escape(s string) string {
+	if !d.html {
+		return s
+	}
+	return html.EscapeString(s)
+}
+
+// noCase encloses values in the markup of text that keeps its case, if the
+// decoder protects groups and there are values.
+func (d *decoder) noCase(values []ast.Expr) []ast.Expr {
+	if !d.protect || len(values) == 0 {
+		return values
+	}
+	out := make([]ast.Expr, 0, len(values)+2)
+	out = append(out, &ast.Text{Value: cite.NoCaseOpen})
+	out = append(out, values...)
+	return append(out, &ast.Text{Value: cite.NoCaseClose})
+}
+
+// isAccent reports whether v is an accent or an escaped character, like \"o
+// or \&.
+func isAccent(v ast.Expr) bool {
+	switch v.(type) {
+	case *ast.TextAccent, *ast.TextEscaped:
+		return true
+	}
+	return false
+}
+
+// merge joins adjacent texts, which the name parser would read as separate
+// words.
+func merge(values []ast.Expr) []ast.Expr {
+	out := values[:0]
+	for _, v := range values {
+		t, ok := v.(*ast.Text)
+		if prev, isText := at(out, len(out)-1).(*ast.Text); ok && isText {
+			out[len(out)-1] = &ast.Text{ValuePos: prev.ValuePos, Value: prev.Value + t.Value}
+			continue
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+// macro decodes the macro at values[i], and returns its text and the index
+// of the last value it consumed.
+func (d *decoder) macro(m *ast.TextMacro, values []ast.Expr, i int) ([]ast.Expr, int) {
+	text := func(s string) []ast.Expr {
+		return []ast.Expr{&ast.Text{ValuePos: m.Cmd, Value: s}}
+	}
+	switch name := m.Name; {
+	case name == "url":
+		return text(d.escape(render(m.Values))), i
+	case name == "href":
+		// The URL is parsed with the macro, and the text follows
+		if arg, j := argument(values, i); arg != nil {
+			return d.values(arg), j
+		}
+		return text(d.escape(render(m.Values))), i
+	case accents[name] != 0:
+		arg, j := argument(values, i)
+		if arg == nil {
+			// An argument without braces is the letter after the spaces,
+			// like \v c
+			j = skipSpaces(values, i) + 1
+			t, ok := at(values, j).(*ast.Text)
+			if !ok || t.Value == "" {
+				return text(string(accents[name])), i
+			}
+			r, size := utf8.DecodeRuneInString(t.Value)
+			return text(d.escape(accent(name, string(r)) + ligatures.Replace(t.Value[size:]))), j
+		}
+		return text(accent(name, render(d.values(arg)))), j
+	case hasKey(symbols, name):
+		// Control words end at the spaces or the empty group after them
+		j := skipSpaces(values, i)
+		if p, ok := at(values, i+1).(*ast.ParsedText); ok && len(p.Values) == 0 {
+			j = i + 1
+		}
+		return text(d.escape(symbols[name])), j
+	case discarded[name]:
+		_, j := argument(values, i)
+		return nil, j
+	case hasKey(markup, name):
+		arg, j := argument(values, i)
+		return d.wrap(markup[name], d.values(arg)), j
+	case hasKey(declarations, name):
+		// The declaration applies to the rest of the group
+		rest := values[skipSpaces(values, i)+1:]
+		return d.wrap(declarations[name], d.values(rest)), len(values) - 1
+	default:
+		d.warn(name)
+		out := text(d.escape(`\` + name))
+		if arg, j := argument(values, i); arg != nil {
+			out = append(out, &ast.Text{Value: "{"})
+			out = append(out, d.values(arg)...)
+			out = append(out, &ast.Text{Value: "}"})
+			i = j
+		}
+		return out, i
+	}
+}
+
+// wrap wraps values in an HTML element, or returns them as is if the
+// decoder does not write HTML, the element is "" or there are no values.
+func (d *decoder) wrap(element string, values []ast.Expr) []ast.Expr {
+	if !d.html || element == "" || len(values) == 0 {
+		return values
+	}
+	open, close := "<"+element+">", "</"+element+">"
+	if element == "small-caps" {
+		open, close = `<span style="font-variant: small-caps">`, "</span>"
+	}
+	out := make([]ast.Expr, 0, len(values)+2)
+	out = append(out, &ast.Text{Value: open})
+	out = append(out, values...)
+	return append(out, &ast.Text{Value: close})
+}
+
+// argument returns the values of the braced argument after values[i], and
+// its index, or nil if there is none.
+func argument(values []ast.Expr, i int) ([]ast.Expr, int) {
+	j := skipSpaces(values, i)
+	if p, ok := at(values, j+1).(*ast.ParsedText); ok {
+		return p.Values, j + 1
+	}
+	return nil, i
+}
+
+// skipSpaces returns the index of the last space after values[i], or i if
+// there is none.
+func skipSpaces(values []ast.Expr, i int) int {
+	for {
+		if _, ok := at(values, i+1).(*ast.TextSpace); !ok {
+			return i
+		}
+		i++
+	}
+}
+
+// at returns values[i], or nil if i is out of range.
+func at(values []ast.Expr, i int) ast.Expr {
+	if i < 0 || i >= len(values) {
+		return nil
+	}
+	return values[i]
+}
+
+// accent puts an accent on the first letter of s.
+func accent(name, s string) string {
+	mark := accents[name]
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError {
+		return string(mark)
+	}
+	// The dotless i and j take accents without their dot
+	switch r {
+	case 'ı':
+		r = 'i'
+	case 'ȷ':
+		r = 'j'
+	}
+	if c, ok := composed[string([]rune{r, mark})]; ok {
+		return string(c) + s[size:]
+	}
+	return string(r) + string(mark) + s[size:]
+}
+
+// formula returns the math mode text s in the form of the decoder.
+func (d *decoder) formula(s string) string {
+	if !d.html {
+		return d.math(s)
+	}
+	switch d.mode {
+	case MathJax:
+		return `\(` + html.EscapeString(s) + `\)`
+	case MathKaTeX:
+		return `<span class="math inline">` + html.EscapeString(s) + `</span>`
+	case MathML:
+		return `<math>` + d.mathML(s, "") + `</math>`
+	default:
+		return d.math(s)
+	}
+}
+
+// math decodes the text of math mode, like "\\alpha^{2}": symbols become
+// their characters, superscripts and subscripts HTML elements, fractions
+// slashes, and braces are dropped.
+func (d *decoder) math(s string) string {
+	var sb strings.Builder
+	for s != "" {
+		switch c := s[0]; c {
+		case '\\':
+			name := macroName(s[1:])
+			s = s[1+len(name):]
+			switch {
+			case name == "frac":
+				var num, den string
+				num, s = mathArgument(s)
+				den, s = mathArgument(s)
+				sb.WriteString(d.math(num) + "/" + d.math(den))
+			case hasKey(mathSymbols, name):
+				sb.WriteString(d.escape(mathSymbols[name]))
+			case hasKey(symbols, name):
+				sb.WriteString(d.escape(symbols[name]))
+			case mathFonts[name]:
+				var arg string
+				arg, s = mathArgument(s)
+				sb.WriteString(d.math(arg))
+			default:
+				d.warn(name)
+				sb.WriteString(d.escape(`\` + name))
+			}
+			// Spaces end control words
+			if name != "" && isLetter(name[0]) {
+				s = strings.TrimLeft(s, " ")
+			}
+		case '^', '_':
+			var arg string
+			arg, s = mathArgument(s[1:])
+			element := "sup"
+			if c == '_' {
+				element = "sub"
+			}
+			if d.html {
+				sb.WriteString("<" + element + ">" + d.math(arg) + "</" + element + ">")
+			} else {
+				sb.WriteString(d.math(arg))
+			}
+		case '{', '}':
+			s = s[1:]
+		case '~':
+			sb.WriteString(" ")
+			s = s[1:]
+		default:
+			sb.WriteString(d.escape(s[:1]))
+			s = s[1:]
+		}
+	}
+	return sb.String()
+}
+
+// macroName returns the name of the macro at the start of s, after its
+// backslash: the letters of a control word, or a single other character.
+func macroName(s string) string {
+	i := 0
+	for i < len(s) && isLetter(s[i]) {
+		i++
+	}
+	if i == 0 && s != "" {
+		_, size := utf8.DecodeRuneInString(s)
+		return s[:size]
+	}
+	return s[:i]
+}
+
+// mathArgument splits the argument at the start of s from the rest: the
+// text in braces, or a single character.
+func mathArgument(s string) (arg, rest string) {
+	s = strings.TrimLeft(s, " ")
+	if s == "" {
+		return "", ""
+	}
+	if s[0] != '{' {
+		if s[0] == '\\' {
+			name := macroName(s[1:])
+			return s[:1+len(name)], s[1+len(name):]
+		}
+		_, size := utf8.DecodeRuneInString(s)
+		return s[:size], s[size:]
+	}
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return s[1:i], s[i+1:]
+			}
+		}
+	}
+	return s[1:], ""
+}
+
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// render returns the text of decoded values.
+func render(values []ast.Expr) string {
+	var sb strings.Builder
+	for _, v := range values {
+		switch v := v.(type) {
+		case *ast.Text:
+			sb.WriteString(v.Value)
+		case *ast.ParsedText:
+			sb.WriteString(render(v.Values))
+		case *ast.TextMacro:
+			sb.WriteString(render(v.Values))
+		case *ast.TextComma:
+			sb.WriteString(",")
+		case *ast.TextHyphen:
+			sb.WriteString("-")
+		case *ast.TextSpace, *ast.TextNBSP:
+			sb.WriteString(" ")
+		}
+	}
+	return sb.String()
+}
+
+func hasKey[V any](m map[string]V, key string) bool {
+	_, ok := m[key]
+	return ok
+}
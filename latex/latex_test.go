@@ -0,0 +1,105 @@
+package latex
+
+import (
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/jschaf/bibtex/ast"
+	"github.com/jschaf/bibtex/parser"
+	"github.com/lmondada/goldmark-bibtex/cite"
+)
+
+// decode returns the decoded text of a field value, and the messages of the
+// warnings of its unknown macros.
+func decode(t *testing.T, r Resolver, field, value string) (string, []string) {
+	t.Helper()
+	f, err := parser.ParseFile(token.NewFileSet(), "test.bib", "@misc{key, "+field+" = {"+value+"}}", parser.ParseStrings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var warnings []string
+	r.Warn = func(w cite.Warning) {
+		warnings = append(warnings, w.Message)
+	}
+	if err := r.Resolve(f); err != nil {
+		t.Fatal(err)
+	}
+	for _, tag := range f.Entries[0].(*ast.BibDecl).Tags {
+		if tag.Name == field {
+			if txt, ok := tag.Value.(*ast.ParsedText); ok {
+				return render(txt.Values), warnings
+			}
+		}
+	}
+	t.Fatalf("no %s field", field)
+	return "", nil
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		field, value, want string
+	}{
+		// Accents and symbols
+		{"note", `G\"{o}del and Erd\H{o}s`, "Gödel and Erdős"},
+		{"note", `{\'E}cole, \v{c}, \c{c} and {\ss}`, "École, č, ç and ß"},
+		{"note", `\o, \AA{} and \dots`, "ø, Å and …"},
+		// Ligatures and escapes
+		{"note", "pages 1--2 --- ``quoted''", "pages 1–2 — “quoted”"},
+		{"note", `R\&D, 50\% and \$5`, "R&amp;D, 50% and $5"},
+		{"note", `x < y & "z"`, "x &lt; y &amp; &#34;z&#34;"},
+		// Markup
+		{"note", `\emph{Graphs} and \textbf{trees}`, "<em>Graphs</em> and <b>trees</b>"},
+		{"note", `{\em Graphs} and {\it trees}`, "<em>Graphs</em> and <i>trees</i>"},
+		{"note", `\url{https://example.com/?a=1&b=2}`, "https://example.com/?a=1&amp;b=2"},
+		// Names keep their text without markup or escaping
+		{"author", `\emph{Smith} \& Co.`, "Smith & Co."},
+		{"author", `M{\"u}ller, J{\"o}rg`, "Müller, Jörg"},
+		// Titles keep the case of their groups and math
+		{"title", `The {RETE} algorithm`, "The " + cite.NoCaseOpen + "RETE" + cite.NoCaseClose + " algorithm"},
+		{"title", `{\"O}sterreich and {\em Graphs}`, "Österreich and <em>Graphs</em>"},
+		{"title", `$O(n)$ time`, cite.NoCaseOpen + "O(n)" + cite.NoCaseClose + " time"},
+		{"note", `{RETE} and $n$`, "RETE and n"},
+		// Math
+		{"note", `$\alpha^2 + x_i < \infty$`, "α<sup>2</sup> + x<sub>i</sub> &lt; ∞"},
+		// Unknown macros are kept as written
+		{"note", `\foo{bar} baz`, `\foo{bar} baz`},
+	}
+	for _, tt := range tests {
+		if got, _ := decode(t, Resolver{}, tt.field, tt.value); got != tt.want {
+			t.Errorf("%s = {%s}: decoded %q; want %q", tt.field, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestResolveMath(t *testing.T) {
+	tests := []struct {
+		mode MathMode
+		want string
+	}{
+		{MathText, "α<sup>2</sup> &lt; n"},
+		{MathJax, `\(\alpha^2 &lt; n\)`},
+		{MathKaTeX, `<span class="math inline">\alpha^2 &lt; n</span>`},
+		{MathML, `<math><msup><mi>α</mi><mrow><mn>2</mn></mrow></msup><mo>&lt;</mo><mi>n</mi></math>`},
+	}
+	for _, tt := range tests {
+		if got, _ := decode(t, Resolver{Math: tt.mode}, "note", `$\alpha^2 < n$`); got != tt.want {
+			t.Errorf("math mode %d: decoded %q; want %q", tt.mode, got, tt.want)
+		}
+		// Names always have math as text
+		if got, _ := decode(t, Resolver{Math: tt.mode}, "author", `$\alpha$ Smith`); got != "α Smith" {
+			t.Errorf("math mode %d: decoded name %q; want %q", tt.mode, got, "α Smith")
+		}
+	}
+}
+
+func TestResolveWarnings(t *testing.T) {
+	_, warnings := decode(t, Resolver{}, "note", `\foo{bar} \emph{x} \baz`)
+	if got, want := strings.Join(warnings, "; "), `unknown LaTeX macro \foo; unknown LaTeX macro \baz`; got != want {
+		t.Errorf("warnings = %s; want %s", got, want)
+	}
+	// Verbatim fields are not decoded
+	if _, warnings := decode(t, Resolver{}, "url", `https://example.com/\foo`); len(warnings) != 0 {
+		t.Errorf("url warnings = %v; want none", warnings)
+	}
+}
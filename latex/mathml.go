@@ -0,0 +1,159 @@
+package latex
+
+import (
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// mathVariants maps the math font macros to the MathML mathvariant of their
+// argument. The text macros, which are not listed, become <mtext> elements.
+var mathVariants = map[string]string{
+	"mathrm":       "normal",
+	"operatorname": "normal",
+	"mathit":       "italic",
+	"mathbf":       "bold",
+	"boldsymbol":   "bold-italic",
+	"mathsf":       "sans-serif",
+	"mathtt":       "monospace",
+	"mathcal":      "script",
+	"mathscr":      "script",
+	"mathbb":       "double-struck",
+	"mathfrak":     "fraktur",
+}
+
+// mathML converts the text of math mode, like "O(n^2)", to MathML elements.
+// Letters take the mathvariant variant, unless it is "", and runs of letters
+// in a variant are a single identifier, like \mathrm{QMA}.
+func (d *decoder) mathML(s, variant string) string {
+	var sb strings.Builder
+	for s != "" {
+		var base string
+		base, s = d.mathMLAtom(s, variant)
+		var sub, sup string
+		for {
+			t := strings.TrimLeft(s, " ")
+			if t == "" || t[0] != '^' && t[0] != '_' {
+				break
+			}
+			var arg string
+			arg, s = mathArgument(t[1:])
+			script := "<mrow>" + d.mathML(arg, variant) + "</mrow>"
+			if t[0] == '^' {
+				sup = script
+			} else {
+				sub = script
+			}
+		}
+		switch {
+		case sub != "" && sup != "":
+			sb.WriteString("<msubsup>" + base + sub + sup + "</msubsup>")
+		case sup != "":
+			sb.WriteString("<msup>" + base + sup + "</msup>")
+		case sub != "":
+			sb.WriteString("<msub>" + base + sub + "</msub>")
+		default:
+			sb.WriteString(base)
+		}
+	}
+	return sb.String()
+}
+
+// mathMLAtom converts the first atom of s, like a letter, a number, a
+// symbol or a group, and returns it with the rest of s. Spaces are dropped,
+// as in math mode.
+func (d *decoder) mathMLAtom(s, variant string) (string, string) {
+	switch c := s[0]; {
+	case c == ' ' || c == '}':
+		return "", s[1:]
+	case c == '^' || c == '_':
+		// A script without a base, like ^2
+		return "<mrow></mrow>", s
+	case c == '{':
+		arg, rest := mathArgument(s)
+		return "<mrow>" + d.mathML(arg, variant) + "</mrow>", rest
+	case c == '~':
+		return "<mtext> </mtext>", s[1:]
+	case '0' <= c && c <= '9':
+		i := 1
+		for i < len(s) && ('0' <= s[i] && s[i] <= '9' || s[i] == '.' && i+1 < len(s) && '0' <= s[i+1] && s[i+1] <= '9') {
+			i++
+		}
+		return "<mn>" + s[:i] + "</mn>", s[i:]
+	case isLetter(c):
+		i := 1
+		if variant != "" {
+			for i < len(s) && isLetter(s[i]) {
+				i++
+			}
+		}
+		return mathMLIdentifier(s[:i], variant), s[i:]
+	case c == '\\':
+		name := macroName(s[1:])
+		rest := s[1+len(name):]
+		// Spaces end control words
+		if name != "" && isLetter(name[0]) {
+			rest = strings.TrimLeft(rest, " ")
+		}
+		return d.mathMLMacro(name, rest, variant)
+	default:
+		_, size := utf8.DecodeRuneInString(s)
+		return "<mo>" + html.EscapeString(s[:size]) + "</mo>", s[size:]
+	}
+}
+
+// mathMLMacro converts the macro name, whose arguments start rest.
+func (d *decoder) mathMLMacro(name, rest, variant string) (string, string) {
+	switch {
+	case name == "frac":
+		num, rest := mathArgument(rest)
+		den, rest := mathArgument(rest)
+		return "<mfrac><mrow>" + d.mathML(num, variant) + "</mrow><mrow>" + d.mathML(den, variant) + "</mrow></mfrac>", rest
+	case name == "sqrt":
+		arg, rest := mathArgument(rest)
+		return "<msqrt>" + d.mathML(arg, variant) + "</msqrt>", rest
+	case hasKey(mathVariants, name):
+		arg, rest := mathArgument(rest)
+		return "<mrow>" + d.mathML(arg, mathVariants[name]) + "</mrow>", rest
+	case mathFonts[name]:
+		arg, rest := mathArgument(rest)
+		// The text is decoded without markup, then escaped
+		plain := *d
+		plain.html = false
+		return "<mtext>" + html.EscapeString(plain.math(arg)) + "</mtext>", rest
+	case hasKey(mathSymbols, name):
+		return mathMLSymbol(mathSymbols[name]), rest
+	case hasKey(symbols, name):
+		return mathMLSymbol(symbols[name]), rest
+	default:
+		d.warn(name)
+		return "<mtext>\\" + html.EscapeString(name) + "</mtext>", rest
+	}
+}
+
+// mathMLSymbol returns the element of the text of a symbol macro: an
+// identifier for letters and function names, like α and sin, a space for
+// spacing macros, and an operator otherwise.
+func mathMLSymbol(text string) string {
+	r, _ := utf8.DecodeRuneInString(text)
+	switch {
+	case text == "":
+		return ""
+	case strings.TrimSpace(text) == "":
+		return "<mtext>" + strings.Repeat(" ", len(text)) + "</mtext>"
+	case unicode.IsLetter(r):
+		return mathMLIdentifier(text, "")
+	default:
+		return "<mo>" + html.EscapeString(text) + "</mo>"
+	}
+}
+
+// mathMLIdentifier returns the identifier element of name in variant, or in
+// the default font of MathML if variant is "".
+func mathMLIdentifier(name, variant string) string {
+	if variant == "" {
+		return "<mi>" + html.EscapeString(name) + "</mi>"
+	}
+	return `<mi mathvariant="` + variant + `">` + html.EscapeString(name) + "</mi>"
+}
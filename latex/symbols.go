@@ -0,0 +1,307 @@
+package latex
+
+// symbols maps the LaTeX macros without arguments to their text.
+var symbols = map[string]string{
+	"ss": "ß",
+	"ae": "æ", "AE": "Æ",
+	"oe": "œ", "OE": "Œ",
+	"o": "ø", "O": "Ø",
+	"aa": "å", "AA": "Å",
+	"l": "ł", "L": "Ł",
+	"i": "ı", "j": "ȷ",
+	"dh": "ð", "DH": "Ð",
+	"dj": "đ", "DJ": "Đ",
+	"th": "þ", "TH": "Þ",
+	"ng": "ŋ", "NG": "Ŋ",
+
+	"textendash":          "–",
+	"textemdash":          "—",
+	"textquoteleft":       "‘",
+	"textquoteright":      "’",
+	"textquotedblleft":    "“",
+	"textquotedblright":   "”",
+	"textquotesingle":     "'",
+	"textquotedbl":        "\"",
+	"guillemotleft":       "«",
+	"guillemotright":      "»",
+	"guilsinglleft":       "‹",
+	"guilsinglright":      "›",
+	"quotedblbase":        "„",
+	"quotesinglbase":      "‚",
+	"textexclamdown":      "¡",
+	"textquestiondown":    "¿",
+	"ldots":               "…",
+	"dots":                "…",
+	"textellipsis":        "…",
+	"textbullet":          "•",
+	"textperiodcentered":  "·",
+	"textdagger":          "†",
+	"dag":                 "†",
+	"textdaggerdbl":       "‡",
+	"ddag":                "‡",
+	"S":                   "§",
+	"textsection":         "§",
+	"P":                   "¶",
+	"textparagraph":       "¶",
+	"copyright":           "©",
+	"textcopyright":       "©",
+	"textregistered":      "®",
+	"texttrademark":       "™",
+	"pounds":              "£",
+	"textsterling":        "£",
+	"euro":                "€",
+	"texteuro":            "€",
+	"textyen":             "¥",
+	"textcent":            "¢",
+	"textdollar":          "$",
+	"textdegree":          "°",
+	"textmu":              "µ",
+	"textbackslash":       "\\",
+	"textasciitilde":      "~",
+	"textasciicircum":     "^",
+	"textunderscore":      "_",
+	"textbar":             "|",
+	"textless":            "<",
+	"textgreater":         ">",
+	"textbraceleft":       "{",
+	"textbraceright":      "}",
+	"textvisiblespace":    "␣",
+	"textnumero":          "№",
+	"textordfeminine":     "ª",
+	"textordmasculine":    "º",
+	"textonehalf":         "½",
+	"textonequarter":      "¼",
+	"textthreequarters":   "¾",
+	"texttimes":           "×",
+	"textdiv":             "÷",
+	"textpm":              "±",
+	"textminus":           "−",
+	"slash":               "/",
+	"nobreakspace":        " ",
+	"space":               " ",
+	"quad":                " ",
+	"qquad":               "  ",
+	"enspace":             " ",
+	"thinspace":           " ",
+	"LaTeX":               "LaTeX",
+	"LaTeXe":              "LaTeX2ε",
+	"TeX":                 "TeX",
+	"BibTeX":              "BibTeX",
+	"XeTeX":               "XeTeX",
+	"relax":               "",
+	"ignorespaces":        "",
+	"unskip":              "",
+	"newblock":            "",
+	"protect":             "",
+	"allowbreak":          "",
+	"textcompwordmark":    "",
+	"textperthousand":     "‰",
+	"textpertenthousand":  "‱",
+	"textinterrobang":     "‽",
+	"textleaf":            "❧",
+	"textmusicalnote":     "♪",
+	"textestimated":       "℮",
+	"textcelsius":         "℃",
+	"textohm":             "Ω",
+	"textmho":             "℧",
+	"textreferencemark":   "※",
+	"textlangle":          "⟨",
+	"textrangle":          "⟩",
+	"textlbrackdbl":       "⟦",
+	"textrbrackdbl":       "⟧",
+	"textleftarrow":       "←",
+	"textrightarrow":      "→",
+	"textuparrow":         "↑",
+	"textdownarrow":       "↓",
+	"textblank":           "␢",
+	"textdiscount":        "⁒",
+	"textrecipe":          "℞",
+	"textservicemark":     "℠",
+	"textcircledP":        "℗",
+	"textopenbullet":      "◦",
+	"textbrokenbar":       "¦",
+	"textcurrency":        "¤",
+	"textflorin":          "ƒ",
+	"textlira":            "₤",
+	"textnaira":           "₦",
+	"textpeso":            "₱",
+	"textwon":             "₩",
+	"textdong":            "₫",
+	"textbaht":            "฿",
+	"textguarani":         "₲",
+	"textcolonmonetary":   "₡",
+	"textfractionsolidus": "⁄",
+	"textlnot":            "¬",
+	"textsurd":            "√",
+	"textonesuperior":     "¹",
+	"texttwosuperior":     "²",
+	"textthreesuperior":   "³",
+	"textacutedbl":        "˝",
+	"textasciiacute":      "´",
+	"textasciibreve":      "˘",
+	"textasciicaron":      "ˇ",
+	"textasciidieresis":   "¨",
+	"textasciigrave":      "`",
+	"textasciimacron":     "¯",
+}
+
+// accents maps the LaTeX accent macros to the combining characters they put
+// on their argument. The parser reads the accents written with a symbol,
+// like \'e, and the cedilla \c as accent nodes, and the others as macros.
+var accents = map[string]rune{
+	"'":  '́',
+	"`":  '̀',
+	"^":  '̂',
+	"\"": '̈',
+	"~":  '̃',
+	"=":  '̄',
+	".":  '̇',
+	"c":  '̧',
+	"v":  '̌',
+	"u":  '̆',
+	"H":  '̋',
+	"k":  '̨',
+	"r":  '̊',
+	"d":  '̣',
+	"b":  '̱',
+	"t":  '͡',
+}
+
+// composed maps letters followed by a combining character to the
+// precomposed letter, for the letters of the Latin-1 Supplement and Latin
+// Extended-A blocks. Other combinations keep the combining character.
+var composed = make(map[string]rune)
+
+func init() {
+	for mark, pairs := range map[rune]string{
+		'́': "aáeéiíoóuúyýAÁEÉIÍOÓUÚYÝcćCĆnńNŃsśSŚzźZŹlĺLĹrŕRŔgǵGǴ",
+		'̀': "aàeèiìoòuùAÀEÈIÌOÒUÙnǹNǸ",
+		'̂': "aâeêiîoôuûAÂEÊIÎOÔUÛcĉCĈgĝGĜhĥHĤjĵJĴsŝSŜwŵWŴyŷYŶ",
+		'̈': "aäeëiïoöuüyÿAÄEËIÏOÖUÜYŸ",
+		'̃': "aãnñoõiĩuũAÃNÑOÕIĨUŨ",
+		'̄': "aāeēiīoōuūAĀEĒIĪOŌUŪ",
+		'̇': "cċeėgġzżCĊEĖGĠIİZŻ",
+		'̧': "cçsştţgģkķlļnņrŗCÇSŞTŢGĢKĶLĻNŅRŖ",
+		'̌': "cčdďeěnňrřsštťzžaǎiǐoǒuǔgǧkǩCČDĎEĚNŇRŘSŠTŤZŽAǍIǏOǑUǓGǦKǨ",
+		'̆': "aăeĕgğiĭoŏuŭAĂEĔGĞIĬOŎUŬ",
+		'̋': "oőuűOŐUŰ",
+		'̨': "aąeęiįuųAĄEĘIĮUŲ",
+		'̊': "aåuůAÅUŮ",
+	} {
+		runes := []rune(pairs)
+		for i := 0; i+1 < len(runes); i += 2 {
+			composed[string([]rune{runes[i], mark})] = runes[i+1]
+		}
+	}
+}
+
+// markup maps the LaTeX text formatting macros to the HTML elements of
+// their argument. Macros mapped to "" only keep their argument.
+var markup = map[string]string{
+	"emph":              "em",
+	"textit":            "i",
+	"textsl":            "i",
+	"textbf":            "b",
+	"textsc":            "small-caps",
+	"texttt":            "code",
+	"textsuperscript":   "sup",
+	"textsubscript":     "sub",
+	"underline":         "u",
+	"textrm":            "",
+	"textsf":            "",
+	"textup":            "",
+	"textmd":            "",
+	"textnormal":        "",
+	"text":              "",
+	"mbox":              "",
+	"hbox":              "",
+	"textnohyphenation": "",
+	"NoCaseChange":      "",
+	"MakeUppercase":     "",
+	"MakeLowercase":     "",
+	"url":               "",
+	"nolinkurl":         "",
+}
+
+// discarded lists the macros whose argument is not part of the text, like
+// the sort key of \noopsort.
+var discarded = map[string]bool{
+	"noopsort":    true,
+	"hyphenation": true,
+	"label":       true,
+	"index":       true,
+}
+
+// declarations maps the LaTeX formatting declarations, which apply to the
+// rest of their group like {\em text}, to HTML elements.
+var declarations = map[string]string{
+	"em":         "em",
+	"it":         "i",
+	"itshape":    "i",
+	"sl":         "i",
+	"slshape":    "i",
+	"bf":         "b",
+	"bfseries":   "b",
+	"sc":         "small-caps",
+	"scshape":    "small-caps",
+	"tt":         "code",
+	"ttfamily":   "code",
+	"rm":         "",
+	"rmfamily":   "",
+	"sf":         "",
+	"sffamily":   "",
+	"normalfont": "",
+	"upshape":    "",
+	"mdseries":   "",
+}
+
+// mathSymbols maps the macros of math mode to their text.
+var mathSymbols = map[string]string{
+	"alpha": "α", "beta": "β", "gamma": "γ", "delta": "δ", "epsilon": "ϵ",
+	"varepsilon": "ε", "zeta": "ζ", "eta": "η", "theta": "θ", "vartheta": "ϑ",
+	"iota": "ι", "kappa": "κ", "lambda": "λ", "mu": "μ", "nu": "ν", "xi": "ξ",
+	"pi": "π", "varpi": "ϖ", "rho": "ρ", "varrho": "ϱ", "sigma": "σ",
+	"varsigma": "ς", "tau": "τ", "upsilon": "υ", "phi": "ϕ", "varphi": "φ",
+	"chi": "χ", "psi": "ψ", "omega": "ω",
+	"Gamma": "Γ", "Delta": "Δ", "Theta": "Θ", "Lambda": "Λ", "Xi": "Ξ",
+	"Pi": "Π", "Sigma": "Σ", "Upsilon": "Υ", "Phi": "Φ", "Psi": "Ψ",
+	"Omega": "Ω",
+
+	"times": "×", "div": "÷", "pm": "±", "mp": "∓", "cdot": "·",
+	"ast": "∗", "star": "⋆", "circ": "∘", "bullet": "•", "oplus": "⊕",
+	"otimes": "⊗", "leq": "≤", "le": "≤", "geq": "≥", "ge": "≥", "neq": "≠",
+	"ne": "≠", "approx": "≈", "sim": "∼", "simeq": "≃", "cong": "≅",
+	"equiv": "≡", "propto": "∝", "ll": "≪", "gg": "≫", "in": "∈",
+	"notin": "∉", "ni": "∋", "subset": "⊂", "supset": "⊃", "subseteq": "⊆",
+	"supseteq": "⊇", "cup": "∪", "cap": "∩", "emptyset": "∅",
+	"varnothing": "∅", "setminus": "∖", "forall": "∀", "exists": "∃",
+	"neg": "¬", "lnot": "¬", "wedge": "∧", "land": "∧", "vee": "∨",
+	"lor": "∨", "infty": "∞", "partial": "∂", "nabla": "∇", "sum": "∑",
+	"prod": "∏", "int": "∫", "oint": "∮", "sqrt": "√", "to": "→",
+	"rightarrow": "→", "leftarrow": "←", "gets": "←", "leftrightarrow": "↔",
+	"Rightarrow": "⇒", "Leftarrow": "⇐", "Leftrightarrow": "⇔",
+	"mapsto": "↦", "uparrow": "↑", "downarrow": "↓", "langle": "⟨",
+	"rangle": "⟩", "lceil": "⌈", "rceil": "⌉", "lfloor": "⌊",
+	"rfloor": "⌋", "ldots": "…", "cdots": "⋯", "vdots": "⋮", "ddots": "⋱",
+	"prime": "′", "hbar": "ℏ", "ell": "ℓ", "Re": "ℜ", "Im": "ℑ",
+	"aleph": "ℵ", "perp": "⊥", "parallel": "∥", "mid": "∣", "angle": "∠",
+	"triangle": "△", "top": "⊤", "bot": "⊥", "vdash": "⊢", "models": "⊨",
+	"dagger": "†", "degree": "°", "log": "log", "ln": "ln", "exp": "exp",
+	"sin": "sin", "cos": "cos", "tan": "tan", "max": "max", "min": "min",
+	"lim": "lim", "det": "det", "dim": "dim", "ker": "ker", "deg": "deg",
+	"gcd": "gcd", "Pr": "Pr", "sup": "sup", "inf": "inf", "arg": "arg",
+	"mod": "mod", "bmod": "mod", "lvert": "|", "rvert": "|", "vert": "|",
+	"Vert": "‖", "lbrace": "{", "rbrace": "}", "{": "{", "}": "}",
+	"%": "%", "&": "&", "$": "$", "#": "#", "_": "_", ",": " ",
+	";": " ", ":": " ", "!": "", " ": " ", "quad": " ",
+	"qquad": "  ", "left": "", "right": "", "big": "", "Big": "",
+	"bigl": "", "bigr": "", "Bigl": "", "Bigr": "",
+}
+
+// mathFonts lists the math font macros, whose argument is kept as is.
+var mathFonts = map[string]bool{
+	"mathrm": true, "mathit": true, "mathbf": true, "mathsf": true,
+	"mathtt": true, "mathcal": true, "mathbb": true, "mathfrak": true,
+	"mathscr": true, "text": true, "textrm": true, "textit": true,
+	"textbf": true, "operatorname": true, "mbox": true, "boldsymbol": true,
+}
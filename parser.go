@@ -1,6 +1,10 @@
 package bibtex
 
 import (
+	"bytes"
+	"strings"
+
+	"github.com/lmondada/goldmark-bibtex/cite"
 	"github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/parser"
 	"github.com/yuin/goldmark/text"
@@ -42,12 +46,244 @@ func (s *citationParser) Parse(parent ast.Node, block text.Reader, pc parser.Con
 		return nil
 	}
 
-	block.Advance(i)
-	return &Citation{
+	citation := &Citation{
 		BaseInline: ast.BaseInline{},
 		Key:        string(line[1:i]),
 		RawText:    string(line[:i]),
+		Mode:       cite.ModeAuthorInText,
+	}
+
+	// A narrative citation may be followed by a bracketed locator and suffix,
+	// like `@smith2023 [p. 4]`
+	if n := narrativeSuffixLen(line[i:]); n > 0 {
+		suffix := ", " + string(bytes.TrimSpace(line[i+2:i+n-1]))
+		citation.Locator, citation.Suffix = splitLocator(suffix)
+		i += n
+		citation.RawText = string(line[:i])
+	}
+
+	block.Advance(i)
+	return citation
+}
+
+// narrativeSuffixLen returns the length of the bracketed suffix of a narrative
+// citation at the start of rest, like ` [p. 4]`, or 0 if there is none.
+func narrativeSuffixLen(rest []byte) int {
+	if !bytes.HasPrefix(rest, []byte(" [")) {
+		return 0
+	}
+	end := bytes.IndexByte(rest, ']')
+	if end < 0 {
+		return 0
+	}
+	content := bytes.TrimSpace(rest[2:end])
+	if len(content) == 0 || bytes.ContainsAny(content, "@[") {
+		return 0
+	}
+	// Leave inline links and reference links to the link parser
+	if end+1 < len(rest) && (rest[end+1] == '(' || rest[end+1] == '[') {
+		return 0
+	}
+	return end + 1
+}
+
+type citationGroupParser struct{}
+
+// NewCitationGroupParser returns a new inline parser for bracketed citation
+// groups in the Pandoc syntax, like `[see @smith2023, pp. 33-35; also @doe2020]`.
+func NewCitationGroupParser() parser.InlineParser {
+	return &citationGroupParser{}
+}
+
+// Trigger implements parser.InlineParser interface.
+func (s *citationGroupParser) Trigger() []byte {
+	return []byte{'['}
+}
+
+// Parse implements parser.InlineParser interface. A group may wrap across
+// the soft line breaks of its paragraph, like `[@smith2023;` and `@doe2020]`
+// on two lines.
+func (s *citationGroupParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, lastLine, lastPos, lastStart := peekGroup(block)
+	if len(line) <= 2 || line[0] != '[' {
+		return nil
+	}
+
+	end := bytes.IndexByte(line, ']')
+	if end < 0 || bytes.IndexByte(line[1:end], '[') >= 0 {
+		return nil
 	}
+	// Leave inline links and reference links to the link parser
+	if end+1 < len(line) && (line[end+1] == '(' || line[end+1] == '[') {
+		return nil
+	}
+
+	group := &CitationGroup{
+		RawText: string(line[:end+1]),
+		Nocite:  bytes.HasPrefix(line[end+1:], nociteClass),
+	}
+	for _, item := range bytes.Split(line[1:end], []byte{';'}) {
+		citation := parseCitationItem(item)
+		if citation == nil && group.Nocite && string(bytes.TrimSpace(item)) == "@*" {
+			citation = &Citation{Key: "*", RawText: "@*"}
+		}
+		if citation == nil {
+			// Not a citation group, e.g. a plain link label
+			return nil
+		}
+		group.AppendChild(group, citation)
+	}
+
+	if group.Nocite {
+		end += len(nociteClass)
+		group.RawText = string(line[:end+1])
+	}
+	block.SetPosition(lastLine, lastPos)
+	block.Advance(end + 1 - lastStart)
+	return group
+}
+
+// peekGroup returns the text from the reader position to the end of the
+// line of the first ']', joining the lines of the paragraph with spaces, or
+// to the end of the paragraph if there is none. It also returns the position
+// of the last line and the offset in the text where it starts, to advance
+// the reader to a point in it.
+func peekGroup(block text.Reader) (group []byte, lastLine int, lastPos text.Segment, lastStart int) {
+	line, pos := block.Position()
+	defer block.SetPosition(line, pos)
+	for {
+		peeked, _ := block.PeekLine()
+		lastLine, lastPos = block.Position()
+		lastStart = len(group)
+		group = append(group, peeked...)
+		if peeked == nil || bytes.IndexByte(peeked, ']') >= 0 {
+			break
+		}
+		// The line break becomes a space, as in the rendered paragraph
+		if n := len(group); n > 0 && group[n-1] == '\n' {
+			group[n-1] = ' '
+			if n > 1 && group[n-2] == '\r' {
+				group[n-2] = ' '
+			}
+		}
+		block.AdvanceLine()
+	}
+	return group, lastLine, lastPos, lastStart
+}
+
+// nociteClass follows a citation group whose works are listed in the
+// reference list without being cited, like `[@smith2023; @doe2020]{.nocite}`.
+var nociteClass = []byte("{.nocite}")
+
+// parseCitationItem parses a single item of a citation group, like
+// `see @smith2023, pp. 33-35, and passim`. A `-` before the key suppresses the
+// author. It returns nil if the item does not contain a citation key.
+func parseCitationItem(item []byte) *Citation {
+	at := -1
+	for i, c := range item {
+		if c == '@' && (i == 0 || item[i-1] == ' ' || item[i-1] == '-') {
+			at = i
+			break
+		}
+	}
+	if at < 0 {
+		return nil
+	}
+
+	mode := cite.ModeNormal
+	prefixEnd := at
+	if at > 0 && item[at-1] == '-' {
+		if at > 1 && item[at-2] != ' ' {
+			return nil
+		}
+		mode = cite.ModeSuppressAuthor
+		prefixEnd = at - 1
+	}
+
+	var i int
+	for i = at + 1; i < len(item); i++ {
+		if !isValidCitationChar(item[i]) {
+			break
+		}
+	}
+	if i == at+1 {
+		return nil
+	}
+
+	locator, suffix := splitLocator(string(item[i:]))
+	return &Citation{
+		Key:     string(item[at+1 : i]),
+		RawText: string(bytes.TrimSpace(item)),
+		Mode:    mode,
+		Prefix:  string(bytes.TrimSpace(item[:prefixEnd])),
+		Locator: locator,
+		Suffix:  strings.TrimRight(suffix, " "),
+	}
+}
+
+// locatorTerms are the labels that introduce a locator, longest first so that
+// e.g. "pp." is not mistaken for "p.".
+var locatorTerms = []string{
+	"chapter", "section", "volume", "figure", "pages", "page", "lines", "line",
+	"chap.", "vols.", "figs.", "para.", "col.", "vol.", "fig.", "art.",
+	"pp.", "ch.", "sec.", "no.", "nn.", "ll.", "bk.", "pt.",
+	"p.", "n.", "l.", "v.", "§§", "§",
+}
+
+// splitLocator splits the text after a citation key into a locator and the
+// remaining suffix. A locator is a comma followed by a locator term and its
+// value, like ", pp. 33-35". The suffix keeps its leading punctuation.
+func splitLocator(rest string) (locator, suffix string) {
+	if !strings.HasPrefix(rest, ",") {
+		return "", rest
+	}
+	s := strings.TrimLeft(rest[1:], " ")
+
+	var term string
+	for _, t := range locatorTerms {
+		if strings.HasPrefix(s, t) {
+			term = t
+			break
+		}
+	}
+	if term == "" {
+		return "", rest
+	}
+
+	i := len(term)
+	if i < len(s) && s[i] != ' ' && (s[i] < '0' || s[i] > '9') {
+		// The term is only the beginning of a word, like "chapters"
+		return "", rest
+	}
+	for i < len(s) && s[i] == ' ' {
+		i++
+	}
+	start := i
+scan:
+	for i < len(s) {
+		switch {
+		case isLocatorChar(s[i]):
+			i++
+		case strings.HasPrefix(s[i:], "–"):
+			i += len("–")
+		case strings.HasPrefix(s[i:], ", ") && i+2 < len(s) && s[i+2] >= '0' && s[i+2] <= '9':
+			// A list of pages, like "pp. 33-35, 38"
+			i += 2
+		default:
+			break scan
+		}
+	}
+	if i == start {
+		return "", rest
+	}
+	return s[:i], s[i:]
+}
+
+func isLocatorChar(c byte) bool {
+	return (c >= 'a' && c <= 'z') ||
+		(c >= 'A' && c <= 'Z') ||
+		(c >= '0' && c <= '9') ||
+		c == '-'
 }
 
 func isValidCitationChar(c byte) bool {
@@ -1,9 +1,13 @@
 package bibtex
 
 import (
+	"fmt"
+	"regexp"
+	"strings"
+
 	"github.com/jschaf/bibtex"
 	"github.com/lmondada/goldmark-bibtex/acm"
-	"github.com/lmondada/goldmark-bibtex/apa"
+	"github.com/lmondada/goldmark-bibtex/cite"
 	"github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/renderer"
 	"github.com/yuin/goldmark/util"
@@ -11,24 +15,33 @@ import (
 
 // CitationRenderer is a renderer.NodeRenderer implementation that renders Citation nodes.
 type CitationRenderer struct {
-	bibliography map[string]bibtex.Entry
+	style Style
 }
 
-// NewCitationRenderer returns a new CitationRenderer.
+// NewCitationRenderer returns a new CitationRenderer that formats citations
+// with acm.Style. The citations carry their entries, which the bibliography
+// transformer finds, so bib is not used.
+//
+// Deprecated: bib is ignored. Use an Extender, created with New and
+// configured with WithStyle, or NewStyleCitationRenderer with a Style.
 func NewCitationRenderer(bib []bibtex.Entry) renderer.NodeRenderer {
-	bibMap := make(map[string]bibtex.Entry, len(bib))
-	for _, b := range bib {
-		bibMap[b.Key] = b
-	}
+	return NewStyleCitationRenderer(acm.Style{})
+}
 
+// NewStyleCitationRenderer returns a new CitationRenderer that formats
+// citations with the given style.
+func NewStyleCitationRenderer(style Style) renderer.NodeRenderer {
 	return &CitationRenderer{
-		bibliography: bibMap,
+		style: style,
 	}
 }
 
 // RegisterFuncs implements renderer.NodeRenderer interface.
 func (r *CitationRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
 	reg.Register(CitationKind, r.Render)
+	reg.Register(CitationGroupKind, r.RenderGroup)
+	reg.Register(BibliographyKind, r.RenderBibliography)
+	reg.Register(CitationNotesKind, r.RenderNotes)
 }
 
 func (r *CitationRenderer) Render(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
@@ -37,22 +50,149 @@ func (r *CitationRenderer) Render(w util.BufWriter, source []byte, node ast.Node
 	}
 
 	n := node.(*Citation)
-	entry, ok := r.bibliography[n.Key]
-	if !ok {
+	if n.Note > 0 {
+		r.renderCitation(w, []cite.Item{citationItem(n)}, n.Note, r.optionsOf(n.options))
+		return ast.WalkContinue, nil
+	}
+	if n.Entry == nil {
 		// Citation not found, render as question mark
 		_, _ = w.WriteString("[?]")
 		return ast.WalkContinue, nil
 	}
 
-	r.renderCitation(w, &entry)
+	r.renderCitation(w, []cite.Item{citationItem(n)}, 0, r.optionsOf(n.options))
 
 	return ast.WalkContinue, nil
 }
 
-func (r *CitationRenderer) renderCitation(w util.BufWriter, entry *bibtex.Entry) {
-	// currently ACM, but with APA citation key style as ACM requires numbering
+// RenderGroup renders a CitationGroup node as a single parenthetical citation.
+func (r *CitationRenderer) RenderGroup(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	n := node.(*CitationGroup)
+	r.renderCitation(w, groupItems(n), n.Note, r.optionsOf(n.options))
+
+	return ast.WalkSkipChildren, nil
+}
+
+func groupItems(n *CitationGroup) []cite.Item {
+	items := make([]cite.Item, 0, n.ChildCount())
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		items = append(items, citationItem(c.(*Citation)))
+	}
+	return items
+}
+
+func citationItem(n *Citation) cite.Item {
+	return cite.Item{
+		Entry:   n.Entry,
+		Number:  n.Number,
+		Key:     n.Key,
+		Mode:    n.Mode,
+		Prefix:  n.Prefix,
+		Locator: n.Locator,
+		Suffix:  n.Suffix,
+		First:   n.First,
+
+		Disambiguation: n.Disambiguation,
+	}
+}
+
+// optionsOf returns the options of a node, or the options of the renderer if
+// the bibliography transformer did not set them.
+func (r *CitationRenderer) optionsOf(opts *documentOptions) documentOptions {
+	if opts == nil {
+		return documentOptions{style: r.style}
+	}
+	return *opts
+}
+
+// renderCitation renders a citation, followed by the reference to its footnote
+// if note is not 0.
+func (r *CitationRenderer) renderCitation(w util.BufWriter, items []cite.Item, note int, opts documentOptions) {
 	_, _ = w.WriteString(`<span class="citation">`)
-	_, _ = w.WriteString(apa.FormatCitationKey(entry))
-	_, _ = w.WriteString(acm.FormatCitation(entry))
+	citation := opts.style.FormatCitation(items)
+	if opts.link {
+		citation = linkCitationKeys(citation)
+	}
+	_, _ = w.WriteString(citation)
+	if note > 0 {
+		_, _ = fmt.Fprintf(w, `<sup class="footnote-ref"><a href="#cite-note-%d" id="cite-noteref-%d">%d</a></sup>`, note, note, note)
+	}
 	_, _ = w.WriteString(`</span>`)
 }
+
+// RenderBibliography renders a Bibliography node as the reference list of the style.
+func (r *CitationRenderer) RenderBibliography(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	n := node.(*Bibliography)
+	_, _ = w.WriteString("<section class=\"references\">\n")
+	_, _ = w.WriteString(r.optionsOf(n.options).style.FormatBibliography(n.References))
+	_, _ = w.WriteString("</section>\n")
+
+	return ast.WalkSkipChildren, nil
+}
+
+// RenderNotes renders a CitationNotes node as the footnotes of a note style,
+// each with a link back to its reference in the text.
+func (r *CitationRenderer) RenderNotes(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	n := node.(*CitationNotes)
+	style, ok := r.optionsOf(n.options).style.(NoteStyle)
+	if !ok {
+		return ast.WalkSkipChildren, nil
+	}
+	_, _ = w.WriteString("<section class=\"footnotes citation-notes\">\n<ol>\n")
+	for i, items := range n.Notes {
+		_, _ = fmt.Fprintf(w, `<li id="cite-note-%d">`, i+1)
+		_, _ = w.WriteString(style.FormatNote(items))
+		_, _ = fmt.Fprintf(w, ` <a href="#cite-noteref-%d" class="footnote-backref">↩︎</a></li>`+"\n", i+1)
+	}
+	_, _ = w.WriteString("</ol>\n</section>\n")
+
+	return ast.WalkSkipChildren, nil
+}
+
+var citationKeySpan = regexp.MustCompile(`<span data-bibtex-key="([^" ]+)[^"]*" class="citation-key">`)
+
+// linkCitationKeys makes the label of each cited entry in a formatted
+// citation a link to its reference, the first one for a range of entries.
+func linkCitationKeys(citation string) string {
+	var sb strings.Builder
+	for {
+		loc := citationKeySpan.FindStringSubmatchIndex(citation)
+		if loc == nil {
+			sb.WriteString(citation)
+			return sb.String()
+		}
+		sb.WriteString(citation[:loc[1]])
+		sb.WriteString(`<a href="#ref-` + citation[loc[2]:loc[3]] + `">`)
+		citation = citation[loc[1]:]
+		// Close the link before the end of the span, skipping nested spans
+		end, depth := 0, 0
+		for {
+			i := strings.Index(citation[end:], "</span>")
+			if i < 0 {
+				end = len(citation)
+				break
+			}
+			depth += strings.Count(citation[end:end+i], "<span")
+			if depth == 0 {
+				end += i
+				break
+			}
+			depth--
+			end += i + len("</span>")
+		}
+		sb.WriteString(citation[:end] + "</a>")
+		citation = citation[end:]
+	}
+}
@@ -0,0 +1,304 @@
+// Package ris loads bibliographies in the RIS format exported by reference
+// managers, converting each record to the BibTeX entry the styles format:
+//
+//	entries, err := ris.Load("references.ris")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	bibExtender := bibtex.NewFromEntries(entries)
+//
+// Reference types are mapped to the closest BibTeX entry type, like JOUR to
+// article, and tags to the matching fields, like T2 to the journal of an
+// article or the booktitle of a chapter. Records without an ID get a key
+// made of the last name of the first author and the year, like "Smith2020".
+package ris
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jschaf/bibtex"
+	bibtexAst "github.com/jschaf/bibtex/ast"
+	"github.com/lmondada/goldmark-bibtex/cite"
+)
+
+// Error is an error in a RIS document.
+type Error struct {
+	// Line is the line of the error, starting at 1.
+	Line int
+	// Key is the ID of the record, or "" for errors outside records or in
+	// records without an ID.
+	Key string
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Key == "" {
+		return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+	}
+	return fmt.Sprintf("line %d: record %q: %v", e.Line, e.Key, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Load loads the entries of a RIS file.
+func Load(path string) ([]bibtex.Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(bytes.NewReader(data))
+}
+
+// LoadFS loads the entries of a RIS file in fsys, like an embed.FS.
+func LoadFS(fsys fs.FS, name string) ([]bibtex.Entry, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(bytes.NewReader(data))
+}
+
+// field is a tagged line of a record.
+type field struct {
+	tag, value string
+}
+
+// Parse parses the records of a RIS document. Errors are returned as *Error.
+func Parse(r io.Reader) ([]bibtex.Entry, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	keys := make(cite.KeyGenerator)
+	var entries []bibtex.Entry
+	var record []field
+	start, line := 0, 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(strings.TrimPrefix(scanner.Text(), "\ufeff"), " \r")
+		if text == "" {
+			continue
+		}
+		tag, value, ok := splitLine(text)
+		if !ok {
+			if record == nil {
+				return nil, &Error{Line: line, Err: fmt.Errorf("expected a tag, found %q", text)}
+			}
+			// A value continued on the next line
+			record[len(record)-1].value += " " + strings.TrimSpace(text)
+			continue
+		}
+		switch {
+		case tag == "TY":
+			if record != nil {
+				return nil, &Error{Line: line, Err: errors.New("TY before the ER of the previous record")}
+			}
+			start = line
+			record = []field{{tag, value}}
+		case record == nil:
+			return nil, &Error{Line: line, Err: fmt.Errorf("%s outside a record", tag)}
+		case tag == "ER":
+			entry := convert(record)
+			if entry.Key == "" {
+				entry.Key = keys.Key(&entry)
+			} else {
+				keys[entry.Key] = true
+			}
+			entries = append(entries, entry)
+			record = nil
+		default:
+			record = append(record, field{tag, value})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, &Error{Line: line, Err: err}
+	}
+	if record != nil {
+		return nil, &Error{Line: start, Err: errors.New("record without ER")}
+	}
+	return entries, nil
+}
+
+// splitLine splits a line like "AU  - Smith, John" into its tag and value.
+func splitLine(line string) (tag, value string, ok bool) {
+	if len(line) < 5 || line[2:5] != "  -" {
+		return "", "", false
+	}
+	tag = line[:2]
+	for _, c := range tag {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return "", "", false
+		}
+	}
+	return tag, strings.TrimSpace(line[5:]), true
+}
+
+// entryTypes maps RIS reference types to BibTeX entry types. Other types
+// are misc entries.
+var entryTypes = map[string]string{
+	"JOUR":   "article",
+	"JFULL":  "article",
+	"MGZN":   "article",
+	"NEWS":   "article",
+	"BOOK":   "book",
+	"EDBOOK": "book",
+	"CHAP":   "incollection",
+	"CONF":   "inproceedings",
+	"CPAPER": "inproceedings",
+	"THES":   "phdthesis",
+	"RPRT":   "techreport",
+	"UNPB":   "unpublished",
+	"PAMP":   "booklet",
+}
+
+// tagFields maps RIS tags to BibTeX fields. Tags that depend on the
+// reference type are handled in convert.
+var tagFields = map[string]string{
+	"ID": "key",
+	"TI": "title",
+	"T1": "title",
+	"T3": "series",
+	"JO": "journal",
+	"JF": "journal",
+	"BT": "booktitle",
+	"VL": "volume",
+	"IS": "number",
+	"PB": "publisher",
+	"CY": "address",
+	"DO": "doi",
+	"UR": "url",
+	"AB": "abstract",
+	"N2": "abstract",
+	"N1": "note",
+	"ET": "edition",
+	"M3": "type",
+	"LA": "language",
+}
+
+// convert converts the fields of a record to a BibTeX entry.
+func convert(record []field) bibtex.Entry {
+	entry := bibtex.Entry{
+		Type: entryTypes[record[0].value],
+		Tags: make(map[string]bibtexAst.Expr),
+	}
+	if entry.Type == "" {
+		entry.Type = "misc"
+	}
+	setText := func(field, value string) {
+		if value != "" {
+			entry.Tags[field] = &bibtexAst.Text{Value: value}
+		}
+	}
+
+	var authors, editors bibtexAst.Authors
+	var keywords []string
+	var startPage, endPage, container, date string
+	for _, f := range record[1:] {
+		// Values are plain text, escaped for HTML like the decoded fields of
+		// BibTeX files, except keys
+		value := f.value
+		if f.tag != "ID" {
+			value = html.EscapeString(value)
+		}
+		switch f.tag {
+		case "AU", "A1":
+			authors = append(authors, cite.ParseName(value))
+		case "A2", "ED":
+			editors = append(editors, cite.ParseName(value))
+		case "T2":
+			container = value
+		case "SP":
+			startPage = value
+		case "EP":
+			endPage = value
+		case "PY", "Y1", "DA":
+			if date == "" || f.tag != "DA" {
+				date = value
+			}
+		case "KW":
+			keywords = append(keywords, value)
+		case "SN":
+			if entry.Type == "book" || entry.Type == "incollection" {
+				setText("isbn", value)
+			} else {
+				setText("issn", value)
+			}
+		default:
+			if name, ok := tagFields[f.tag]; ok {
+				if _, set := entry.Tags[name]; !set {
+					setText(name, value)
+				}
+			}
+		}
+	}
+	if key, ok := entry.Tags["key"]; ok {
+		entry.Key = cite.Text(key)
+		delete(entry.Tags, "key")
+	}
+	if len(authors) > 0 {
+		entry.Tags["author"] = authors
+	}
+	if len(editors) > 0 {
+		entry.Tags["editor"] = editors
+	}
+	if len(keywords) > 0 {
+		setText("keywords", strings.Join(keywords, ", "))
+	}
+	switch {
+	case startPage != "" && endPage != "":
+		setText("pages", startPage+"–"+endPage)
+	default:
+		setText("pages", cite.PageRange(startPage))
+	}
+
+	switch entry.Type {
+	case "article":
+		if _, ok := entry.Tags["journal"]; !ok {
+			setText("journal", container)
+		}
+	case "incollection", "inproceedings":
+		if _, ok := entry.Tags["booktitle"]; !ok {
+			setText("booktitle", container)
+		}
+	case "phdthesis":
+		if strings.Contains(strings.ToLower(cite.Field(&entry, "type")), "master") {
+			entry.Type = "mastersthesis"
+		}
+		delete(entry.Tags, "type")
+		moveField(&entry, "publisher", "school")
+	case "techreport":
+		moveField(&entry, "publisher", "institution")
+	case "misc":
+		setText("howpublished", container)
+	}
+
+	// Dates are written like "2020/03/15/" or "2020"
+	parts := strings.Split(date, "/")
+	if len(parts[0]) >= 4 {
+		setText("year", parts[0][:4])
+	}
+	if len(parts) > 1 {
+		if month, err := strconv.Atoi(parts[1]); err == nil && month >= 1 && month <= 12 {
+			entry.Tags["month"] = &bibtexAst.Ident{Name: cite.MonthKeys[month-1]}
+		}
+	}
+	return entry
+}
+
+// moveField renames a field of an entry.
+func moveField(entry *bibtex.Entry, from, to string) {
+	if value, ok := entry.Tags[from]; ok {
+		entry.Tags[to] = value
+		delete(entry.Tags, from)
+	}
+}
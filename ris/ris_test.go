@@ -0,0 +1,138 @@
+package ris
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/lmondada/goldmark-bibtex/cite"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name, src string
+		typ, key  string
+		fields    map[string]string
+	}{
+		{
+			"article",
+			"TY  - JOUR\nID  - Mahfoud2020\nAU  - Mahfoud, Houari\nTI  - Graph pattern matching\nT2  - Cluster Computing\n" +
+				"VL  - 23\nIS  - 3\nSP  - 1529\nEP  - 1553\nPY  - 2020/03/15/\nSN  - 1573-7543\nER  - \n",
+			"article", "Mahfoud2020",
+			map[string]string{"title": "Graph pattern matching", "journal": "Cluster Computing", "volume": "23",
+				"number": "3", "pages": "1529–1553", "year": "2020", "month": "mar", "issn": "1573-7543"},
+		},
+		{
+			// A byte order mark, CRLF line ends and continued values
+			"continued",
+			"\uFEFFTY  - BOOK\r\nTI  - A long\r\n  title\r\nSN  - 978-3\r\nPB  - Springer\r\nER  - \r\n",
+			"book", "Alongtitle",
+			map[string]string{"title": "A long title", "isbn": "978-3", "publisher": "Springer", "issn": ""},
+		},
+		{
+			"chapter",
+			"TY  - CHAP\nAU  - Doe, Jane\nT1  - Chapter\nTI  - Ignored\nT2  - The Book\nED  - Roe, Richard\nSP  - 10--20\nY1  - 2019\nER  - \n",
+			"incollection", "Doe2019",
+			map[string]string{"title": "Chapter", "booktitle": "The Book", "pages": "10–20", "year": "2019"},
+		},
+		{
+			"master's thesis",
+			"TY  - THES\nAU  - Smith, John\nTI  - Graphs\nM3  - Master's thesis\nPB  - MIT\nDA  - 2018/13\nPY  - 2019\nER  - \n",
+			"mastersthesis", "Smith2019",
+			map[string]string{"school": "MIT", "publisher": "", "type": "", "year": "2019", "month": ""},
+		},
+		{
+			"report",
+			"TY  - RPRT\nAU  - Doe, Jane\nPB  - CERN\nKW  - graphs\nKW  - rewriting\nDA  - 2022\nER  - \n",
+			"techreport", "Doe2022",
+			map[string]string{"institution": "CERN", "keywords": "graphs, rewriting", "year": "2022"},
+		},
+		{
+			// Values are escaped for HTML, except keys
+			"escaped",
+			"TY  - BOOK\nAU  - O'Brien, Pat\nTI  - Bounds for n < m & <script>alert(1)</script>\nPB  - Smith & Sons\nPY  - 2020\nER  - \n",
+			"book", "OBrien2020",
+			map[string]string{"title": "Bounds for n &lt; m &amp; &lt;script&gt;alert(1)&lt;/script&gt;", "publisher": "Smith &amp; Sons"},
+		},
+		{
+			"unknown type",
+			"TY  - ELEC\nTI  - Web page\nT2  - Example\nUR  - https://example.com\nPY  - 2021\nER  - \n",
+			"misc", "Webpage2021",
+			map[string]string{"howpublished": "Example", "url": "https://example.com", "year": "2021"},
+		},
+	}
+	for _, tt := range tests {
+		entries, err := Parse(strings.NewReader(tt.src))
+		if err != nil {
+			t.Errorf("%s: Parse error = %v", tt.name, err)
+			continue
+		}
+		if len(entries) != 1 {
+			t.Errorf("%s: got %d entries; want 1", tt.name, len(entries))
+			continue
+		}
+		entry := entries[0]
+		if entry.Type != tt.typ || entry.Key != tt.key {
+			t.Errorf("%s: entry = @%s{%s}; want @%s{%s}", tt.name, entry.Type, entry.Key, tt.typ, tt.key)
+		}
+		for field, want := range tt.fields {
+			if got := cite.Field(&entry, field); got != want {
+				t.Errorf("%s: %s = %q; want %q", tt.name, field, got, want)
+			}
+		}
+	}
+}
+
+func TestParseNames(t *testing.T) {
+	src := "TY  - EDBOOK\nA2  - van Gogh, Vincent\nED  - Smith, Jr., John\nER  - \n" +
+		"TY  - JOUR\nAU  - Doe, Jane\nPY  - 2020\nER  - \n" +
+		"TY  - JOUR\nA1  - Doe, John\nPY  - 2020\nER  - \n" +
+		"TY  - JOUR\nID  - Doe2020b\nAU  - Doe & <b>Roe</b>, Ann\nER  - \n"
+	entries, err := Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	editors := cite.Names(&entries[0], "editor")
+	if len(editors) != 2 || cite.Text(editors[0].Prefix) != "van" || cite.Text(editors[0].Last) != "Gogh" ||
+		cite.Text(editors[1].Suffix) != "Jr." || cite.Text(editors[1].First) != "John" {
+		t.Errorf("editors = %v", editors)
+	}
+
+	if got := cite.Text(cite.Names(&entries[3], "author")[0].Last); got != "Doe &amp; &lt;b&gt;Roe&lt;/b&gt;" {
+		t.Errorf("author = %q; want it escaped", got)
+	}
+
+	// Keys come from the lead of the entries, and do not reuse the keys of
+	// the file
+	var keys []string
+	for _, entry := range entries {
+		keys = append(keys, entry.Key)
+	}
+	if got, want := strings.Join(keys, " "), "Gogh Doe2020 Doe2020a Doe2020b"; got != want {
+		t.Errorf("keys = %s; want %s", got, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name, src, want string
+	}{
+		{"text outside a record", "Some notes\nTY  - BOOK\nER  - \n", `line 1: expected a tag, found "Some notes"`},
+		{"tag outside a record", "\nTI  - Title\n", "line 2: TI outside a record"},
+		{"missing ER", "TY  - BOOK\nID  - Book1\nTI  - Title\nTY  - JOUR\nER  - \n", "line 4: TY before the ER of the previous record"},
+		{"unterminated", "TY  - BOOK\nER  - \nTY  - JOUR\nTI  - Title\n", "line 3: record without ER"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(strings.NewReader(tt.src))
+		var risErr *Error
+		if !errors.As(err, &risErr) || err.Error() != tt.want {
+			t.Errorf("%s: Parse error = %v; want %s", tt.name, err, tt.want)
+		}
+	}
+
+	// Lines that are not tags continue the value before them
+	entries, err := Parse(strings.NewReader("TY  - BOOK\nTI  - Title\nti  - more\nER  - \n"))
+	if err != nil || cite.Field(&entries[0], "title") != "Title ti  - more" {
+		t.Errorf("lowercase tag: Parse = %v, %v; want the title continued", entries, err)
+	}
+}
@@ -0,0 +1,199 @@
+package bibtex
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/jschaf/bibtex"
+	"github.com/lmondada/goldmark-bibtex/cite"
+	"github.com/lmondada/goldmark-bibtex/csljson"
+	"github.com/lmondada/goldmark-bibtex/endnote"
+	"github.com/lmondada/goldmark-bibtex/latex"
+	"github.com/lmondada/goldmark-bibtex/ris"
+)
+
+// ErrDuplicateKey is the error of a *BibError for an entry whose key is
+// already defined, with the DuplicateError policy.
+var ErrDuplicateKey = errors.New("duplicate entry key")
+
+// Source is a named bibliography, like the contents of a BibTeX file.
+type Source struct {
+	// Name is the name of the source, like the file name, used in errors and
+	// origins. Sources with the .json, .ris and .xml extensions are in the
+	// CSL-JSON, RIS and EndNote XML formats, see the csljson, ris and endnote
+	// packages, and are not resolved by WithResolvers.
+	Name string
+	Data []byte
+}
+
+// Origin is the place where an entry is defined.
+type Origin struct {
+	// File is the name of the source of the entry.
+	File string
+	// Line is the line of the entry, starting at 1, or 0 for entries that
+	// are not from BibTeX sources.
+	Line int
+}
+
+func (o Origin) String() string {
+	if o.Line == 0 {
+		return o.File
+	}
+	if o.File == "" {
+		return fmt.Sprintf("line %d", o.Line)
+	}
+	return fmt.Sprintf("%s:%d", o.File, o.Line)
+}
+
+// Duplicate is an entry dropped because its key was defined twice.
+type Duplicate struct {
+	Key string
+	// Kept is where the entry in the bibliography is defined.
+	Kept Origin
+	// Dropped is where the dropped entry is defined.
+	Dropped Origin
+}
+
+// DuplicatePolicy is the way entries with the same key are merged.
+type DuplicatePolicy int
+
+const (
+	// DuplicateFirstWins keeps the first definition of a key, like BibTeX.
+	DuplicateFirstWins DuplicatePolicy = iota
+	// DuplicateLastWins keeps the last definition of a key, so that later
+	// sources override earlier ones.
+	DuplicateLastWins
+	// DuplicateError fails with an ErrDuplicateKey error for every
+	// definition of a key after the first.
+	DuplicateError
+)
+
+// WithDuplicatePolicy sets the way entries with the same key, in the same
+// source or in different ones, are merged. Defaults to DuplicateFirstWins.
+// The dropped entries are listed in Extender.Duplicates.
+func WithDuplicatePolicy(policy DuplicatePolicy) Option {
+	return func(e *Extender) {
+		e.duplicates = policy
+	}
+}
+
+// NewFromFiles creates a new BibTeX extender with the entries of several
+// bibliography files, merged with the duplicate policy. Errors are reported
+// as by New.
+func NewFromFiles(bibFiles []string, opts ...Option) (*Extender, error) {
+	sources := make([]Source, len(bibFiles))
+	for i, name := range bibFiles {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		sources[i] = Source{Name: name, Data: data}
+	}
+	return NewFromSources(sources, opts...)
+}
+
+// NewFromSources creates a new BibTeX extender with the entries of several
+// sources, merged with the duplicate policy. Errors are reported as by New.
+func NewFromSources(sources []Source, opts ...Option) (*Extender, error) {
+	e := newExtender(opts)
+	if err := e.addSources(sources); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// parseSource parses a source in the format of the extension of its name:
+// CSL-JSON for .json, RIS for .ris, EndNote XML for .xml and BibTeX
+// otherwise. The LaTeX markup of BibTeX sources is decoded by decoder.
+func parseSource(src Source, resolvers []bibtex.Resolver, decoder latex.Resolver) ([]bibtex.Entry, []Origin, error) {
+	var entries []bibtex.Entry
+	var err error
+	switch strings.ToLower(path.Ext(src.Name)) {
+	case ".json":
+		entries, err = csljson.Parse(bytes.NewReader(src.Data))
+	case ".ris":
+		entries, err = ris.Parse(bytes.NewReader(src.Data))
+	case ".xml":
+		entries, err = endnote.Parse(bytes.NewReader(src.Data))
+	default:
+		return parseBibliography(src.Name, src.Data, resolvers, decoder)
+	}
+	if err != nil {
+		var jsonErr *csljson.Error
+		var risErr *ris.Error
+		var xmlErr *endnote.Error
+		switch {
+		case errors.As(err, &jsonErr):
+			return nil, nil, &BibError{File: src.Name, Line: jsonErr.Line, Key: jsonErr.Key, Err: jsonErr.Err}
+		case errors.As(err, &risErr):
+			return nil, nil, &BibError{File: src.Name, Line: risErr.Line, Key: risErr.Key, Err: risErr.Err}
+		case errors.As(err, &xmlErr):
+			return nil, nil, &BibError{File: src.Name, Line: xmlErr.Line, Err: xmlErr.Err}
+		}
+		return nil, nil, &BibError{File: src.Name, Err: err}
+	}
+	origins := make([]Origin, len(entries))
+	for i := range origins {
+		origins[i] = Origin{File: src.Name}
+	}
+	return entries, origins, nil
+}
+
+// addSources merges the entries of sources into the bibliography with the
+// duplicate policy.
+func (e *Extender) addSources(sources []Source) error {
+	if e.Origins == nil {
+		e.Origins = make(map[string]Origin)
+	}
+	index := make(map[string]int, len(e.Bibliography))
+	for i, entry := range e.Bibliography {
+		index[entry.Key] = i
+	}
+	var errs []error
+	for _, src := range sources {
+		entries, origins, err := parseSource(src, e.resolvers, latex.Resolver{Warn: e.warn, Math: e.math})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for i, entry := range entries {
+			cite.Normalize(&entry)
+			origin := origins[i]
+			j, ok := index[entry.Key]
+			if !ok {
+				index[entry.Key] = len(e.Bibliography)
+				e.Bibliography = append(e.Bibliography, entry)
+				e.Origins[entry.Key] = origin
+				continue
+			}
+			kept := e.Origins[entry.Key]
+			switch e.duplicates {
+			case DuplicateError:
+				errs = append(errs, &BibError{
+					File: origin.File,
+					Line: origin.Line,
+					Key:  entry.Key,
+					Err:  fmt.Errorf("%w, first defined at %s", ErrDuplicateKey, kept),
+				})
+			case DuplicateLastWins:
+				e.Bibliography[j] = entry
+				e.Origins[entry.Key] = origin
+				e.Duplicates = append(e.Duplicates, Duplicate{Key: entry.Key, Dropped: kept})
+			default:
+				e.Duplicates = append(e.Duplicates, Duplicate{Key: entry.Key, Dropped: origin})
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return joinErrors(errs)
+	}
+	for i, d := range e.Duplicates {
+		e.Duplicates[i].Kept = e.Origins[d.Key]
+	}
+	e.inherit()
+	return nil
+}
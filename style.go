@@ -0,0 +1,67 @@
+package bibtex
+
+import (
+	"github.com/jschaf/bibtex"
+	"github.com/lmondada/goldmark-bibtex/acm"
+	"github.com/lmondada/goldmark-bibtex/apa"
+	"github.com/lmondada/goldmark-bibtex/chicago"
+	"github.com/lmondada/goldmark-bibtex/cite"
+	"github.com/lmondada/goldmark-bibtex/csl"
+	"github.com/lmondada/goldmark-bibtex/ieee"
+)
+
+// Style formats inline citations and the reference list. The apa, acm, ieee
+// and chicago packages provide implementations, and the csl package loads any CSL style;
+// use WithStyle to choose one or to supply your own.
+type Style interface {
+	// FormatCitation formats the inline citation of a group of cited items.
+	// A group of a single bare `@key` has the cite.ModeAuthorInText mode.
+	FormatCitation(items []cite.Item) string
+	// FormatReference formats the full reference of an entry.
+	FormatReference(ref cite.Reference) string
+	// Less reports whether entry a sorts before entry b in the reference
+	// list. Entries that are not ordered keep the order in which they are
+	// first cited. Entries are numbered in the sorted order.
+	Less(a, b *bibtex.Entry) bool
+	// FormatBibliography formats the reference list of the sorted
+	// references.
+	FormatBibliography(refs []cite.Reference) string
+}
+
+// NoteStyle is a Style that puts citations in footnotes, like the Chicago
+// notes-bibliography style. Each citation or citation group is replaced by a
+// footnote reference, and the notes are listed at the end of the document,
+// before an appended reference list. FormatCitation formats the text that
+// stays in place, like the author of a narrative citation, and is usually
+// empty.
+type NoteStyle interface {
+	Style
+	// FormatNote formats the footnote of a group of cited items. Items cited
+	// for the first time in the document have First set.
+	FormatNote(items []cite.Item) string
+}
+
+// Disambiguator is a Style with author-year citations that tells apart works
+// with the same inline label, like "Smith 2020a" and "Smith 2020b".
+type Disambiguator interface {
+	Style
+	// Disambiguate sets the disambiguation of the sorted references, usually
+	// with cite.Disambiguate. Citations get the disambiguation of the
+	// reference of their entry.
+	Disambiguate(refs []cite.Reference)
+}
+
+var (
+	_ Style = apa.Style{}
+	_ Style = acm.Style{}
+	_ Style = acm.Style{Numeric: true}
+	_ Style = ieee.Style{}
+	_ Style = (*csl.Style)(nil)
+	_ Style = chicago.AuthorDate{}
+
+	_ Disambiguator = apa.Style{}
+	_ Disambiguator = acm.Style{}
+	_ Disambiguator = chicago.AuthorDate{}
+	_ Disambiguator = (*csl.Style)(nil)
+	_ NoteStyle     = chicago.Notes{}
+)
@@ -0,0 +1,346 @@
+package bibtex
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"sort"
+
+	"github.com/jschaf/bibtex"
+	"github.com/lmondada/goldmark-bibtex/cite"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+type bibliographyTransformer struct {
+	entries      []bibtex.Entry
+	bibliography map[string]bibtex.Entry
+	style        Style
+	warn         func(cite.Warning)
+	link         bool
+	nocite       []string
+	minCrossrefs int
+	// extender is the extender of the transformer, whose resolvers and
+	// duplicate policy apply to the bibliographies of documents, or nil
+	extender *Extender
+}
+
+// NewBibliographyTransformer returns a new AST transformer that resolves the
+// entry of each citation and inserts the reference list of all cited entries,
+// sorted by the style, into the document. The list replaces the
+// first bibliography marker (see isBibliographyMarker), or is appended to the
+// end of the document when there is no marker.
+func NewBibliographyTransformer(bib []bibtex.Entry, style Style) parser.ASTTransformer {
+	return newBibliographyTransformer(bib, style, nil)
+}
+
+// newBibliographyTransformer returns a bibliography transformer that reports
+// problems to warn, if it is not nil.
+func newBibliographyTransformer(bib []bibtex.Entry, style Style, warn func(cite.Warning)) *bibliographyTransformer {
+	return &bibliographyTransformer{
+		entries:      bib,
+		bibliography: keyEntries(bib),
+		style:        style,
+		warn:         warn,
+	}
+}
+
+// keyEntries maps the keys of entries to the entries.
+func keyEntries(entries []bibtex.Entry) map[string]bibtex.Entry {
+	bibMap := make(map[string]bibtex.Entry, len(entries))
+	for _, b := range entries {
+		bibMap[b.Key] = b
+	}
+	return bibMap
+}
+
+// Transform implements parser.ASTTransformer interface.
+func (t *bibliographyTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	bibEntries, bibliography := t.entries, t.bibliography
+	nocite := t.nocite
+	opts := &documentOptions{style: t.style, link: t.link}
+	var sources []Source
+	if t.extender != nil && t.extender.root != nil {
+		var keys []string
+		sources, keys, opts = t.frontMatter(pc)
+		nocite = append(slices.Clip(nocite), keys...)
+	}
+	sources = append(sources, bibliographyBlocks(doc, reader.Source())...)
+	if len(sources) > 0 {
+		bibEntries = t.extend(sources)
+		bibliography = keyEntries(bibEntries)
+	}
+
+	var citations []*Citation
+	// units are the citations outside groups and the groups, which become
+	// footnotes in note styles
+	var units []ast.Node
+	var entries []*bibtex.Entry
+	seen := make(map[string]bool)
+	var markers []ast.Node
+	var nociteGroups []ast.Node
+	_ = ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		if isBibliographyMarker(node, reader.Source()) {
+			markers = append(markers, node)
+			return ast.WalkSkipChildren, nil
+		}
+		if g, ok := node.(*CitationGroup); ok && g.Nocite {
+			for c := g.FirstChild(); c != nil; c = c.NextSibling() {
+				nocite = append(slices.Clip(nocite), c.(*Citation).Key)
+			}
+			nociteGroups = append(nociteGroups, g)
+			return ast.WalkSkipChildren, nil
+		}
+		if g, ok := node.(*CitationGroup); ok {
+			g.options = opts
+			units = append(units, node)
+		}
+		n, ok := node.(*Citation)
+		if !ok {
+			return ast.WalkContinue, nil
+		}
+		if _, ok := n.Parent().(*CitationGroup); !ok {
+			units = append(units, n)
+		}
+		citations = append(citations, n)
+		n.options = opts
+		n.First = !seen[n.Key]
+		if entry, ok := bibliography[n.Key]; ok {
+			n.Entry = &entry
+			if !seen[n.Key] {
+				entries = append(entries, n.Entry)
+				t.report(check(n.Entry, bibliography)...)
+			}
+		} else if !seen[n.Key] {
+			t.report(cite.Warning{Key: n.Key, Message: "not found in the bibliography"})
+		}
+		seen[n.Key] = true
+		return ast.WalkContinue, nil
+	})
+
+	for _, g := range nociteGroups {
+		parent := g.Parent()
+		parent.RemoveChild(parent, g)
+		// Drop the paragraph of a group on its own line
+		if parent.Kind() == ast.KindParagraph && parent.ChildCount() == 0 {
+			parent.Parent().RemoveChild(parent.Parent(), parent)
+		}
+	}
+
+	// Uncited entries follow the cited ones
+	for _, key := range nocite {
+		if key == "*" {
+			for i := range bibEntries {
+				if !seen[bibEntries[i].Key] {
+					entry := bibEntries[i]
+					entries = append(entries, &entry)
+					t.report(check(&entry, bibliography)...)
+					seen[entry.Key] = true
+				}
+			}
+			continue
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		entry, ok := bibliography[key]
+		if !ok {
+			t.report(cite.Warning{Key: key, Message: "not found in the bibliography"})
+			continue
+		}
+		entries = append(entries, &entry)
+		t.report(check(&entry, bibliography)...)
+	}
+
+	// Parents cited through enough of their children follow, like in BibTeX
+	if t.minCrossrefs > 0 {
+		var parents []string
+		count := make(map[string]int)
+		for _, entry := range entries {
+			parent := cite.Field(entry, "crossref")
+			if _, ok := bibliography[parent]; ok && !seen[parent] {
+				if count[parent] == 0 {
+					parents = append(parents, parent)
+				}
+				count[parent]++
+			}
+		}
+		for _, key := range parents {
+			if count[key] >= t.minCrossrefs {
+				seen[key] = true
+				entry := bibliography[key]
+				entries = append(entries, &entry)
+				t.report(check(&entry, bibliography)...)
+			}
+		}
+	}
+
+	// Number the entries in the order of the style, which is the order of
+	// first citation for numeric styles
+	style := opts.style
+	sort.SliceStable(entries, func(i, j int) bool {
+		return style.Less(entries[i], entries[j])
+	})
+	bib := &Bibliography{
+		References: make([]cite.Reference, len(entries)),
+		options:    opts,
+	}
+	numbers := make(map[string]int, len(entries))
+	for i, entry := range entries {
+		bib.References[i] = cite.Reference{Entry: entry, Number: i + 1}
+		numbers[entry.Key] = i + 1
+	}
+	if d, ok := style.(Disambiguator); ok {
+		d.Disambiguate(bib.References)
+	}
+	for _, n := range citations {
+		n.Number = numbers[n.Key]
+		if number := n.Number; number > 0 {
+			n.Disambiguation = bib.References[number-1].Disambiguation
+		}
+	}
+
+	if _, ok := style.(NoteStyle); ok && len(units) > 0 {
+		notes := citationNotes(units)
+		notes.options = opts
+		doc.AppendChild(doc, notes)
+	}
+
+	// Only the first marker is replaced, the others are dropped
+	for i, marker := range markers {
+		parent := marker.Parent()
+		if i == 0 && len(bib.References) > 0 {
+			parent.ReplaceChild(parent, marker, bib)
+		} else {
+			parent.RemoveChild(parent, marker)
+		}
+	}
+
+	if len(markers) == 0 && len(bib.References) > 0 {
+		doc.AppendChild(doc, bib)
+	}
+}
+
+// extend returns the bibliography with the entries of the sources of a
+// document, or the bibliography as is if they have errors.
+func (t *bibliographyTransformer) extend(sources []Source) []bibtex.Entry {
+	e := &Extender{Bibliography: slices.Clone(t.entries), warn: t.warn}
+	if t.extender != nil {
+		e.Origins = maps.Clone(t.extender.Origins)
+		e.resolvers = t.extender.resolvers
+		e.duplicates = t.extender.duplicates
+		e.math = t.extender.math
+		e.xdata = maps.Clone(t.extender.xdata)
+	}
+	if err := e.addSources(sources); err != nil {
+		t.report(cite.Warning{Message: err.Error()})
+		return t.entries
+	}
+	return e.Bibliography
+}
+
+// check returns the problems of a cited entry, see cite.Check, and its
+// references to entries missing from the bibliography.
+func check(entry *bibtex.Entry, bibliography map[string]bibtex.Entry) []cite.Warning {
+	warnings := cite.Check(entry)
+	if parent := cite.Field(entry, "crossref"); parent != "" {
+		if _, ok := bibliography[parent]; !ok {
+			warnings = append(warnings, cite.Warning{
+				Key:     entry.Key,
+				Field:   "crossref",
+				Message: fmt.Sprintf("parent %q not found in the bibliography", parent),
+			})
+		}
+	}
+	return warnings
+}
+
+func (t *bibliographyTransformer) report(warnings ...cite.Warning) {
+	if t.warn == nil {
+		return
+	}
+	for _, w := range warnings {
+		t.warn(w)
+	}
+}
+
+// citationNotes numbers the footnotes of the citations and groups in document
+// order and returns the node listing them.
+func citationNotes(units []ast.Node) *CitationNotes {
+	notes := &CitationNotes{
+		Notes: make([][]cite.Item, len(units)),
+	}
+	for i, unit := range units {
+		switch n := unit.(type) {
+		case *CitationGroup:
+			n.Note = i + 1
+			notes.Notes[i] = groupItems(n)
+		case *Citation:
+			n.Note = i + 1
+			notes.Notes[i] = []cite.Item{citationItem(n)}
+		}
+	}
+	return notes
+}
+
+var (
+	bibliographyParagraph = regexp.MustCompile(`^(\[bibliography\]|:::+\s*\{#refs\}\s*\n\s*:::+)$`)
+	bibliographyDiv       = regexp.MustCompile(`^<div\s+id="refs"\s*>\s*</div>$`)
+)
+
+// isBibliographyMarker reports whether a node marks the position of the
+// reference list. A marker is a paragraph containing only `[bibliography]`, an
+// empty fenced div `::: {#refs}` or an empty HTML block `<div id="refs"></div>`.
+func isBibliographyMarker(node ast.Node, source []byte) bool {
+	switch node.Kind() {
+	case ast.KindParagraph:
+		return bibliographyParagraph.Match(bytes.TrimSpace(node.Lines().Value(source)))
+	case ast.KindHTMLBlock:
+		return bibliographyDiv.Match(bytes.TrimSpace(node.Lines().Value(source)))
+	default:
+		return false
+	}
+}
+
+// bibliographyBlocks removes the fenced code blocks with the bibtex or bib
+// language from the document and returns their contents. The contents are
+// preceded by blank lines so that the lines of errors are lines of the
+// document.
+func bibliographyBlocks(doc *ast.Document, source []byte) []Source {
+	var blocks []*ast.FencedCodeBlock
+	_ = ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		if n, ok := node.(*ast.FencedCodeBlock); ok {
+			if lang := string(n.Language(source)); lang == "bibtex" || lang == "bib" {
+				blocks = append(blocks, n)
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+
+	sources := make([]Source, 0, len(blocks))
+	for _, n := range blocks {
+		var data []byte
+		lines := n.Lines()
+		if lines.Len() > 0 {
+			data = bytes.Repeat([]byte("\n"), bytes.Count(source[:lines.At(0).Start], []byte("\n")))
+		}
+		for i := 0; i < lines.Len(); i++ {
+			line := lines.At(i)
+			data = append(data, line.Value(source)...)
+		}
+		sources = append(sources, Source{Data: data})
+		n.Parent().RemoveChild(n.Parent(), n)
+	}
+	return sources
+}